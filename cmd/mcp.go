@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+	"github.com/denysvitali/openhands-runtime-go/pkg/mcp"
+	"github.com/denysvitali/openhands-runtime-go/pkg/redact"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// mcpCmd represents the mcp command
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve the runtime's MCP tools over stdio",
+	Long: `Serve the runtime's MCP tools, resources and prompts over stdin and stdout, so that
+MCP clients such as desktop assistants and IDEs can spawn the runtime as a local MCP server.
+Logs are written to stderr.`,
+	RunE: runMCP,
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+
+	// Not bound to viper, whose binding to the server command's flag would be replaced
+	mcpCmd.Flags().String("working-dir", "", "Working directory for the tools")
+}
+
+func runMCP(cmd *cobra.Command, args []string) error {
+	logger := GetLogger()
+
+	if workingDir, _ := cmd.Flags().GetString("working-dir"); cmd.Flags().Changed("working-dir") {
+		viper.Set("server.working_dir", workingDir)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Mask secrets in everything logged from here on
+	redactor, err := redact.New(cfg.Log.RedactPatterns, cfg.Log.RedactFields)
+	if err != nil {
+		return fmt.Errorf("failed to set up redaction: %w", err)
+	}
+	logger.AddHook(redactor.Hook())
+
+	exec, err := executor.New(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create executor: %w", err)
+	}
+	defer func() {
+		if err := exec.Close(); err != nil {
+			logger.Errorf("Error closing executor: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Infof("Serving MCP over stdio in %s", cfg.Server.WorkingDir)
+	return mcp.NewServer(logger, exec).ServeStdio(ctx, os.Stdin, os.Stdout)
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/redact"
 	"github.com/denysvitali/openhands-runtime-go/pkg/server"
 	"github.com/denysvitali/openhands-runtime-go/pkg/telemetry"
 	"github.com/spf13/cobra"
@@ -44,7 +45,7 @@ func init() {
 	serverCmd.Flags().String("browsergym-eval-env", "", "BrowserGym environment for browser evaluation")
 	serverCmd.Flags().String("session-api-key", "", "API key for session authentication")
 	serverCmd.Flags().Bool("enable-telemetry", true, "Enable OpenTelemetry tracing")
-	serverCmd.Flags().String("otel-endpoint", "", "OpenTelemetry endpoint (if empty, uses auto-export)")
+	serverCmd.Flags().String("otel-endpoint", "", "OpenTelemetry OTLP endpoint, overriding OTEL_EXPORTER_OTLP_ENDPOINT")
 
 	// Bind flags to viper
 	_ = viper.BindPFlag("server.port", serverCmd.Flags().Lookup("port"))
@@ -68,11 +69,18 @@ func runServer(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	// Mask secrets in everything logged from here on, and in exported telemetry
+	redactor, err := redact.New(cfg.Log.RedactPatterns, cfg.Log.RedactFields)
+	if err != nil {
+		return fmt.Errorf("failed to set up redaction: %w", err)
+	}
+	logger.AddHook(redactor.Hook())
+
 	// Initialize telemetry if enabled
 	var cleanupTelemetry func()
 	if cfg.Telemetry.Enabled {
 		logger.Info("Initializing OpenTelemetry with autoexport")
-		cleanupTelemetry, err = telemetry.Initialize(cfg.Telemetry, logger)
+		cleanupTelemetry, err = telemetry.Initialize(cfg.Telemetry, logger, redactor)
 		if err != nil {
 			logger.Warnf("Failed to initialize telemetry: %v", err)
 		} else {
@@ -90,6 +98,11 @@ func runServer(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
 
+	// Apply changes to hot-reloadable settings without a restart
+	config.Watch(srv.Executor().Config(), logger, setupLogging)
+	stopReloadOnSignal := config.ReloadOnSignal(srv.Executor().Config(), logger, setupLogging, syscall.SIGHUP)
+	defer stopReloadOnSignal()
+
 	// Start server in a goroutine
 	serverErrors := make(chan error, 1)
 	go func() {
@@ -117,19 +130,28 @@ func runServer(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("server error: %w", err)
 	case sig := <-interrupt:
 		logger.Infof("Received signal %v, shutting down...", sig)
+		return shutdownServer(srv)
+	case <-srv.ShutdownRequested():
+		logger.Info("Shutdown requested over HTTP, shutting down...")
+		return shutdownServer(srv)
+	}
+}
 
-		// Graceful shutdown with timeout
-		// The server.Shutdown() method now also handles executor.Close()
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+// shutdownServer gracefully stops the server, draining in-flight requests
+func shutdownServer(srv *server.Server) error {
+	logger := GetLogger()
 
-		if err := srv.Shutdown(ctx); err != nil {
-			logger.Errorf("Server shutdown error: %v", err)
-			// Even if shutdown has an error, we return it, and deferred telemetry cleanup will run.
-			return err
-		}
+	// Graceful shutdown with timeout
+	// The server.Shutdown() method now also handles executor.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-		logger.Info("Server stopped gracefully")
-		return nil
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Errorf("Server shutdown error: %v", err)
+		// Even if shutdown has an error, we return it, and deferred telemetry cleanup will run.
+		return err
 	}
+
+	logger.Info("Server stopped gracefully")
+	return nil
 }
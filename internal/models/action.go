@@ -4,12 +4,38 @@ import (
 	"encoding/json"
 	"errors" // Added for errors.New
 	"fmt"    // Added for fmt.Errorf
+	"reflect"
+	"strings"
 	"time"
 )
 
 // ActionRequest represents an incoming action request
 type ActionRequest struct {
 	Action map[string]interface{} `json:"action" binding:"required"`
+	ID     *int64                 `json:"id,omitempty"` // Alternative to an "id" inside the action
+}
+
+// KillRequest asks the runtime to interrupt a running command
+type KillRequest struct {
+	CommandID string `json:"command_id" binding:"required"` // As reported in the start event of /execute_action_stream
+	Signal    string `json:"signal,omitempty"`              // INT (default), TERM or KILL; escalates if ignored
+}
+
+// ActionID returns the optional ID of an action, which its observation echoes back as the cause
+func ActionID(actionMap map[string]interface{}) (int64, bool) {
+	switch id := actionMap["id"].(type) {
+	case float64:
+		return int64(id), true
+	case int64:
+		return id, true
+	case int:
+		return int64(id), true
+	case json.Number:
+		n, err := id.Int64()
+		return n, err == nil
+	default:
+		return 0, false
+	}
 }
 
 // Action represents a base action
@@ -19,17 +45,29 @@ type Action struct {
 	Args      map[string]interface{} `json:"args,omitempty"`
 }
 
+// ExtraArgs is embedded in action types to hold the args that the type doesn't have or whose
+// values have the wrong type, which would be in Args of the base Action
+type ExtraArgs struct {
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+func (a *ExtraArgs) setExtraArgs(args map[string]interface{}) { a.Args = args }
+
 // CmdRunAction represents a command execution action
 type CmdRunAction struct {
+	ExtraArgs
 	Action      string `json:"action"`
 	Command     string `json:"command"`
 	Cwd         string `json:"cwd,omitempty"`
 	IsStatic    bool   `json:"is_static,omitempty"`
 	HardTimeout int    `json:"hard_timeout,omitempty"`
+	LogToFile   string `json:"log_to_file,omitempty"` // Workspace-relative file that receives the full output
+	EnvFile     string `json:"env_file,omitempty"`    // Dotenv file loaded into the session before the command runs
 }
 
 // FileReadAction represents a file read action
 type FileReadAction struct {
+	ExtraArgs
 	Action string `json:"action"`
 	Path   string `json:"path"`
 	Start  int    `json:"start,omitempty"`
@@ -38,13 +76,71 @@ type FileReadAction struct {
 
 // FileWriteAction represents a file write action
 type FileWriteAction struct {
+	ExtraArgs
 	Action   string `json:"action"`
 	Path     string `json:"path"`
 	Contents string `json:"contents"`
+	Mode     string `json:"mode,omitempty"` // Octal permissions of a newly created file, e.g. "0755"
+}
+
+// FilePatchAction applies an RFC 6902 JSON patch to a JSON file
+type FilePatchAction struct {
+	ExtraArgs
+	Action string          `json:"action"`
+	Path   string          `json:"path"`
+	Patch  json.RawMessage `json:"patch"` // Array of patch operations
+}
+
+// PatchAction applies a unified diff, such as one from git diff, to the workspace like git apply.
+// Hunks that don't apply are rejected while the others still are. Within a git repository the
+// diff's paths are relative to its top level, as with git apply.
+type PatchAction struct {
+	ExtraArgs
+	Action string `json:"action"`
+	Patch  string `json:"patch"`           // Unified diff
+	Path   string `json:"path"`            // Directory the diff's paths are relative to, the working directory when empty
+	Strip  *int   `json:"strip,omitempty"` // Leading path components to strip from the diff's paths, 1 when they all have a/ and b/ prefixes and 0 otherwise by default
+}
+
+// ListFilesAction lists the entries of a directory as structured file info
+type ListFilesAction struct {
+	ExtraArgs
+	Action           string `json:"action"`
+	Path             string `json:"path"`              // Directory to list, relative to the working directory when not absolute
+	Recursive        bool   `json:"recursive"`         // Whether to descend into subdirectories
+	MaxDepth         int    `json:"max_depth"`         // Levels to descend into when recursive, 0 for unlimited
+	MaxResults       int    `json:"max_results"`       // Maximum number of entries to return, 0 for the default
+	RespectGitignore bool   `json:"respect_gitignore"` // Whether to leave out .git and paths ignored by .gitignore files
+}
+
+// SearchFilesAction searches the workspace for files by name or by content
+type SearchFilesAction struct {
+	ExtraArgs
+	Action           string `json:"action"`
+	Query            string `json:"query"`             // Glob or substring matched against file names, or substring matched against lines
+	Path             string `json:"path"`              // Directory to search, the working directory when empty
+	Content          bool   `json:"content"`           // Whether to search file contents rather than names
+	MaxResults       int    `json:"max_results"`       // Maximum number of matches to return, 0 for the default
+	RespectGitignore bool   `json:"respect_gitignore"` // Whether to skip files ignored by .gitignore files
+}
+
+// GrepAction searches file contents for a regular expression, like grep -n -C
+type GrepAction struct {
+	ExtraArgs
+	Action           string   `json:"action"`
+	Pattern          string   `json:"pattern"`           // Go regular expression matched against each line
+	Path             string   `json:"path"`              // Directory to search, the working directory when empty
+	Include          []string `json:"include"`           // Globs of files to search; all files when empty
+	Exclude          []string `json:"exclude"`           // Globs of files to skip
+	ContextLines     int      `json:"context_lines"`     // Number of lines to return before and after each match
+	IgnoreCase       bool     `json:"ignore_case"`       // Whether to match case-insensitively
+	RespectGitignore bool     `json:"respect_gitignore"` // Whether to skip files ignored by .gitignore files
+	MaxResults       int      `json:"max_results"`       // Maximum number of matches to return, 0 for the default
 }
 
 // FileEditAction represents a file edit action
 type FileEditAction struct {
+	ExtraArgs
 	Action     string `json:"action"`
 	Path       string `json:"path"`
 	Command    string `json:"command,omitempty"`
@@ -53,6 +149,10 @@ type FileEditAction struct {
 	OldStr     string `json:"old_str,omitempty"`
 	NewStr     string `json:"new_str,omitempty"`
 	InsertLine *int   `json:"insert_line,omitempty"` // Changed to pointer to handle nil
+	Mode       string `json:"mode,omitempty"`        // Octal permissions of a newly created file, e.g. "0755"
+	// yaml_set fields
+	KeyPath string      `json:"key_path,omitempty"` // Dotted path of the key to set, e.g. "server.port"
+	Value   interface{} `json:"value,omitempty"`
 	// LLM-based editing fields
 	Content string `json:"content,omitempty"`
 	Start   int    `json:"start,omitempty"`
@@ -62,21 +162,26 @@ type FileEditAction struct {
 
 // IPythonRunCellAction represents an IPython cell execution action
 type IPythonRunCellAction struct {
+	ExtraArgs
 	Action         string `json:"action"`
 	Code           string `json:"code"`
 	Thought        string `json:"thought,omitempty"`
 	IncludeExtra   bool   `json:"include_extra,omitempty"`
 	KernelInitCode string `json:"kernel_init_code,omitempty"`
+	Timeout        int    `json:"timeout,omitempty"` // Per-cell timeout in seconds, overrides server.ipython_timeout_seconds
+	Kernel         string `json:"kernel,omitempty"`  // Jupyter kernelspec name, defaults to python3
 }
 
 // BrowseURLAction represents a browser URL navigation action
 type BrowseURLAction struct {
+	ExtraArgs
 	Action string `json:"action"`
 	URL    string `json:"url"`
 }
 
 // BrowseInteractiveAction represents a browser interaction action
 type BrowseInteractiveAction struct {
+	ExtraArgs
 	Action           string `json:"action"`
 	BrowserID        string `json:"browser_id"`
 	Coordinate       []int  `json:"coordinate,omitempty"`
@@ -86,6 +191,21 @@ type BrowseInteractiveAction struct {
 	WaitBeforeAction int    `json:"wait_before_action,omitempty"`
 }
 
+// ThinkAction represents the agent logging a thought
+type ThinkAction struct {
+	ExtraArgs
+	Action  string `json:"action"`
+	Thought string `json:"thought"`
+}
+
+// RecallAction represents the agent asking to recall knowledge or workspace context
+type RecallAction struct {
+	ExtraArgs
+	Action     string `json:"action"`
+	Query      string `json:"query,omitempty"`
+	RecallType string `json:"recall_type,omitempty"`
+}
+
 // genericUnmarshalAction is a helper function to unmarshal JSON data into a specific action type.
 // It is unexported as it's intended for use only within this package.
 func genericUnmarshalAction[T any](jsonData []byte) (T, error) {
@@ -98,16 +218,108 @@ func genericUnmarshalAction[T any](jsonData []byte) (T, error) {
 	return action, nil
 }
 
-// ParseAction parses a generic action map into a specific action type
-func ParseAction(actionMap map[string]interface{}) (interface{}, error) {
+// ActionParser unmarshals the flattened parameters of an action into its specific type.
+// Parameters that don't fit the type are returned as unparsed instead of failing the whole action.
+type ActionParser func(params map[string]interface{}) (action interface{}, unparsed map[string]interface{}, err error)
+
+// ActionParsers looks up the parser of an action type
+type ActionParsers func(actionType string) (ActionParser, bool)
+
+// ParserFor returns an ActionParser producing actions of type A
+func ParserFor[A any]() ActionParser {
+	return func(params map[string]interface{}) (interface{}, map[string]interface{}, error) {
+		return lenientUnmarshalAction[A](params)
+	}
+}
+
+// lenientUnmarshalAction unmarshals params into A, setting aside parameters that A doesn't have
+// or whose values have the wrong type so that the remaining ones can still be used. If A embeds
+// ExtraArgs, the parameters set aside are also kept in its Args.
+func lenientUnmarshalAction[A any](params map[string]interface{}) (A, map[string]interface{}, error) {
+	actionType := reflect.TypeOf((*A)(nil)).Elem()
+	if actionType.Kind() != reflect.Struct {
+		// Maps and other free-form types take every parameter
+		jsonData, err := json.Marshal(params)
+		if err != nil {
+			var zero A
+			return zero, nil, fmt.Errorf("failed to marshal action parameters to JSON: %w", err)
+		}
+		action, err := genericUnmarshalAction[A](jsonData)
+		return action, nil, err
+	}
+
+	known := jsonFieldNames(actionType)
+	accepted := make(map[string]interface{}, len(params))
+	unparsed := make(map[string]interface{})
+
+	for key, value := range params {
+		if !known[strings.ToLower(key)] {
+			unparsed[key] = value
+			continue
+		}
+
+		// Try each parameter on its own so that a single bad value can be singled out
+		single, err := json.Marshal(map[string]interface{}{key: value})
+		if err == nil {
+			var probe A
+			err = json.Unmarshal(single, &probe)
+		}
+		if err != nil {
+			unparsed[key] = value
+			continue
+		}
+		accepted[key] = value
+	}
+
+	jsonData, err := json.Marshal(accepted)
+	if err != nil {
+		var zero A
+		return zero, nil, fmt.Errorf("failed to marshal action parameters to JSON: %w", err)
+	}
+	action, err := genericUnmarshalAction[A](jsonData)
+	if holder, ok := any(&action).(interface{ setExtraArgs(map[string]interface{}) }); ok && err == nil && len(unparsed) > 0 {
+		holder.setExtraArgs(unparsed)
+	}
+	return action, unparsed, err
+}
+
+// jsonFieldNames returns the lower-cased JSON names of a struct type's fields,
+// matching encoding/json's case-insensitive field lookup
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[strings.ToLower(name)] = true
+	}
+	return names
+}
+
+// ParseAction parses a generic action map into the specific action type that parsers has for it.
+// Action types without a parser are parsed into the base Action.
+func ParseAction(actionMap map[string]interface{}, parsers ActionParsers) (interface{}, error) {
+	action, _, err := ParseActionLenient(actionMap, parsers)
+	return action, err
+}
+
+// ParseActionLenient parses a generic action map into a specific action type like ParseAction,
+// and also returns the args that were unknown to the action type or had values of the wrong type.
+// These are left out of the action rather than failing it.
+func ParseActionLenient(actionMap map[string]interface{}, parsers ActionParsers) (action interface{}, unparsed map[string]interface{}, err error) {
 	actionTypeVal, ok := actionMap["action"]
 	if !ok {
-		return nil, errors.New("action map is missing 'action' field")
+		return nil, nil, errors.New("action map is missing 'action' field")
 	}
 
 	actionType, ok := actionTypeVal.(string)
 	if !ok {
-		return nil, fmt.Errorf("'action' field is not a string, got %T", actionTypeVal)
+		return nil, nil, fmt.Errorf("'action' field is not a string, got %T", actionTypeVal)
 	}
 
 	// mapForUnmarshalling will contain the actual parameters for the action.
@@ -122,7 +334,7 @@ func ParseAction(actionMap map[string]interface{}) (interface{}, error) {
 			}
 		} else {
 			// "args" field is present but not a map, which is an invalid structure.
-			return nil, fmt.Errorf("'args' field is present but is not a map[string]interface{}, got %T", argsVal)
+			return nil, nil, fmt.Errorf("'args' field is present but is not a map[string]interface{}, got %T", argsVal)
 		}
 	} else {
 		// No "args" field, assume actionMap is already flat.
@@ -136,34 +348,17 @@ func ParseAction(actionMap map[string]interface{}) (interface{}, error) {
 	// as specific action structs (e.g., CmdRunAction) also have an "action" field.
 	mapForUnmarshalling["action"] = actionType
 
-	jsonData, err := json.Marshal(mapForUnmarshalling)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal mapForUnmarshalling to JSON: %w", err)
+	if parse, registered := parsers(actionType); registered {
+		return parse(mapForUnmarshalling)
 	}
 
-	switch actionType {
-	case "run":
-		return genericUnmarshalAction[CmdRunAction](jsonData)
-	case "read":
-		return genericUnmarshalAction[FileReadAction](jsonData)
-	case "write":
-		return genericUnmarshalAction[FileWriteAction](jsonData)
-	case "edit": // Changed from "str_replace_editor"
-		return genericUnmarshalAction[FileEditAction](jsonData)
-	case "run_ipython":
-		return genericUnmarshalAction[IPythonRunCellAction](jsonData)
-	case "browse":
-		return genericUnmarshalAction[BrowseURLAction](jsonData)
-	case "browse_interactive":
-		return genericUnmarshalAction[BrowseInteractiveAction](jsonData)
-	default:
-		// For unknown action types, parse into the base Action struct.
-		// The base Action struct expects an "action" field and an "args" field (if present in original).
-		// Therefore, for the default case, we should marshal the original actionMap.
-		originalJsonData, err := json.Marshal(actionMap)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal original actionMap to JSON for default case: %w", err)
-		}
-		return genericUnmarshalAction[Action](originalJsonData)
+	// For unknown action types, parse into the base Action struct.
+	// The base Action struct expects an "action" field and an "args" field (if present in original).
+	// Therefore, for the default case, we should marshal the original actionMap.
+	originalJsonData, err := json.Marshal(actionMap)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal original actionMap to JSON for default case: %w", err)
 	}
+	action, err = genericUnmarshalAction[Action](originalJsonData)
+	return action, nil, err
 }
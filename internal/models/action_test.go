@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readParsers knows only the read action
+func readParsers(actionType string) (ActionParser, bool) {
+	if actionType == "read" {
+		return ParserFor[FileReadAction](), true
+	}
+	return nil, false
+}
+
+func TestParseActionLenient(t *testing.T) {
+	action, unparsed, err := ParseActionLenient(map[string]interface{}{
+		"action": "read",
+		"args": map[string]interface{}{
+			"path":    "file.txt",
+			"start":   "ten",
+			"end":     float64(20),
+			"unknown": "value",
+		},
+	}, readParsers)
+	require.NoError(t, err)
+
+	readAction, ok := action.(FileReadAction)
+	require.True(t, ok, "expected FileReadAction, got %T", action)
+	assert.Equal(t, "file.txt", readAction.Path)
+	assert.Equal(t, 0, readAction.Start)
+	assert.Equal(t, 20, readAction.End)
+	assert.Equal(t, map[string]interface{}{"start": "ten", "unknown": "value"}, unparsed)
+	assert.Equal(t, unparsed, readAction.Args, "the action keeps the args set aside")
+}
+
+func TestParseAction_MalformedInput(t *testing.T) {
+	_, err := ParseAction(map[string]interface{}{"args": map[string]interface{}{}}, readParsers)
+	assert.ErrorContains(t, err, "missing 'action' field")
+
+	_, err = ParseAction(map[string]interface{}{"action": "read", "args": "a.txt"}, readParsers)
+	assert.ErrorContains(t, err, "'args' field is present but is not a map")
+}
+
+func TestParseAction_UnknownType(t *testing.T) {
+	action, err := ParseAction(map[string]interface{}{"action": "run", "args": map[string]interface{}{"command": "ls"}}, readParsers)
+	require.NoError(t, err)
+	_, ok := action.(Action)
+	assert.True(t, ok, "expected the base Action, got %T", action)
+}
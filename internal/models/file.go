@@ -7,10 +7,18 @@ type FileInfo struct {
 	Size  int64  `json:"size"`
 }
 
+// ArchiveStats describes the content of a download before it is archived
+type ArchiveStats struct {
+	FileCount  int   `json:"file_count"`
+	TotalBytes int64 `json:"total_bytes"` // Uncompressed size of the files
+}
+
 // ListFilesRequest represents the request to list files
 type ListFilesRequest struct {
-	Path      string `json:"path"`
-	Recursive bool   `json:"recursive"`
+	Path             string `json:"path"`
+	Recursive        bool   `json:"recursive"`
+	MaxDepth         int    `json:"max_depth,omitempty"`         // Levels to descend into when recursive, 0 for unlimited
+	RespectGitignore bool   `json:"respect_gitignore,omitempty"` // Whether to leave out .git and paths ignored by .gitignore files
 }
 
 // MCPServerRequest represents a request to update MCP servers
@@ -7,6 +7,7 @@ type Observation[T any] struct {
 	Observation string    `json:"observation"`
 	Content     string    `json:"content"`
 	Timestamp   time.Time `json:"timestamp"`
+	Cause       *int64    `json:"cause,omitempty"` // ID of the action this observation answers
 	Extras      T         `json:"extras,omitempty"`
 }
 
@@ -15,19 +16,118 @@ type BasicObservation struct {
 	Observation string                 `json:"observation"`
 	Content     string                 `json:"content"`
 	Timestamp   time.Time              `json:"timestamp"`
+	Cause       *int64                 `json:"cause,omitempty"` // ID of the action this observation answers
 	Extras      map[string]interface{} `json:"extras,omitempty"`
 }
 
+// WithCause returns a copy of the observation attributed to the action with the given ID
+func (o Observation[T]) WithCause(id int64) interface{} {
+	o.Cause = &id
+	return o
+}
+
+// WithCause returns a copy of the observation attributed to the action with the given ID
+func (o BasicObservation) WithCause(id int64) interface{} {
+	o.Cause = &id
+	return o
+}
+
+// WithCause attributes an observation of any type to the action with the given ID.
+// Values that aren't observations are returned unchanged.
+func WithCause(observation interface{}, id int64) interface{} {
+	if o, ok := observation.(interface{ WithCause(int64) interface{} }); ok {
+		return o.WithCause(id)
+	}
+	return observation
+}
+
 // CmdOutputExtras contains extra fields for command output observations
 type CmdOutputExtras struct {
-	ExitCode  int    `json:"exit_code"`
-	CommandID string `json:"command_id,omitempty"`
-	Command   string `json:"command,omitempty"`
+	ExitCode          int          `json:"exit_code"`
+	CommandID         string       `json:"command_id,omitempty"`
+	Command           string       `json:"command,omitempty"`
+	WorkingDir        string       `json:"working_dir,omitempty"`        // Directory the shell was in when the command finished
+	Truncated         bool         `json:"truncated,omitempty"`          // Whether the output was cut at max_observation_content_bytes
+	Timeline          []OutputLine `json:"timeline,omitempty"`           // Capture time of each output line, see timestamp_command_output
+	TimelineTruncated bool         `json:"timeline_truncated,omitempty"` // Whether middle lines of the timeline were dropped to fit max_observation_content_bytes
+	LogFile           string       `json:"log_file,omitempty"`           // File holding the full output, see CmdRunAction.LogToFile
+	Killed            bool         `json:"killed,omitempty"`             // Killed by SIGKILL the runtime didn't send, possibly out of memory
+	Install           *InstallInfo `json:"install,omitempty"`            // Set when the command installed packages, see summarize_install_output
+}
+
+// InstallInfo describes a package install command whose output was summarized
+type InstallInfo struct {
+	Manager  string   `json:"manager"`            // pip, npm, yarn, pnpm or apt
+	Packages []string `json:"packages,omitempty"` // Packages reported installed, or requested if the output doesn't list them
+	Success  bool     `json:"success"`
+}
+
+// OutputLine is a line of command output with the time its first byte was captured
+type OutputLine struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"` // stdout or stderr
+	Line   string    `json:"line"`
 }
 
 // FileReadExtras contains extra fields for file read observations
 type FileReadExtras struct {
+	Path       string `json:"path"`
+	Encoding   string `json:"encoding,omitempty"`    // Source encoding before transcoding to UTF-8, from a BOM, else utf-8 if valid, else assumed windows-1252
+	TotalLines int    `json:"total_lines,omitempty"` // Number of lines in the whole file
+	Truncated  bool   `json:"truncated,omitempty"`   // Whether the content was cut at max_read_lines or max_observation_content_bytes
+	Media      bool   `json:"-"`                     // Whether the content is a data URL, which is never truncated
+}
+
+// ListFilesExtras contains extra fields for list files observations
+type ListFilesExtras struct {
+	Path      string     `json:"path"`
+	Files     []FileInfo `json:"files"`
+	Truncated bool       `json:"truncated,omitempty"` // Whether files were cut at max_results
+}
+
+// SearchMatch is a file, or a line in a file, matching a search
+type SearchMatch struct {
 	Path string `json:"path"`
+	Line int    `json:"line,omitempty"` // 1-based line number of a content match
+	Text string `json:"text,omitempty"` // Matching line of a content match
+}
+
+// SearchExtras contains extra fields for search observations
+type SearchExtras struct {
+	Query     string        `json:"query"`
+	Path      string        `json:"path"`
+	Matches   []SearchMatch `json:"matches"`
+	Truncated bool          `json:"truncated,omitempty"` // Whether matches were cut at max_results
+}
+
+// GrepMatch is a line matching a grep, with its surrounding lines
+type GrepMatch struct {
+	Path   string   `json:"path"`
+	Line   int      `json:"line"` // 1-based line number
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"` // Up to context_lines lines preceding the match
+	After  []string `json:"after,omitempty"`  // Up to context_lines lines following the match
+}
+
+// GrepExtras contains extra fields for grep observations
+type GrepExtras struct {
+	Pattern   string      `json:"pattern"`
+	Path      string      `json:"path"`
+	Matches   []GrepMatch `json:"matches"`
+	Truncated bool        `json:"truncated,omitempty"` // Whether matches were cut at max_results
+}
+
+// RejectedHunks are the hunks of a patch that didn't apply to a file
+type RejectedHunks struct {
+	Path  string `json:"path"`
+	Hunks string `json:"hunks"` // The rejected hunks, in unified diff format
+}
+
+// PatchExtras contains extra fields for patch observations
+type PatchExtras struct {
+	Path          string          `json:"path"`
+	ChangedFiles  []string        `json:"changed_files"`
+	RejectedHunks []RejectedHunks `json:"rejected_hunks,omitempty"`
 }
 
 // FileWriteExtras contains extra fields for file write observations
@@ -58,6 +158,22 @@ type BrowserExtras struct {
 	FocusedElementBID string   `json:"focused_element_bid,omitempty"`
 }
 
+// NullExtras contains extra fields for null observations
+type NullExtras struct {
+	Action interface{} `json:"action,omitempty"` // The action that was received but had no effect
+}
+
+// ThinkExtras contains extra fields for think observations
+type ThinkExtras struct {
+	Thought string `json:"thought,omitempty"`
+}
+
+// RecallExtras contains extra fields for recall observations
+type RecallExtras struct {
+	Query      string `json:"query,omitempty"`
+	RecallType string `json:"recall_type,omitempty"`
+}
+
 // ErrorExtras contains extra fields for error observations
 type ErrorExtras struct {
 	ErrorID string `json:"error_id,omitempty"`
@@ -65,8 +181,18 @@ type ErrorExtras struct {
 
 // IPythonExtras contains extra fields for IPython observations
 type IPythonExtras struct {
-	Code      string   `json:"code,omitempty"`
-	ImageURLs []string `json:"image_urls,omitempty"`
+	Code      string          `json:"code,omitempty"`
+	ImageURLs []string        `json:"image_urls,omitempty"`
+	Outputs   []IPythonOutput `json:"outputs,omitempty"`
+	// Set when the cell raised an exception
+	ErrorName  string `json:"ename,omitempty"`
+	ErrorValue string `json:"evalue,omitempty"`
+}
+
+// IPythonOutput is a single output produced by a cell, e.g. text, HTML or a base64 encoded image
+type IPythonOutput struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"`
 }
 
 // NewCmdOutputObservation creates a new command execution output observation
@@ -95,6 +221,63 @@ func NewFileReadObservation(content string, path string) Observation[FileReadExt
 	}
 }
 
+// NewListFilesObservation creates a new list files observation
+func NewListFilesObservation(content string, path string, files []FileInfo) Observation[ListFilesExtras] {
+	return Observation[ListFilesExtras]{
+		Observation: "list_files",
+		Content:     content,
+		Timestamp:   time.Now(),
+		Extras: ListFilesExtras{
+			Path:  path,
+			Files: files,
+		},
+	}
+}
+
+// NewSearchObservation creates a new search observation
+func NewSearchObservation(content string, query string, path string, matches []SearchMatch, truncated bool) Observation[SearchExtras] {
+	return Observation[SearchExtras]{
+		Observation: "search",
+		Content:     content,
+		Timestamp:   time.Now(),
+		Extras: SearchExtras{
+			Query:     query,
+			Path:      path,
+			Matches:   matches,
+			Truncated: truncated,
+		},
+	}
+}
+
+// NewGrepObservation creates a new grep observation
+func NewGrepObservation(content string, pattern string, path string, matches []GrepMatch, truncated bool) Observation[GrepExtras] {
+	return Observation[GrepExtras]{
+		Observation: "grep",
+		Content:     content,
+		Timestamp:   time.Now(),
+		Extras: GrepExtras{
+			Pattern:   pattern,
+			Path:      path,
+			Matches:   matches,
+			Truncated: truncated,
+		},
+	}
+}
+
+// NewPatchObservation creates a new patch observation
+func NewPatchObservation(content string, path string, changedFiles []string, rejectedHunks []RejectedHunks) Observation[PatchExtras] {
+	return Observation[PatchExtras]{
+		Observation: "patch",
+		Content:     content,
+		Timestamp:   time.Now(),
+		Extras: PatchExtras{
+			Path:          path,
+			ChangedFiles:  changedFiles,
+			RejectedHunks: rejectedHunks,
+		},
+	}
+}
+
 // NewFileWriteObservation creates a new file write observation
 func NewFileWriteObservation(content string, path string) Observation[FileWriteExtras] {
 	return Observation[FileWriteExtras]{
@@ -139,6 +322,44 @@ func NewErrorObservation(content string, errorID string) Observation[ErrorExtras
 	}
 }
 
+// NewNullObservation creates an observation for an action that was understood but had no effect,
+// echoing the action back so that the agent can adapt
+func NewNullObservation(content string, action interface{}) Observation[NullExtras] {
+	return Observation[NullExtras]{
+		Observation: "null",
+		Content:     content,
+		Timestamp:   time.Now(),
+		Extras: NullExtras{
+			Action: action,
+		},
+	}
+}
+
+// NewThinkObservation acknowledges a logged thought
+func NewThinkObservation(thought string) Observation[ThinkExtras] {
+	return Observation[ThinkExtras]{
+		Observation: "think",
+		Content:     "Your thought has been logged.",
+		Timestamp:   time.Now(),
+		Extras: ThinkExtras{
+			Thought: thought,
+		},
+	}
+}
+
+// NewRecallObservation acknowledges a recall request with the recalled content
+func NewRecallObservation(content string, query string, recallType string) Observation[RecallExtras] {
+	return Observation[RecallExtras]{
+		Observation: "recall",
+		Content:     content,
+		Timestamp:   time.Now(),
+		Extras: RecallExtras{
+			Query:      query,
+			RecallType: recallType,
+		},
+	}
+}
+
 // NewBrowserObservation creates a new browser interaction output observation
 func NewBrowserObservation(content string, url string, screenshot string, triggerByAction string) Observation[BrowserExtras] {
 	return Observation[BrowserExtras]{
@@ -155,14 +376,17 @@ func NewBrowserObservation(content string, url string, screenshot string, trigge
 
 // SystemResources represents system resource information from Python get_system_stats()
 type SystemResources struct {
-	CPUCount      int     `json:"cpu_count"`
-	CPUPercent    float64 `json:"cpu_percent"`
-	MemoryTotal   int64   `json:"memory_total"`
-	MemoryUsed    int64   `json:"memory_used"`
-	MemoryPercent float64 `json:"memory_percent"`
-	DiskTotal     int64   `json:"disk_total"`
-	DiskUsed      int64   `json:"disk_used"`
-	DiskPercent   float64 `json:"disk_percent"`
+	CPUCount       int     `json:"cpu_count"`        // Host cores
+	CPUPercent     float64 `json:"cpu_percent"`      // Runtime process, kept for compatibility
+	HostCPUPercent float64 `json:"host_cpu_percent"` // Whole host
+	MemoryTotal    int64   `json:"memory_total"`
+	MemoryUsed     int64   `json:"memory_used"`
+	MemoryPercent  float64 `json:"memory_percent"`
+	DiskTotal      int64   `json:"disk_total"`
+	DiskUsed       int64   `json:"disk_used"`
+	DiskPercent    float64 `json:"disk_percent"`
+	IOReadRate     float64 `json:"io_read_bytes_per_sec"`  // Runtime process, see io_sample_interval_seconds
+	IOWriteRate    float64 `json:"io_write_bytes_per_sec"` // Runtime process, see io_sample_interval_seconds
 }
 
 // ServerInfoResponse represents the server info response that matches Python implementation
@@ -170,6 +394,7 @@ type ServerInfoResponse struct {
 	Uptime    float64         `json:"uptime"`
 	IdleTime  float64         `json:"idle_time"`
 	Resources SystemResources `json:"resources"`
+	Version   string          `json:"version,omitempty"` // Build version of the runtime
 }
 
 // ServerInfo represents server information
@@ -189,10 +414,12 @@ type ServerInfo struct {
 
 // SystemStats represents system statistics that match Python's get_system_stats output
 type SystemStats struct {
-	CPUPercent float64     `json:"cpu_percent"`
-	Memory     MemoryStats `json:"memory"`
-	Disk       DiskStats   `json:"disk"`
-	IO         IOStats     `json:"io"`
+	CPUPercent     float64     `json:"cpu_percent"`               // CPU usage of the runtime process
+	HostCPUPercent float64     `json:"host_cpu_percent"`          // CPU usage of the whole host across all cores
+	PerCPUPercent  []float64   `json:"per_cpu_percent,omitempty"` // Host CPU usage per core, see per_cpu_stats
+	Memory         MemoryStats `json:"memory"`
+	Disk           DiskStats   `json:"disk"`
+	IO             IOStats     `json:"io"`
 }
 
 // MemoryStats represents memory usage statistics
@@ -200,6 +427,10 @@ type MemoryStats struct {
 	RSS     uint64  `json:"rss"`     // Resident Set Size in bytes
 	VMS     uint64  `json:"vms"`     // Virtual Memory Size in bytes
 	Percent float32 `json:"percent"` // Memory usage percentage
+
+	// Host memory, as opposed to the process figures above
+	Total     uint64 `json:"total,omitempty"`     // Total host memory in bytes
+	Available uint64 `json:"available,omitempty"` // Host memory available for new processes in bytes
 }
 
 // DiskStats represents disk usage statistics
@@ -214,6 +445,17 @@ type DiskStats struct {
 type IOStats struct {
 	ReadBytes  uint64 `json:"read_bytes"`  // Total bytes read
 	WriteBytes uint64 `json:"write_bytes"` // Total bytes written
+
+	// Throughput over the last sampling interval, see io_sample_interval_seconds
+	ReadBytesPerSec  float64 `json:"read_bytes_per_sec"`
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec"`
+}
+
+// ReadinessCheck is the outcome of one of the self-tests run by /ready
+type ReadinessCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
 }
 
 // UploadResponse represents file upload response
@@ -228,7 +470,7 @@ type VSCodeConnectionToken struct {
 }
 
 // NewIPythonRunCellObservation creates a new IPython cell execution output observation
-func NewIPythonRunCellObservation(content string, code string, imageURLs []string) Observation[IPythonExtras] {
+func NewIPythonRunCellObservation(content string, code string, imageURLs []string, outputs []IPythonOutput) Observation[IPythonExtras] {
 	return Observation[IPythonExtras]{
 		Observation: "run_ipython",
 		Content:     content,
@@ -236,6 +478,7 @@ func NewIPythonRunCellObservation(content string, code string, imageURLs []strin
 		Extras: IPythonExtras{
 			Code:      code,
 			ImageURLs: imageURLs,
+			Outputs:   outputs,
 		},
 	}
 }
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateContent(t *testing.T) {
+	t.Run("short content is unchanged", func(t *testing.T) {
+		content, truncated := TruncateContent("hello", 100)
+		assert.False(t, truncated)
+		assert.Equal(t, "hello", content)
+	})
+
+	t.Run("zero disables truncation", func(t *testing.T) {
+		content, truncated := TruncateContent(strings.Repeat("a", 1000), 0)
+		assert.False(t, truncated)
+		assert.Len(t, content, 1000)
+	})
+
+	t.Run("keeps head and tail", func(t *testing.T) {
+		content, truncated := TruncateContent(strings.Repeat("a", 500)+strings.Repeat("b", 500), 200)
+		assert.True(t, truncated)
+		assert.LessOrEqual(t, len(content), 200)
+		assert.True(t, strings.HasPrefix(content, "aaa"))
+		assert.True(t, strings.HasSuffix(content, "bbb"))
+		assert.Contains(t, content, TruncationMarker)
+	})
+
+	t.Run("does not split multibyte characters", func(t *testing.T) {
+		content, truncated := TruncateContent(strings.Repeat("é", 500), 201)
+		assert.True(t, truncated)
+		assert.True(t, utf8.ValidString(content))
+	})
+}
+
+func TestWithTruncatedContent(t *testing.T) {
+	obs := WithTruncatedContent(NewFileReadObservation(strings.Repeat("x", 1000), "big.txt"), 100)
+
+	readObs, ok := obs.(Observation[FileReadExtras])
+	require.True(t, ok, "expected FileReadObservation, got %T", obs)
+	assert.LessOrEqual(t, len(readObs.Content), 100)
+	assert.True(t, readObs.Extras.Truncated)
+	assert.Equal(t, "big.txt", readObs.Extras.Path)
+
+	errObs := WithTruncatedContent(NewErrorObservation(strings.Repeat("x", 1000), "Boom"), 100)
+	assert.LessOrEqual(t, len(errObs.(Observation[ErrorExtras]).Content), 100)
+
+	t.Run("command timeline", func(t *testing.T) {
+		cmdObs := NewCmdOutputObservation("", 0, "", "yes")
+		for i := 0; i < 1000; i++ {
+			cmdObs.Extras.Timeline = append(cmdObs.Extras.Timeline, OutputLine{Stream: "stdout", Line: fmt.Sprintf("y%d", i)})
+		}
+
+		truncated := WithTruncatedContent(cmdObs, 1000).(Observation[CmdOutputExtras])
+		timeline := truncated.Extras.Timeline
+		require.NotEmpty(t, timeline)
+		assert.Less(t, len(timeline), 1000/timelineLineOverhead+1)
+		assert.Equal(t, "y0", timeline[0].Line)
+		assert.Equal(t, "y999", timeline[len(timeline)-1].Line)
+		assert.True(t, truncated.Extras.TimelineTruncated)
+		assert.False(t, truncated.Extras.Truncated, "the content fits")
+		assert.Len(t, cmdObs.Extras.Timeline, 1000, "the original observation is unchanged")
+	})
+}
@@ -0,0 +1,128 @@
+package models
+
+import "unicode/utf8"
+
+// TruncationMarker replaces the middle of observation content that exceeds the size limit
+const TruncationMarker = "\n[... Observation truncated due to length ...]\n"
+
+// TruncateContent shortens content to at most maxBytes by keeping its head and tail
+// around TruncationMarker. Cuts never split a UTF-8 sequence.
+func TruncateContent(content string, maxBytes int) (string, bool) {
+	if maxBytes <= 0 || len(content) <= maxBytes {
+		return content, false
+	}
+
+	half := (maxBytes - len(TruncationMarker)) / 2
+	if half <= 0 {
+		return content[:runeStart(content, maxBytes)], true
+	}
+
+	head := runeStart(content, half)
+	tail := len(content) - half
+	for tail < len(content) && !utf8.RuneStart(content[tail]) {
+		tail++
+	}
+	return content[:head] + TruncationMarker + content[tail:], true
+}
+
+// runeStart moves i back to the start of the UTF-8 sequence it falls in
+func runeStart(s string, i int) int {
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// timelineLineOverhead approximates the bytes a timeline line takes when encoded besides its text
+const timelineLineOverhead = 64
+
+// TruncateTimeline shortens a timeline to about maxBytes when encoded, counting timelineLineOverhead
+// bytes per line, by keeping its first and last lines
+func TruncateTimeline(lines []OutputLine, maxBytes int) ([]OutputLine, bool) {
+	size := func(line OutputLine) int { return len(line.Line) + timelineLineOverhead }
+
+	total := 0
+	for _, line := range lines {
+		total += size(line)
+	}
+	if maxBytes <= 0 || total <= maxBytes {
+		return lines, false
+	}
+
+	head, headBudget := 0, maxBytes/2
+	for head < len(lines) && size(lines[head]) <= headBudget {
+		headBudget -= size(lines[head])
+		head++
+	}
+	tail, tailBudget := len(lines), maxBytes/2
+	for tail > head && size(lines[tail-1]) <= tailBudget {
+		tailBudget -= size(lines[tail-1])
+		tail--
+	}
+
+	kept := make([]OutputLine, 0, head+len(lines)-tail)
+	kept = append(kept, lines[:head]...)
+	return append(kept, lines[tail:]...), true
+}
+
+// extrasTruncator is implemented by extras that hold output besides the observation's content
+type extrasTruncator interface {
+	truncateExtras(maxBytes int)
+}
+
+func (e *CmdOutputExtras) truncateExtras(maxBytes int) {
+	if timeline, truncated := TruncateTimeline(e.Timeline, maxBytes); truncated {
+		e.Timeline, e.TimelineTruncated = timeline, true
+	}
+}
+
+// truncationFlagger is implemented by extras that report whether their observation's content was cut
+type truncationFlagger interface {
+	markTruncated()
+}
+
+// truncationExempter is implemented by extras whose observation's content must be kept whole
+type truncationExempter interface {
+	exemptFromTruncation() bool
+}
+
+func (e *FileReadExtras) exemptFromTruncation() bool { return e.Media }
+
+func (e *CmdOutputExtras) markTruncated() { e.Truncated = true }
+func (e *FileReadExtras) markTruncated()  { e.Truncated = true }
+
+// WithTruncatedContent returns a copy of the observation whose content, and output in its extras,
+// fit in maxBytes. Media such as images is left whole, since a cut data URL can't be decoded.
+func (o Observation[T]) WithTruncatedContent(maxBytes int) interface{} {
+	if exempter, ok := any(&o.Extras).(truncationExempter); ok && exempter.exemptFromTruncation() {
+		return o
+	}
+	if truncator, ok := any(&o.Extras).(extrasTruncator); ok {
+		truncator.truncateExtras(maxBytes)
+	}
+
+	content, truncated := TruncateContent(o.Content, maxBytes)
+	if !truncated {
+		return o
+	}
+	o.Content = content
+	if flagger, ok := any(&o.Extras).(truncationFlagger); ok {
+		flagger.markTruncated()
+	}
+	return o
+}
+
+// WithTruncatedContent returns a copy of the observation whose content fits in maxBytes
+func (o BasicObservation) WithTruncatedContent(maxBytes int) interface{} {
+	o.Content, _ = TruncateContent(o.Content, maxBytes)
+	return o
+}
+
+// WithTruncatedContent limits the content of an observation of any type to maxBytes.
+// Values that aren't observations are returned unchanged.
+func WithTruncatedContent(observation interface{}, maxBytes int) interface{} {
+	if o, ok := observation.(interface{ WithTruncatedContent(int) interface{} }); ok {
+		return o.WithTruncatedContent(maxBytes)
+	}
+	return observation
+}
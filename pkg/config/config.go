@@ -1,9 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
@@ -12,44 +16,121 @@ type Config struct {
 	Server    ServerConfig    `mapstructure:"server"`
 	Telemetry TelemetryConfig `mapstructure:"telemetry"`
 	Log       LogConfig       `mapstructure:"log"`
+	MCP       MCPConfig       `mapstructure:"mcp"`
 }
 
 // ServerConfig contains server-specific configuration
 type ServerConfig struct {
-	Port               int      `mapstructure:"port"`
-	WorkingDir         string   `mapstructure:"working_dir"`
-	Plugins            []string `mapstructure:"plugins"`
-	Username           string   `mapstructure:"username"`
-	UserID             int      `mapstructure:"user_id"`
-	BrowserGymEvalEnv  string   `mapstructure:"browsergym_eval_env"`
-	SessionAPIKey      string   `mapstructure:"session_api_key"`
-	FileViewerPort     int      `mapstructure:"file_viewer_port"`
-	MaxMemoryGB        int      `mapstructure:"max_memory_gb"`
-	NoChangeTimeoutSec int      `mapstructure:"no_change_timeout_seconds"`
-	MaxFileSize        int64    `mapstructure:"max_file_size"`
+	Port                       int      `mapstructure:"port"`
+	WorkingDir                 string   `mapstructure:"working_dir"`
+	Plugins                    []string `mapstructure:"plugins"`
+	Username                   string   `mapstructure:"username"`
+	UserID                     int      `mapstructure:"user_id"`
+	BrowserGymEvalEnv          string   `mapstructure:"browsergym_eval_env"`
+	SessionAPIKey              string   `mapstructure:"session_api_key"`
+	FileViewerPort             int      `mapstructure:"file_viewer_port"`
+	VSCodePort                 int      `mapstructure:"vscode_port"`
+	JupyterPort                int      `mapstructure:"jupyter_port"`
+	MaxMemoryGB                int      `mapstructure:"max_memory_gb"`
+	NoChangeTimeoutSec         int      `mapstructure:"no_change_timeout_seconds"`
+	MaxFileSize                int64    `mapstructure:"max_file_size"`
+	MaxReadLines               int      `mapstructure:"max_read_lines"`
+	AllowAbsolutePaths         bool     `mapstructure:"allow_absolute_paths"`
+	Shell                      string   `mapstructure:"shell"`
+	CmdErrorOnNonzero          bool     `mapstructure:"cmd_error_on_nonzero"`
+	MaxConcurrentFileOps       int      `mapstructure:"max_concurrent_file_ops"`
+	MinFreeDiskBytes           uint64   `mapstructure:"min_free_disk_bytes"`
+	IPythonTimeoutSec          int      `mapstructure:"ipython_timeout_seconds"`
+	IPythonMatplotlibInline    bool     `mapstructure:"ipython_matplotlib_inline"`
+	EnableRemoteShutdown       bool     `mapstructure:"enable_remote_shutdown"`
+	MaxObservationContentBytes int      `mapstructure:"max_observation_content_bytes"`
+	EnvRedactPatterns          []string `mapstructure:"env_redact_patterns"`
+	TimestampCommandOutput     bool     `mapstructure:"timestamp_command_output"`
+	PersistSessionState        bool     `mapstructure:"persist_session_state"`
+	SessionStatePath           string   `mapstructure:"session_state_path"`
+	PerCPUStats                bool     `mapstructure:"per_cpu_stats"`
+	IOSampleIntervalSec        int      `mapstructure:"io_sample_interval_seconds"`
+	StripANSI                  bool     `mapstructure:"strip_ansi"`
+	CommandOutputEncoding      string   `mapstructure:"command_output_encoding"`
+	ZipWorkers                 int      `mapstructure:"zip_workers"`
+	TempDir                    string   `mapstructure:"temp_dir"`
+	SummarizeInstallOutput     bool     `mapstructure:"summarize_install_output"`
+	StreamStatusIntervalSec    int      `mapstructure:"stream_status_interval_seconds"`
+	DisabledActions            []string `mapstructure:"disabled_actions"`
+	DisabledEndpoints          []string `mapstructure:"disabled_endpoints"`
+	UnixSocket                 string   `mapstructure:"unix_socket"`
+	UnixSocketOnly             bool     `mapstructure:"unix_socket_only"`
+	EnablePprof                bool     `mapstructure:"enable_pprof"`
+	MaxSSEConnections          int      `mapstructure:"max_sse_connections"`
+	MaxWatchedPaths            int      `mapstructure:"max_watched_paths"`
+	BackupOnEdit               bool     `mapstructure:"backup_on_edit"`
+	BackupDir                  string   `mapstructure:"backup_dir"`
+	MaxBackupsPerFile          int      `mapstructure:"max_backups_per_file"`
+	VSCodeConnectionToken      string   `mapstructure:"vscode_connection_token"`
 }
 
 // TelemetryConfig contains telemetry configuration
 type TelemetryConfig struct {
-	Enabled  bool   `mapstructure:"enabled"`
-	Endpoint string `mapstructure:"endpoint"`
+	Enabled            bool              `mapstructure:"enabled"`
+	Endpoint           string            `mapstructure:"endpoint"`
+	MaxQueueSize       int               `mapstructure:"max_queue_size"`
+	ExportTimeoutSec   int               `mapstructure:"export_timeout_seconds"`
+	ServiceName        string            `mapstructure:"service_name"`
+	ServiceVersion     string            `mapstructure:"service_version"`
+	ResourceAttributes map[string]string `mapstructure:"resource_attributes"`
 }
 
 // LogConfig contains logging configuration
 type LogConfig struct {
-	Level string `mapstructure:"level"`
-	JSON  bool   `mapstructure:"json"`
+	Level           string   `mapstructure:"level"`
+	JSON            bool     `mapstructure:"json"`
+	LogBodies       bool     `mapstructure:"log_bodies"`
+	MaxBodyLogBytes int      `mapstructure:"max_body_log_bytes"`
+	RedactPatterns  []string `mapstructure:"redact_patterns"`
+	RedactFields    []string `mapstructure:"redact_fields"`
+}
+
+// MCPConfig contains configuration of the MCP server
+type MCPConfig struct {
+	Tools []MCPToolConfig `mapstructure:"tools"`
+}
+
+// MCPToolConfig defines an extra MCP tool running a shell command. The command refers to the tool's
+// arguments as {{name}} placeholders, every one of which is a required string argument.
+type MCPToolConfig struct {
+	Name        string            `mapstructure:"name"`
+	Description string            `mapstructure:"description"`
+	Command     string            `mapstructure:"command"`
+	Arguments   map[string]string `mapstructure:"arguments"` // Argument descriptions, by name
 }
 
 // Load loads the configuration from viper
 func Load() (*Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// load reads the configuration viper holds without validating it
+func load() (*Config, error) {
 	cfg := &Config{}
 
 	// Set defaults
 	setDefaults()
 
-	// Unmarshal configuration
-	if err := viper.Unmarshal(cfg); err != nil {
+	// Unmarshal configuration, accepting key=value lists for maps as well as comma-separated slices
+	if err := viper.Unmarshal(cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringToMapHookFunc,
+	))); err != nil {
 		return nil, err
 	}
 
@@ -66,23 +147,149 @@ func setDefaults() {
 	viper.SetDefault("server.port", 8000)
 	viper.SetDefault("server.username", "openhands")
 	viper.SetDefault("server.user_id", 1000)
-	viper.SetDefault("server.file_viewer_port", 0) // Auto-assign
+	viper.SetDefault("server.file_viewer_port", 0) // Auto-assign; -1 disables the viewer
+	viper.SetDefault("server.vscode_port", 0)      // Not running
+	viper.SetDefault("server.jupyter_port", 0)     // Not running
 	viper.SetDefault("server.max_memory_gb", 0)    // No limit
 	viper.SetDefault("server.no_change_timeout_seconds", 10)
 	viper.SetDefault("server.max_file_size", 50*1024) // 50KB
+	viper.SetDefault("server.max_read_lines", 2000)   // 0 disables the cap
+	viper.SetDefault("server.allow_absolute_paths", false)
+	viper.SetDefault("server.shell", "bash")
+	viper.SetDefault("server.cmd_error_on_nonzero", false)
+	viper.SetDefault("server.max_concurrent_file_ops", 16)        // 0 disables the limit
+	viper.SetDefault("server.min_free_disk_bytes", 100*1024*1024) // 100MB, 0 disables the check
+	viper.SetDefault("server.ipython_timeout_seconds", 60)
+	viper.SetDefault("server.ipython_matplotlib_inline", true)
+	viper.SetDefault("server.enable_remote_shutdown", false)
+	viper.SetDefault("server.max_observation_content_bytes", 1024*1024) // 1MB, 0 disables truncation
+	viper.SetDefault("server.env_redact_patterns", []string{"*KEY*", "*TOKEN*", "*SECRET*", "*PASSWORD*"})
+	viper.SetDefault("server.timestamp_command_output", false)
+	viper.SetDefault("server.persist_session_state", false)
+	viper.SetDefault("server.session_state_path", "") // Defaults to a file under the user configuration directory, outside the workspace
+	viper.SetDefault("server.per_cpu_stats", false)
+	viper.SetDefault("server.io_sample_interval_seconds", 5) // 0 disables IO rate sampling
+	viper.SetDefault("server.strip_ansi", true)
+	viper.SetDefault("server.command_output_encoding", "")        // Empty uses the charset of the session locale, UTF-8 if none
+	viper.SetDefault("server.zip_workers", 4)                     // Files compressed in parallel for downloads; 0 or 1 compresses sequentially
+	viper.SetDefault("server.temp_dir", "")                       // Scratch space, e.g. for notebooks; defaults to .openhands_tmp in the working directory
+	viper.SetDefault("server.summarize_install_output", false)    // Replace the output of package installs with a short summary
+	viper.SetDefault("server.stream_status_interval_seconds", 30) // Interval of status events in streamed command output and MCP progress notifications; 0 disables
+	viper.SetDefault("server.disabled_actions", []string{})       // Action types refused with an ActionDisabledError observation
+	viper.SetDefault("server.disabled_endpoints", []string{})     // Routes left unregistered, so they answer 404
+	viper.SetDefault("server.unix_socket", "")                    // Also listen on this Unix domain socket
+	viper.SetDefault("server.unix_socket_only", false)            // Listen on server.unix_socket instead of the TCP port
+	viper.SetDefault("server.enable_pprof", false)                // Serve net/http/pprof profiles under /debug/pprof
+	viper.SetDefault("server.max_sse_connections", 64)            // Further /sse connections are refused with 503; 0 for no limit
+	viper.SetDefault("server.max_watched_paths", 64)              // Further /watch streams are refused with 503; 0 for no limit
+	viper.SetDefault("server.backup_on_edit", false)
+	viper.SetDefault("server.backup_dir", "")              // Defaults to backups in the session state directory, outside the workspace
+	viper.SetDefault("server.max_backups_per_file", 10)    // Older backups of a file are pruned; 0 for no limit
+	viper.SetDefault("server.vscode_connection_token", "") // The token the VSCode server was started with
 
 	// Telemetry defaults
 	viper.SetDefault("telemetry.enabled", true)
+	viper.SetDefault("telemetry.max_queue_size", 2048)       // Spans and log records waiting for export; more are dropped
+	viper.SetDefault("telemetry.export_timeout_seconds", 10) // Exports to an unresponsive collector give up after this
+	viper.SetDefault("telemetry.service_name", "openhands-runtime")
+	viper.SetDefault("telemetry.service_version", "")                      // Empty reports the build version
+	viper.SetDefault("telemetry.resource_attributes", map[string]string{}) // Extra resource attributes, e.g. to tell runtimes apart
 
 	// Log defaults
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("log.json", false)
+	viper.SetDefault("log.log_bodies", false)        // Log JSON request and response bodies, with secrets redacted
+	viper.SetDefault("log.max_body_log_bytes", 4096) // Logged bodies are cut at this size; 0 logs them whole
+	// Secrets masked in logs and telemetry: regular expressions, masking their first group if they have one,
+	// and globs of field or variable names whose values are masked
+	viper.SetDefault("log.redact_patterns", []string{
+		`(?i)\bbearer\s+([A-Za-z0-9._~+/-]+=*)`,
+		`\bgh[pousr]_[A-Za-z0-9]{36,}`,
+		`\bsk-[A-Za-z0-9_-]{20,}`,
+		`\bAKIA[0-9A-Z]{16}\b`,
+	})
+	viper.SetDefault("log.redact_fields", []string{"*KEY*", "*TOKEN*", "*SECRET*", "*PASSWORD*"})
+
+	bindEnv()
+}
+
+// envAliases lists additional, legacy environment variable names for config keys.
+// They are consulted after the systematic SECTION_KEY name.
+var envAliases = map[string][]string{
+	"server.session_api_key":           {"SESSION_API_KEY"},
+	"server.max_memory_gb":             {"RUNTIME_MAX_MEMORY_GB"},
+	"server.no_change_timeout_seconds": {"NO_CHANGE_TIMEOUT_SECONDS"},
+	"server.vscode_port":               {"VSCODE_PORT"},
+	"server.jupyter_port":              {"JUPYTER_PORT"},
+	"telemetry.endpoint":               {"OTEL_EXPORTER_OTLP_ENDPOINT"},
+}
 
-	// Environment variable mappings
-	_ = viper.BindEnv("server.session_api_key", "SESSION_API_KEY")
-	_ = viper.BindEnv("server.max_memory_gb", "RUNTIME_MAX_MEMORY_GB")
-	_ = viper.BindEnv("server.no_change_timeout_seconds", "NO_CHANGE_TIMEOUT_SECONDS")
-	_ = viper.BindEnv("telemetry.endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT")
+// bindEnv binds config keys to environment variables named after them in upper case
+// with dots replaced by underscores, e.g. server.max_memory_gb is SERVER_MAX_MEMORY_GB.
+// Slices such as server.plugins are given as comma-separated values. Keys that can't be
+// written as a string, like the list of tools in mcp.tools, are left to the config file.
+func bindEnv() {
+	for _, key := range configKeys() {
+		_ = viper.BindEnv(append([]string{key, EnvVarName(key)}, envAliases[key]...)...)
+	}
+}
+
+// EnvVarName returns the environment variable that overrides the given config key
+func EnvVarName(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configKeys returns the keys of the config fields that an environment variable can set, e.g. server.port:
+// those holding a scalar, a list of scalars or a map of strings
+func configKeys() []string {
+	var keys []string
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		section := configType.Field(i)
+		for j := 0; j < section.Type.NumField(); j++ {
+			field := section.Type.Field(j)
+			if !settableFromString(field.Type) {
+				continue
+			}
+			keys = append(keys, section.Tag.Get("mapstructure")+"."+field.Tag.Get("mapstructure"))
+		}
+	}
+	return keys
+}
+
+// settableFromString reports whether a config field of type t can be decoded from a single string
+func settableFromString(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return false
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Struct && t.Elem().Kind() != reflect.Map && t.Elem().Kind() != reflect.Slice
+	case reflect.Map:
+		return t == reflect.TypeOf(map[string]string{})
+	default:
+		return true
+	}
+}
+
+// stringToMapHookFunc decodes maps given as a single string, such as from an environment variable,
+// in the key1=value1,key2=value2 format of OTEL_RESOURCE_ATTRIBUTES
+func stringToMapHookFunc(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(map[string]string{}) {
+		return data, nil
+	}
+
+	values := map[string]string{}
+	for _, pair := range strings.Split(data.(string), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid key=value pair %q", pair)
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return values, nil
 }
 
 func postProcess(cfg *Config) error {
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	workingDir := t.TempDir()
+	t.Setenv("SERVER_WORKING_DIR", workingDir)
+	t.Setenv("SERVER_MAX_MEMORY_GB", "4")
+	t.Setenv("SERVER_PLUGINS", "jupyter,vscode")
+	t.Setenv("SERVER_ALLOW_ABSOLUTE_PATHS", "true")
+	t.Setenv("TELEMETRY_ENABLED", "false")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("SESSION_API_KEY", "legacy-key")
+	t.Setenv("TELEMETRY_RESOURCE_ATTRIBUTES", "conversation.id=abc123, host.role=worker")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, workingDir, cfg.Server.WorkingDir)
+	assert.Equal(t, 4, cfg.Server.MaxMemoryGB)
+	assert.Equal(t, []string{"jupyter", "vscode"}, cfg.Server.Plugins)
+	assert.True(t, cfg.Server.AllowAbsolutePaths)
+	assert.False(t, cfg.Telemetry.Enabled)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, "legacy-key", cfg.Server.SessionAPIKey)
+	assert.Equal(t, map[string]string{"conversation.id": "abc123", "host.role": "worker"}, cfg.Telemetry.ResourceAttributes)
+	assert.Equal(t, "openhands-runtime", cfg.Telemetry.ServiceName)
+}
+
+func TestLoad_EnvOverridesTakePrecedenceOverAliases(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	t.Setenv("SERVER_WORKING_DIR", t.TempDir())
+	t.Setenv("RUNTIME_MAX_MEMORY_GB", "2")
+	t.Setenv("SERVER_MAX_MEMORY_GB", "8")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 8, cfg.Server.MaxMemoryGB)
+}
+
+func TestLoad_EnvSkipsKeysThatArentStrings(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	t.Setenv("SERVER_WORKING_DIR", t.TempDir())
+	t.Setenv("MCP_TOOLS", "lint")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.MCP.Tools)
+	assert.NotContains(t, configKeys(), "mcp.tools")
+}
+
+func TestEnvVarName(t *testing.T) {
+	assert.Equal(t, "SERVER_MAX_MEMORY_GB", EnvVarName("server.max_memory_gb"))
+	assert.Equal(t, "TELEMETRY_ENDPOINT", EnvVarName("telemetry.endpoint"))
+}
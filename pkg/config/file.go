@@ -0,0 +1,47 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// configFileExts lists the supported config file extensions in search order.
+// The empty extension matches an extensionless file, which is read as YAML.
+var configFileExts = []string{".yaml", ".yml", ".json", ".toml", ""}
+
+// FindConfigFile returns the first config file named name with a supported extension in dirs,
+// or an empty string if there is none
+func FindConfigFile(name string, dirs ...string) string {
+	for _, dir := range dirs {
+		for _, ext := range configFileExts {
+			path := filepath.Join(dir, name+ext)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// ReadConfigFile reads the config file at path into viper.
+// The format is detected from the extension, defaulting to YAML.
+func ReadConfigFile(path string) error {
+	viper.SetConfigFile(path)
+	viper.SetConfigType(configType(path))
+	return viper.ReadInConfig()
+}
+
+// configType returns the viper config type for a config file path
+func configType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".toml":
+		return "toml"
+	default:
+		return "yaml"
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "json",
+			file:    "config.json",
+			content: `{"server": {"port": 9100, "max_read_lines": 42}, "log": {"level": "debug"}}`,
+		},
+		{
+			name:    "toml",
+			file:    "config.toml",
+			content: "[server]\nport = 9100\nmax_read_lines = 42\n\n[log]\nlevel = \"debug\"\n",
+		},
+		{
+			name:    "extensionless yaml",
+			file:    ".openhands-runtime",
+			content: "server:\n  port: 9100\n  max_read_lines: 42\nlog:\n  level: debug\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+			viper.Set("server.working_dir", dir)
+
+			require.NoError(t, ReadConfigFile(path))
+			cfg, err := Load()
+			require.NoError(t, err)
+
+			assert.Equal(t, 9100, cfg.Server.Port)
+			assert.Equal(t, 42, cfg.Server.MaxReadLines)
+			assert.Equal(t, "debug", cfg.Log.Level)
+			assert.Equal(t, "openhands", cfg.Server.Username, "defaults still apply")
+		})
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	assert.Empty(t, FindConfigFile(".openhands-runtime", first, second))
+
+	require.NoError(t, os.WriteFile(filepath.Join(second, ".openhands-runtime.json"), []byte("{}"), 0644))
+	assert.Equal(t, filepath.Join(second, ".openhands-runtime.json"), FindConfigFile(".openhands-runtime", first, second))
+
+	require.NoError(t, os.WriteFile(filepath.Join(first, ".openhands-runtime"), nil, 0644))
+	assert.Equal(t, filepath.Join(first, ".openhands-runtime"), FindConfigFile(".openhands-runtime", first, second))
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// mcpToolNamePattern matches valid MCP tool names
+var mcpToolNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// placeholderPattern matches the {{name}} argument placeholders of an MCP tool command
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Placeholders returns the names of the arguments the tool's command refers to, in order of first use
+func (t MCPToolConfig) Placeholders() ([]string, error) {
+	if rest := placeholderPattern.ReplaceAllString(t.Command, ""); strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+		return nil, fmt.Errorf("malformed placeholder in command %q", t.Command)
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(t.Command, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names, nil
+}
+
+// Expand replaces the placeholders of the tool's command with the quote function applied to the
+// values of the arguments they name
+func (t MCPToolConfig) Expand(arguments map[string]string, quote func(string) string) string {
+	return placeholderPattern.ReplaceAllStringFunc(t.Command, func(placeholder string) string {
+		return quote(arguments[placeholderPattern.FindStringSubmatch(placeholder)[1]])
+	})
+}
+
+// validateMCPTools checks that the extra MCP tools have unique valid names and well-formed commands
+func validateMCPTools(tools []MCPToolConfig) []error {
+	var errs []error
+	names := make(map[string]bool)
+	for i, tool := range tools {
+		key := fmt.Sprintf("mcp.tools[%d]", i)
+		if !mcpToolNamePattern.MatchString(tool.Name) {
+			errs = append(errs, fmt.Errorf("%s: name %q must be 1 to 64 letters, digits, _ or -", key, tool.Name))
+		} else if names[tool.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate tool name %q", key, tool.Name))
+		}
+		names[tool.Name] = true
+
+		if strings.TrimSpace(tool.Command) == "" {
+			errs = append(errs, fmt.Errorf("%s: command must not be empty", key))
+			continue
+		}
+		placeholders, err := tool.Placeholders()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		for argument := range tool.Arguments {
+			if !containsString(placeholders, argument) {
+				errs = append(errs, fmt.Errorf("%s: argument %q is not used in the command", key, argument))
+			}
+		}
+	}
+	return errs
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// hotReloadable lists the config keys that take effect without a restart.
+// Everything else (port, working_dir, plugins, ...) is only read at startup.
+var hotReloadable = map[string]bool{
+	"server.no_change_timeout_seconds":      true,
+	"server.max_file_size":                  true,
+	"server.max_read_lines":                 true,
+	"server.allow_absolute_paths":           true,
+	"server.cmd_error_on_nonzero":           true,
+	"server.min_free_disk_bytes":            true,
+	"server.ipython_timeout_seconds":        true,
+	"server.ipython_matplotlib_inline":      true,
+	"server.max_observation_content_bytes":  true,
+	"server.timestamp_command_output":       true,
+	"server.strip_ansi":                     true,
+	"server.command_output_encoding":        true,
+	"server.zip_workers":                    true,
+	"server.temp_dir":                       true,
+	"server.summarize_install_output":       true,
+	"server.stream_status_interval_seconds": true,
+	"server.disabled_actions":               true,
+	"log.level":                             true,
+	"log.json":                              true,
+	"log.log_bodies":                        true,
+	"log.max_body_log_bytes":                true,
+}
+
+// Holder provides concurrent access to a Config whose hot-reloadable settings may change at runtime
+type Holder struct {
+	mu  sync.Mutex
+	cfg atomic.Pointer[Config]
+}
+
+// NewHolder returns a holder for the given config
+func NewHolder(cfg *Config) *Holder {
+	h := &Holder{}
+	h.cfg.Store(cfg)
+	return h
+}
+
+// Get returns the current config. Callers must not modify it.
+func (h *Holder) Get() *Config {
+	return h.cfg.Load()
+}
+
+// Reload applies the hot-reloadable settings of next and returns the keys that changed.
+// Keys that differ but require a restart are left untouched and returned as ignored.
+func (h *Holder) Reload(next *Config) (changed, ignored []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	updated := *h.cfg.Load()
+	current := reflect.ValueOf(&updated).Elem()
+	incoming := reflect.ValueOf(next).Elem()
+
+	for i := 0; i < current.NumField(); i++ {
+		section := current.Type().Field(i).Tag.Get("mapstructure")
+		currentSection, incomingSection := current.Field(i), incoming.Field(i)
+
+		for j := 0; j < currentSection.NumField(); j++ {
+			key := section + "." + currentSection.Type().Field(j).Tag.Get("mapstructure")
+			oldValue, newValue := currentSection.Field(j), incomingSection.Field(j)
+			if reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+				continue
+			}
+			if !hotReloadable[key] {
+				ignored = append(ignored, key)
+				continue
+			}
+			oldValue.Set(newValue)
+			changed = append(changed, key)
+		}
+	}
+
+	h.cfg.Store(&updated)
+	return changed, ignored
+}
+
+// Watch re-reads the config file whenever it changes and applies the hot-reloadable settings to holder.
+// onReload, if not nil, is called after each successful reload. Watch does nothing when no config file is in use.
+func Watch(holder *Holder, logger logrus.FieldLogger, onReload func()) {
+	if viper.ConfigFileUsed() == "" {
+		return
+	}
+
+	viper.OnConfigChange(func(event fsnotify.Event) {
+		// viper has already re-read the file
+		applyReload(holder, logger, event.Name, onReload)
+	})
+	viper.WatchConfig()
+	logger.Infof("Watching config file %s for changes", viper.ConfigFileUsed())
+}
+
+// ReloadOnSignal re-reads the config file and environment whenever one of the signals is received,
+// applying the hot-reloadable settings to holder like Watch does. The returned function stops it.
+func ReloadOnSignal(holder *Holder, logger logrus.FieldLogger, onReload func(), signals ...os.Signal) (stop func()) {
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case sig := <-received:
+				logger.Infof("Received %v, reloading config", sig)
+				source := "the environment"
+				if configFile := viper.ConfigFileUsed(); configFile != "" {
+					if err := viper.ReadInConfig(); err != nil {
+						logger.Errorf("Ignoring unreadable config file %s: %v", configFile, err)
+						continue
+					}
+					source = configFile
+				}
+				applyReload(holder, logger, source, onReload)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(received)
+		close(done)
+	}
+}
+
+// applyReload loads the config viper currently holds and applies its hot-reloadable settings to holder.
+// Only those settings are validated: restart-only ones are ignored anyway.
+func applyReload(holder *Holder, logger logrus.FieldLogger, source string, onReload func()) {
+	next, err := load()
+	if err == nil {
+		err = next.validateHotReloadable()
+	}
+	if err != nil {
+		logger.Errorf("Ignoring invalid config change in %s: %v", source, err)
+		return
+	}
+
+	changed, ignored := holder.Reload(next)
+	for _, key := range changed {
+		logger.Infof("Config %s reloaded", key)
+	}
+	for _, key := range ignored {
+		logger.Warnf("Config %s changed but requires a restart to take effect", key)
+	}
+
+	if onReload != nil {
+		onReload()
+	}
+}
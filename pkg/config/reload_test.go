@@ -0,0 +1,92 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHolderReload(t *testing.T) {
+	holder := NewHolder(&Config{
+		Server: ServerConfig{Port: 8000, MaxReadLines: 2000},
+		Log:    LogConfig{Level: "info"},
+	})
+
+	changed, ignored := holder.Reload(&Config{
+		Server: ServerConfig{Port: 9000, MaxReadLines: 100},
+		Log:    LogConfig{Level: "debug"},
+	})
+
+	assert.ElementsMatch(t, []string{"server.max_read_lines", "log.level"}, changed)
+	assert.Equal(t, []string{"server.port"}, ignored)
+
+	cfg := holder.Get()
+	assert.Equal(t, 100, cfg.Server.MaxReadLines)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, 8000, cfg.Server.Port, "restart-only settings must not be reloaded")
+}
+
+func TestApplyReload_ValidatesHotReloadableSettingsOnly(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	workingDir := t.TempDir()
+	viper.Set("server.working_dir", workingDir)
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	holder := NewHolder(cfg)
+
+	// Invalid restart-only settings don't block hot changes, and aren't checked
+	movedDir := filepath.Join(workingDir, "moved")
+	viper.Set("server.working_dir", movedDir)
+	viper.Set("server.shell", "no-such-shell")
+	viper.Set("server.max_read_lines", 50)
+	applyReload(holder, logger, "test", nil)
+	assert.Equal(t, 50, holder.Get().Server.MaxReadLines)
+	assert.Equal(t, workingDir, holder.Get().Server.WorkingDir)
+	assert.NoDirExists(t, movedDir)
+
+	// Invalid hot-reloadable settings are rejected
+	viper.Set("server.max_read_lines", -1)
+	applyReload(holder, logger, "test", nil)
+	assert.Equal(t, 50, holder.Get().Server.MaxReadLines)
+}
+
+func TestWatch(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(configFile, []byte(content), 0644))
+	}
+	writeConfig("server:\n  port: 8000\n  working_dir: /tmp\n  max_read_lines: 2000\n")
+
+	viper.SetConfigFile(configFile)
+	require.NoError(t, viper.ReadInConfig())
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	holder := NewHolder(cfg)
+	Watch(holder, logger, nil)
+
+	writeConfig("server:\n  port: 9000\n  working_dir: /tmp\n  max_read_lines: 50\n")
+
+	// Writing the file can fire several events, the first ones possibly seeing it truncated
+	require.Eventually(t, func() bool {
+		return holder.Get().Server.MaxReadLines == 50
+	}, 5*time.Second, 10*time.Millisecond, "config change was not picked up")
+	assert.Equal(t, 8000, holder.Get().Server.Port)
+}
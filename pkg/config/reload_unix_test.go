@@ -0,0 +1,60 @@
+//go:build unix
+
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadOnSignal(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(configFile, []byte(content), 0644))
+	}
+	writeConfig("server:\n  port: 8000\n  working_dir: /tmp\nlog:\n  level: info\n")
+
+	viper.SetConfigFile(configFile)
+	require.NoError(t, viper.ReadInConfig())
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.InfoLevel)
+	holder := NewHolder(cfg)
+
+	// Re-apply the log level like the server command does
+	reloaded := make(chan struct{}, 1)
+	stop := ReloadOnSignal(holder, logger, func() {
+		if level, err := logrus.ParseLevel(holder.Get().Log.Level); err == nil {
+			logger.SetLevel(level)
+		}
+		reloaded <- struct{}{}
+	}, syscall.SIGHUP)
+	defer stop()
+
+	writeConfig("server:\n  port: 9000\n  working_dir: /tmp\nlog:\n  level: debug\n")
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config was not reloaded on SIGHUP")
+	}
+	assert.Equal(t, logrus.DebugLevel, logger.GetLevel())
+	assert.Equal(t, "debug", holder.Get().Log.Level)
+	assert.Equal(t, 8000, holder.Get().Server.Port, "restart-only settings must not be reloaded")
+}
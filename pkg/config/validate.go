@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/redact"
+)
+
+// Validate checks the configuration for values that would otherwise fail later at runtime.
+// All problems found are reported together.
+func (c *Config) Validate() error {
+	return c.validate(func(string) bool { return true })
+}
+
+// validateHotReloadable validates only the settings that can change without a restart,
+// so that a reload neither checks nor touches what it will not apply
+func (c *Config) validateHotReloadable() error {
+	return c.validate(func(key string) bool { return hotReloadable[key] })
+}
+
+// validate checks the settings whose keys are selected by check
+func (c *Config) validate(check func(key string) bool) error {
+	var errs []error
+
+	if check("server.port") && (c.Server.Port < 1 || c.Server.Port > 65535) {
+		errs = append(errs, fmt.Errorf("server.port must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	for _, port := range []struct {
+		key   string
+		value int
+	}{
+		{"server.file_viewer_port", c.Server.FileViewerPort},
+		{"server.vscode_port", c.Server.VSCodePort},
+		{"server.jupyter_port", c.Server.JupyterPort},
+	} {
+		if check(port.key) && port.value > 65535 {
+			errs = append(errs, fmt.Errorf("%s must be at most 65535, got %d", port.key, port.value))
+		}
+	}
+
+	for _, limit := range []struct {
+		key   string
+		value int64
+	}{
+		{"server.max_memory_gb", int64(c.Server.MaxMemoryGB)},
+		{"server.no_change_timeout_seconds", int64(c.Server.NoChangeTimeoutSec)},
+		{"server.max_file_size", c.Server.MaxFileSize},
+		{"server.max_read_lines", int64(c.Server.MaxReadLines)},
+		{"server.max_concurrent_file_ops", int64(c.Server.MaxConcurrentFileOps)},
+		{"server.ipython_timeout_seconds", int64(c.Server.IPythonTimeoutSec)},
+		{"server.max_observation_content_bytes", int64(c.Server.MaxObservationContentBytes)},
+		{"server.io_sample_interval_seconds", int64(c.Server.IOSampleIntervalSec)},
+		{"server.zip_workers", int64(c.Server.ZipWorkers)},
+		{"server.stream_status_interval_seconds", int64(c.Server.StreamStatusIntervalSec)},
+		{"server.max_sse_connections", int64(c.Server.MaxSSEConnections)},
+		{"server.max_watched_paths", int64(c.Server.MaxWatchedPaths)},
+		{"server.max_backups_per_file", int64(c.Server.MaxBackupsPerFile)},
+		{"log.max_body_log_bytes", int64(c.Log.MaxBodyLogBytes)},
+		{"telemetry.max_queue_size", int64(c.Telemetry.MaxQueueSize)},
+		{"telemetry.export_timeout_seconds", int64(c.Telemetry.ExportTimeoutSec)},
+	} {
+		if check(limit.key) && limit.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", limit.key, limit.value))
+		}
+	}
+
+	if check("log.redact_patterns") {
+		if _, err := redact.New(c.Log.RedactPatterns, c.Log.RedactFields); err != nil {
+			errs = append(errs, fmt.Errorf("log.redact_patterns: %w", err))
+		}
+	}
+
+	if check("mcp.tools") {
+		errs = append(errs, validateMCPTools(c.MCP.Tools)...)
+	}
+
+	if check("server.unix_socket_only") && c.Server.UnixSocketOnly && c.Server.UnixSocket == "" {
+		errs = append(errs, errors.New("server.unix_socket_only requires server.unix_socket"))
+	}
+
+	if check("server.shell") && c.Server.Shell != "" {
+		if _, err := exec.LookPath(c.Server.Shell); err != nil {
+			errs = append(errs, fmt.Errorf("server.shell %q not found: %w", c.Server.Shell, err))
+		}
+	}
+
+	if check("server.working_dir") {
+		if err := checkWritableDir(c.Server.WorkingDir); err != nil {
+			errs = append(errs, fmt.Errorf("server.working_dir %q is not usable: %w", c.Server.WorkingDir, err))
+		}
+	}
+
+	if tempDir := c.Server.TempDir; check("server.temp_dir") && tempDir != "" {
+		// Relative temp dirs are resolved against the working directory, as the executor does
+		if !filepath.IsAbs(tempDir) {
+			tempDir = filepath.Join(c.Server.WorkingDir, tempDir)
+		}
+		if err := checkWritableDir(tempDir); err != nil {
+			errs = append(errs, fmt.Errorf("server.temp_dir %q is not usable: %w", c.Server.TempDir, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkWritableDir creates dir if needed and verifies that files can be created in it
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, ".openhands-write-check-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
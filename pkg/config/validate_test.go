@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig(t *testing.T) *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:       8000,
+			WorkingDir: t.TempDir(),
+			Shell:      "sh",
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("valid config", func(t *testing.T) {
+		assert.NoError(t, validConfig(t).Validate())
+	})
+
+	t.Run("working dir is created", func(t *testing.T) {
+		cfg := validConfig(t)
+		cfg.Server.WorkingDir = filepath.Join(cfg.Server.WorkingDir, "nested", "workspace")
+		require.NoError(t, cfg.Validate())
+		assert.DirExists(t, cfg.Server.WorkingDir)
+	})
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{
+			name:    "negative port",
+			modify:  func(cfg *Config) { cfg.Server.Port = -1 },
+			wantErr: "server.port must be between 1 and 65535, got -1",
+		},
+		{
+			name:    "port out of range",
+			modify:  func(cfg *Config) { cfg.Server.Port = 70000 },
+			wantErr: "server.port must be between 1 and 65535, got 70000",
+		},
+		{
+			name:    "file viewer port out of range",
+			modify:  func(cfg *Config) { cfg.Server.FileViewerPort = 65536 },
+			wantErr: "server.file_viewer_port must be at most 65535, got 65536",
+		},
+		{
+			name:    "negative max concurrent file ops",
+			modify:  func(cfg *Config) { cfg.Server.MaxConcurrentFileOps = -5 },
+			wantErr: "server.max_concurrent_file_ops must not be negative, got -5",
+		},
+		{
+			name: "malformed MCP tool placeholder",
+			modify: func(cfg *Config) {
+				cfg.MCP.Tools = []MCPToolConfig{{Name: "run_tests", Command: "go test {{package}"}}
+			},
+			wantErr: `mcp.tools[0]: malformed placeholder in command "go test {{package}"`,
+		},
+		{
+			name: "MCP tool argument not in command",
+			modify: func(cfg *Config) {
+				cfg.MCP.Tools = []MCPToolConfig{{Name: "run_tests", Command: "go test ./...", Arguments: map[string]string{"package": "Package to test"}}}
+			},
+			wantErr: `mcp.tools[0]: argument "package" is not used in the command`,
+		},
+		{
+			name: "duplicate MCP tool",
+			modify: func(cfg *Config) {
+				cfg.MCP.Tools = []MCPToolConfig{{Name: "run_tests", Command: "make test"}, {Name: "run_tests", Command: "go test ./..."}}
+			},
+			wantErr: `mcp.tools[1]: duplicate tool name "run_tests"`,
+		},
+		{
+			name:    "missing shell",
+			modify:  func(cfg *Config) { cfg.Server.Shell = "no-such-shell" },
+			wantErr: `server.shell "no-such-shell" not found`,
+		},
+		{
+			name: "working dir below a file",
+			modify: func(cfg *Config) {
+				file := filepath.Join(cfg.Server.WorkingDir, "file")
+				require.NoError(t, os.WriteFile(file, nil, 0644))
+				cfg.Server.WorkingDir = filepath.Join(file, "workspace")
+			},
+			wantErr: "is not usable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("all problems are reported", func(t *testing.T) {
+		cfg := validConfig(t)
+		cfg.Server.Port = 0
+		cfg.Server.MaxReadLines = -1
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "server.port")
+		assert.Contains(t, err.Error(), "server.max_read_lines")
+	})
+}
@@ -0,0 +1,20 @@
+package executor
+
+import (
+	"context"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+)
+
+// executeThink acknowledges a thought the agent logged; thoughts have no effect on the runtime
+func (e *Executor) executeThink(ctx context.Context, action models.ThinkAction) (interface{}, error) {
+	e.logger.Debugf("Agent thought: %s", action.Thought)
+	return models.NewThinkObservation(action.Thought), nil
+}
+
+// executeRecall acknowledges a recall request. The runtime keeps no agent memory,
+// so the observation is empty and the agent falls back to its own context.
+func (e *Executor) executeRecall(ctx context.Context, action models.RecallAction) (interface{}, error) {
+	e.logger.Debugf("Agent recall (%s): %s", action.RecallType, action.Query)
+	return models.NewRecallObservation("", action.Query, action.RecallType), nil
+}
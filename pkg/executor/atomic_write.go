@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// writeFileAtomic replaces the content of path by writing a temporary file next to it and renaming
+// it over path, so that readers see either the old or the new content and an interrupted write
+// can't leave the file truncated. An existing file keeps its mode and, where permitted, its owner;
+// a new file is created with perm, masked by the process umask. Symbolic links are followed and the
+// file they point to is replaced.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	existing, err := os.Stat(path)
+	if err == nil {
+		perm = existing.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := createTempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-", perm)
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		if tmpPath != "" {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if existing != nil {
+		// Keep the exact mode of the file being replaced, which the umask may have cut
+		if err := os.Chmod(tmpPath, perm); err != nil {
+			return err
+		}
+		copyOwnership(tmpPath, existing)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		// A file bind mounted into a container can't be renamed over, only rewritten in place
+		if errors.Is(err, syscall.EBUSY) {
+			return os.WriteFile(path, data, perm)
+		}
+		return err
+	}
+	tmpPath = ""
+	return nil
+}
+
+// createTempFile creates a new file in dir whose name starts with prefix. Unlike os.CreateTemp,
+// which always uses mode 0600, the file is created with perm so that the umask applies.
+func createTempFile(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) && try < 10000 {
+			continue
+		}
+		return f, err
+	}
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultBackupDir is the backup directory in the session state directory when server.backup_dir is unset,
+// so that backups neither clutter the workspace nor end up committed
+const defaultBackupDir = "backups"
+
+// backupTimeFormat names backups so that they sort by the time they were taken
+const backupTimeFormat = "20060102T150405.000000000"
+
+// backupSuffix ends the name of every backup
+const backupSuffix = ".bak"
+
+// backupDir returns the directory backups of edited files are kept in
+func (e *Executor) backupDir() string {
+	if dir := e.config.Get().Server.BackupDir; dir != "" {
+		return dir
+	}
+	return filepath.Join(e.stateDir(), defaultBackupDir)
+}
+
+// backupBase returns the path, without timestamp and suffix, of the backups of a file. Files in the
+// working directory keep their relative path under the backup directory, others their absolute one
+// under root/.
+func (e *Executor) backupBase(resolvedPath string) string {
+	rel, err := filepath.Rel(e.workingDir, resolvedPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Join("root", strings.TrimPrefix(filepath.Clean(resolvedPath), string(filepath.Separator)))
+	}
+	return filepath.Join(e.backupDir(), rel)
+}
+
+// backupFile keeps a timestamped copy of content, the content of a file about to be edited, when
+// server.backup_on_edit is set, pruning the oldest beyond server.max_backups_per_file.
+// The edit must not go ahead if this fails.
+func (e *Executor) backupFile(resolvedPath string, content []byte) error {
+	cfg := e.config.Get().Server
+	if !cfg.BackupOnEdit {
+		return nil
+	}
+
+	backup := e.backupBase(resolvedPath) + "." + time.Now().UTC().Format(backupTimeFormat) + backupSuffix
+	if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := os.WriteFile(backup, content, 0600); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	e.logger.Debugf("Backed up %s to %s", resolvedPath, backup)
+
+	if cfg.MaxBackupsPerFile == 0 {
+		return nil
+	}
+	backups, err := e.listBackups(resolvedPath)
+	if err != nil {
+		e.logger.Warnf("Failed to list backups of %s for pruning: %v", resolvedPath, err)
+		return nil
+	}
+	for _, old := range backups[:max(0, len(backups)-cfg.MaxBackupsPerFile)] {
+		if err := os.Remove(old); err != nil {
+			e.logger.Warnf("Failed to prune backup %s: %v", old, err)
+		}
+	}
+	return nil
+}
+
+// latestBackup returns the most recent backup of a file, or "" if there is none
+func (e *Executor) latestBackup(resolvedPath string) (string, error) {
+	backups, err := e.listBackups(resolvedPath)
+	if err != nil || len(backups) == 0 {
+		return "", err
+	}
+	return backups[len(backups)-1], nil
+}
+
+// listBackups returns the paths of the backups of a file, oldest first
+func (e *Executor) listBackups(resolvedPath string) ([]string, error) {
+	base := e.backupBase(resolvedPath)
+	entries, err := os.ReadDir(filepath.Dir(base))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(base) + "."
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), backupSuffix)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			// The backup of another file whose name starts with this one's, such as a.txt.orig
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(base), name))
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
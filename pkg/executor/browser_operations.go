@@ -93,13 +93,11 @@ func (e *Executor) executeBrowseInteractive(ctx context.Context, action models.B
 
 	e.logger.Infof("Interactive browsing with browser ID: %s", action.BrowserID)
 
-	// For now, return a message indicating this is not fully implemented
+	// Interactive browsing isn't implemented, so acknowledge the action without effect.
 	// In a full implementation, this would use a headless browser like chromedp
-	return models.NewBrowserObservation(
-		"Interactive browsing not fully implemented. Consider using browse URL action for basic web content fetching.",
-		"",
-		"",
-		"browse_interactive",
+	return models.NewNullObservation(
+		"Interactive browsing is not implemented. Consider using browse URL action for basic web content fetching.",
+		action,
 	), nil
 }
 
@@ -4,20 +4,32 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/denysvitali/openhands-runtime-go/internal/models"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // executeCmdRun executes a command in the bash shell
 func (e *Executor) executeCmdRun(ctx context.Context, action models.CmdRunAction) (interface{}, error) {
+	return e.runCmd(ctx, action, nil)
+}
+
+// runCmd executes a command in the bash shell, calling status, if not nil, with a description
+// of the command every server.stream_status_interval_seconds while it runs
+func (e *Executor) runCmd(ctx context.Context, action models.CmdRunAction, status func(message string)) (interface{}, error) {
 	_, span := e.tracer.Start(ctx, "cmd_run")
 	defer span.End()
+	defer e.metrics.recordCommandDuration(ctx, "run", time.Now())
 
 	// Set span attributes for tracing
 	span.SetAttributes(
@@ -39,17 +51,15 @@ func (e *Executor) executeCmdRun(ctx context.Context, action models.CmdRunAction
 		), nil
 	}
 
-	// Set working directory if specified
-	cwd := e.workingDir
-	if action.Cwd != "" {
-		// Make sure the path is resolved if it's relative
-		if !filepath.IsAbs(action.Cwd) {
-			cwd = filepath.Join(e.workingDir, action.Cwd)
-		} else {
-			cwd = action.Cwd
+	// Load the env file into the session, so that later commands see its variables too
+	if action.EnvFile != "" {
+		if err := e.loadEnvFile(action.EnvFile); err != nil {
+			return models.NewErrorObservation(fmt.Sprintf("Failed to load env file: %v", err), "EnvFileError"), nil
 		}
 	}
 
+	cwd := e.commandDir(action.Cwd)
+
 	// Create a new context with timeout if hardTimeout is specified
 	execCtx := ctx
 	var cancel context.CancelFunc
@@ -58,25 +68,73 @@ func (e *Executor) executeCmdRun(ctx context.Context, action models.CmdRunAction
 		defer cancel()
 	}
 
+	// The shell reports the directory and environment it ends up with through these files
+	stateDir, err := os.MkdirTemp("", "openhands-cmd-*")
+	if err != nil {
+		return models.NewErrorObservation(
+			fmt.Sprintf("Failed to execute command: %v", err),
+			"CommandExecutionError",
+		), nil
+	}
+	defer func() { _ = os.RemoveAll(stateDir) }()
+	cwdFile := filepath.Join(stateDir, "cwd")
+	envFile := filepath.Join(stateDir, "env")
+
 	// Prepare command options
-	cmd := exec.CommandContext(execCtx, "bash", "-c", action.Command)
+	cmd := e.shellCommand(execCtx, reportFinalState+action.Command)
 	cmd.Dir = cwd
 
-	// Set up environment variables
-	// This is just a basic implementation - in a real scenario, you would
-	// likely want to preserve certain environment variables from the parent process
-	cmd.Env = []string{
-		fmt.Sprintf("PATH=%s", os.Getenv("PATH")),
-		fmt.Sprintf("HOME=%s", os.Getenv("HOME")),
-	}
+	// Commands see the variables exported by earlier ones
+	cmd.Env = append(e.sessionEnvironment(),
+		fmt.Sprintf("%s=%s", cwdFileEnv, cwdFile),
+		fmt.Sprintf("%s=%s", envFileEnv, envFile),
+	)
 
 	// Capture stdout and stderr
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	// Run the command
-	err := cmd.Run()
+	// Optionally record when each line was captured
+	var timeline *outputTimeline
+	if e.config.Get().Server.TimestampCommandOutput {
+		timeline = &outputTimeline{}
+		cmd.Stdout = timeline.writer("stdout", &stdout)
+		cmd.Stderr = timeline.writer("stderr", &stderr)
+	}
+
+	// Optionally archive the full output, which the observation may truncate
+	if action.LogToFile != "" {
+		if err := e.SecurityCheck(action.LogToFile); err != nil {
+			return models.NewErrorObservation(fmt.Sprintf("Security error: %v", err), "SecurityError"), nil
+		}
+		logPath := e.resolvePath(action.LogToFile)
+		if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+			return models.NewErrorObservation(fmt.Sprintf("Failed to create directory for %s: %v", action.LogToFile, err), "CommandExecutionError"), nil
+		}
+		logFile, err := os.Create(logPath)
+		if err != nil {
+			return models.NewErrorObservation(fmt.Sprintf("Failed to create log file %s: %v", action.LogToFile, err), "CommandExecutionError"), nil
+		}
+		defer func() { _ = logFile.Close() }()
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, logFile)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, logFile)
+	}
+
+	// Run the command, allowing it to be interrupted while it runs. The span events mark where
+	// the time goes: running the command is between send_command and capture_start, processing
+	// its output between capture_start and capture_complete.
+	interrupted := false
+	err = cmd.Start()
+	if err == nil {
+		span.AddEvent("send_command", trace.WithAttributes(attribute.Int("pid", cmd.Process.Pid)))
+		finished := e.trackCommand(cmd)
+		stopStatus := e.reportStatus(status)
+		err = cmd.Wait()
+		stopStatus()
+		interrupted = finished()
+		span.AddEvent("capture_start", trace.WithAttributes(attribute.Int("output.bytes", stdout.Len()+stderr.Len())))
+	}
 
 	// Get the command exit code
 	exitCode := 0
@@ -97,12 +155,19 @@ func (e *Executor) executeCmdRun(ctx context.Context, action models.CmdRunAction
 	}
 
 	// Combine stdout and stderr
-	output := stdout.String()
+	outputEncoding := e.commandOutputEncoding()
+	output := e.decodeCommandOutput(stdout.Bytes(), outputEncoding)
 	if stderr.Len() > 0 {
 		if output != "" {
 			output += "\n"
 		}
-		output += stderr.String()
+		output += e.decodeCommandOutput(stderr.Bytes(), outputEncoding)
+	}
+
+	// Color codes and other control sequences only waste the model's context
+	stripEscapes := e.config.Get().Server.StripANSI
+	if stripEscapes {
+		output = stripANSI(output)
 	}
 
 	// If the command timed out, add a message to the output
@@ -114,6 +179,32 @@ func (e *Executor) executeCmdRun(ctx context.Context, action models.CmdRunAction
 		exitCode = 124 // Make sure exit code is set for timeout
 	}
 
+	// SIGKILL that the runtime didn't send itself most likely came from the OOM killer
+	killed := execCtx.Err() == nil && !interrupted && killedBySIGKILL(err, exitCode)
+	if e.exceededMemoryLimit(err, killed, output) {
+		if output != "" {
+			output += "\n"
+		}
+		output += e.memoryLimitNote()
+		e.logger.Warnf("Command exceeded the memory limit: %s", action.Command)
+	} else if killed {
+		if output != "" {
+			output += "\n"
+		}
+		output += killedNote
+		e.logger.Warnf("Command was killed: %s", action.Command)
+	}
+
+	// Package installs are slow to read as well as to run, so optionally keep only their outcome.
+	// The streaming endpoint still shows their progress line by line.
+	var install *models.InstallInfo
+	if e.config.Get().Server.SummarizeInstallOutput && execCtx.Err() == nil && !killed {
+		if installCmd, ok := detectInstallCommand(action.Command); ok {
+			output, install = summarizeInstall(installCmd, output, exitCode)
+		}
+	}
+
+	span.AddEvent("capture_complete", trace.WithAttributes(attribute.Int("exit_code", exitCode)))
 	e.logger.Debugf("Command executed with exit code: %d in directory: %s", exitCode, cwd)
 
 	// Create the CmdOutputObservation with command ID (process ID)
@@ -122,13 +213,141 @@ func (e *Executor) executeCmdRun(ctx context.Context, action models.CmdRunAction
 		commandID = fmt.Sprintf("%d", cmd.Process.Pid)
 	}
 
-	return models.NewCmdOutputObservation(output, exitCode, commandID, action.Command), nil
+	observation := models.NewCmdOutputObservation(output, exitCode, commandID, action.Command)
+	observation.Extras.WorkingDir = cwd
+	if finalCwd, readErr := os.ReadFile(cwdFile); readErr == nil && len(bytes.TrimSpace(finalCwd)) > 0 {
+		observation.Extras.WorkingDir = string(bytes.TrimSpace(finalCwd))
+	}
+	if timeline != nil {
+		observation.Extras.Timeline = timeline.Lines()
+		if stripEscapes {
+			for i := range observation.Extras.Timeline {
+				observation.Extras.Timeline[i].Line = stripANSI(observation.Extras.Timeline[i].Line)
+			}
+		}
+	}
+	observation.Extras.LogFile = action.LogToFile
+	observation.Extras.Killed = killed
+	observation.Extras.Install = install
+	// A cwd given with the action applies to that command only
+	sessionCwd := observation.Extras.WorkingDir
+	if action.Cwd != "" {
+		sessionCwd = ""
+	}
+	e.recordCommand(action.Command, sessionCwd)
+	if finalEnv, readErr := os.ReadFile(envFile); readErr == nil {
+		e.updateSessionEnvironment(finalEnv)
+	}
+	return observation, nil
 }
 
-// StreamCommandExecution executes a command and streams output in real-time
-func (e *Executor) StreamCommandExecution(ctx context.Context, action models.CmdRunAction, outputChan chan<- string) error {
+// reportStatus calls status with a description of a running command every
+// server.stream_status_interval_seconds, until the returned function is called
+func (e *Executor) reportStatus(status func(message string)) (stop func()) {
+	interval := time.Duration(e.config.Get().Server.StreamStatusIntervalSec) * time.Second
+	if status == nil || interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var elapsed time.Duration
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				elapsed += interval
+				status(statusMessage(elapsed))
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// cwdFileEnv names the environment variable holding the file the shell writes its final directory to
+const cwdFileEnv = "OPENHANDS_CWD_FILE"
+
+// reportFinalState is prepended to commands so that the shell records the directory and environment
+// it exits with, including after a cd or export. A command that replaces the EXIT trap falls back to
+// the starting directory and leaves the session environment unchanged.
+const reportFinalState = `trap 'pwd > "$` + cwdFileEnv + `"; env -0 > "$` + envFileEnv + `"' EXIT
+`
+
+// errorOnNonzeroExit turns a command observation with a non-zero exit code into an
+// error observation carrying the same output, when server.cmd_error_on_nonzero is enabled
+func (e *Executor) errorOnNonzeroExit(observation interface{}) interface{} {
+	if !e.config.Get().Server.CmdErrorOnNonzero {
+		return observation
+	}
+
+	cmdObs, ok := observation.(models.Observation[models.CmdOutputExtras])
+	if !ok || cmdObs.Extras.ExitCode == 0 {
+		return observation
+	}
+
+	content := cmdObs.Content
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += fmt.Sprintf("[Command exited with code %d]", cmdObs.Extras.ExitCode)
+	return models.NewErrorObservation(content, "NonZeroExitCode")
+}
+
+// commandDir returns the directory a command runs in: cwd when given, relative to the working
+// directory, and otherwise the directory the session's last command finished in
+func (e *Executor) commandDir(cwd string) string {
+	if cwd == "" {
+		// The directory may have been removed since
+		if info, err := os.Stat(e.sessionCwd()); err == nil && info.IsDir() {
+			return e.sessionCwd()
+		}
+		return e.workingDir
+	}
+	if !filepath.IsAbs(cwd) {
+		return filepath.Join(e.workingDir, cwd)
+	}
+	return cwd
+}
+
+// StreamEventType tells what a StreamEvent reports
+type StreamEventType int
+
+const (
+	// StreamStarted is sent once the command is running; CommandID identifies it for InterruptCommand
+	StreamStarted StreamEventType = iota
+	// StreamOutput carries a line of output, or of an error preventing the command from running, in Data
+	StreamOutput
+	// StreamStatus reports a quiet command is still running, every server.stream_status_interval_seconds;
+	// Data describes how long it has been running
+	StreamStatus
+)
+
+// StreamEvent is sent by StreamCommandExecution as a command runs
+type StreamEvent struct {
+	Type      StreamEventType
+	Data      string
+	CommandID int
+}
+
+// outputEvent returns the StreamEvent carrying a line of output
+func outputEvent(line string) StreamEvent {
+	return StreamEvent{Type: StreamOutput, Data: line}
+}
+
+// StreamCommandExecution executes a command and streams its events in real-time, closing events
+// when the command has finished
+func (e *Executor) StreamCommandExecution(ctx context.Context, action models.CmdRunAction, outputChan chan<- StreamEvent) error {
 	_, span := e.tracer.Start(ctx, "stream_cmd_run")
 	defer span.End()
+	defer e.metrics.recordCommandDuration(ctx, "stream", time.Now())
 
 	// Set span attributes for tracing
 	span.SetAttributes(
@@ -139,25 +358,35 @@ func (e *Executor) StreamCommandExecution(ctx context.Context, action models.Cmd
 	// Log the command execution
 	e.logger.Infof("Streaming command execution: %s", action.Command)
 
+	// Stop sending once the consumer has gone away, rather than blocking on it
+	send := func(event StreamEvent) bool {
+		select {
+		case outputChan <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
 	// Security check for command injection
 	if err := e.sanitizeCommand(action.Command); err != nil {
 		e.logger.Warnf("Potentially dangerous command blocked: %s", action.Command)
-		outputChan <- fmt.Sprintf("Command blocked for security reasons: %v\n", err)
+		send(outputEvent(fmt.Sprintf("Command blocked for security reasons: %v\n", err)))
 		close(outputChan)
 		return err
 	}
 
-	// Set working directory if specified
-	cwd := e.workingDir
-	if action.Cwd != "" {
-		// Make sure the path is resolved if it's relative
-		if !filepath.IsAbs(action.Cwd) {
-			cwd = filepath.Join(e.workingDir, action.Cwd)
-		} else {
-			cwd = action.Cwd
+	// Load the env file into the session, as executeCmdRun does
+	if action.EnvFile != "" {
+		if err := e.loadEnvFile(action.EnvFile); err != nil {
+			send(outputEvent(fmt.Sprintf("Failed to load env file: %v\n", err)))
+			close(outputChan)
+			return err
 		}
 	}
 
+	cwd := e.commandDir(action.Cwd)
+
 	// Create a new context with timeout if hardTimeout is specified
 	execCtx := ctx
 	var cancel context.CancelFunc
@@ -167,85 +396,141 @@ func (e *Executor) StreamCommandExecution(ctx context.Context, action models.Cmd
 	}
 
 	// Prepare command options
-	cmd := exec.CommandContext(execCtx, "bash", "-c", action.Command)
+	cmd := e.shellCommand(execCtx, action.Command)
 	cmd.Dir = cwd
 
-	// Set up environment variables
-	cmd.Env = []string{
-		fmt.Sprintf("PATH=%s", os.Getenv("PATH")),
-		fmt.Sprintf("HOME=%s", os.Getenv("HOME")),
-	}
+	// Commands see the variables exported by earlier ones
+	cmd.Env = e.sessionEnvironment()
 
-	// Create pipes for stdout and stderr
-	stdout, err := cmd.StdoutPipe()
+	// Create pipes for stdout and stderr. They are ours rather than cmd's, so that Wait
+	// returns as soon as the shell exits instead of waiting for its background children.
+	stdout, stdoutWriter, err := os.Pipe()
 	if err != nil {
 		close(outputChan)
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
+	defer stdout.Close()
 
-	stderr, err := cmd.StderrPipe()
+	stderr, stderrWriter, err := os.Pipe()
 	if err != nil {
+		stdoutWriter.Close()
 		close(outputChan)
 		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
+	defer stderr.Close()
+
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
 
 	// Start the command
-	if err := cmd.Start(); err != nil {
+	err = cmd.Start()
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	if err != nil {
 		close(outputChan)
 		return fmt.Errorf("failed to start command: %w", err)
 	}
+	finished := e.trackCommand(cmd)
+	defer finished()
+	if !send(StreamEvent{Type: StreamStarted, CommandID: cmd.Process.Pid}) {
+		close(outputChan)
+		_ = cmd.Wait()
+		return ctx.Err()
+	}
 
-	// Stream output from both stdout and stderr
+	// Clean streamed lines the same way as the output of executeCmdRun. Commands run through
+	// the shell's -c rather than a terminal, so there is no echoed command or prompt to strip.
+	outputEncoding := e.commandOutputEncoding()
+	stripEscapes := e.config.Get().Server.StripANSI
+	cleanLine := func(line []byte) string {
+		text := e.decodeCommandOutput(line, outputEncoding)
+		if stripEscapes {
+			text = stripANSI(text)
+		}
+		return text + "\n"
+	}
+
+	// Stream output from both stdout and stderr. Once the shell has exited, the pipes are
+	// only drained until they go quiet, since a background child may hold them open.
+	exited := make(chan struct{})
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(2)
 	go func() {
 		defer close(outputChan)
+		defer close(stop)
 
 		// Create channels for stdout and stderr
 		stdoutChan := make(chan string)
 		stderrChan := make(chan string)
 
 		// Start goroutines to read from pipes
-		go func() {
-			defer close(stdoutChan)
-			scanner := bufio.NewScanner(stdout)
+		readLines := func(pipe *os.File, lines chan<- string) {
+			defer readers.Done()
+			defer close(lines)
+			scanner := bufio.NewScanner(drainingReader{file: pipe, exited: exited})
 			for scanner.Scan() {
-				stdoutChan <- scanner.Text() + "\n"
-			}
-		}()
-
-		go func() {
-			defer close(stderrChan)
-			scanner := bufio.NewScanner(stderr)
-			for scanner.Scan() {
-				stderrChan <- scanner.Text() + "\n"
+				select {
+				case lines <- cleanLine(scanner.Bytes()):
+				case <-stop:
+					return
+				}
 			}
-		}()
+		}
+		go readLines(stdout, stdoutChan)
+		go readLines(stderr, stderrChan)
+
+		// Let the client know a quiet command is still alive. Each line of output restarts
+		// the interval, so that status events aren't interleaved with a chatty command's output.
+		var status <-chan time.Time
+		var ticker *time.Ticker
+		started := time.Now()
+		interval := time.Duration(e.config.Get().Server.StreamStatusIntervalSec) * time.Second
+		if interval > 0 {
+			ticker = time.NewTicker(interval)
+			defer ticker.Stop()
+			status = ticker.C
+		}
 
 		// Multiplex stdout and stderr
-		for {
+		for stdoutChan != nil || stderrChan != nil {
+			var event StreamEvent
 			select {
+			case <-ctx.Done():
+				return
+			case <-status:
+				event = StreamEvent{Type: StreamStatus, Data: statusMessage(time.Since(started).Truncate(time.Second))}
 			case line, ok := <-stdoutChan:
 				if !ok {
 					stdoutChan = nil
-				} else {
-					outputChan <- line
+					continue
 				}
+				event = outputEvent(line)
 			case line, ok := <-stderrChan:
 				if !ok {
 					stderrChan = nil
-				} else {
-					outputChan <- line
+					continue
 				}
+				event = outputEvent(line)
 			}
-
-			// Exit when both channels are closed
-			if stdoutChan == nil && stderrChan == nil {
-				break
+			if !send(event) {
+				return
+			}
+			if event.Type == StreamOutput && ticker != nil {
+				ticker.Reset(interval)
 			}
 		}
 	}()
 
-	// Wait for command to complete
+	// Wait for the shell to exit, then for the rest of its output
 	err = cmd.Wait()
+	close(exited)
+	for _, pipe := range []*os.File{stdout, stderr} {
+		if pipe.SetReadDeadline(time.Now().Add(streamDrainTimeout)) != nil {
+			pipe.Close()
+		}
+	}
+	readers.Wait()
 	if err != nil {
 		if execCtx.Err() == context.DeadlineExceeded {
 			e.logger.Warnf("Streaming command timed out: %s", action.Command)
@@ -254,3 +539,41 @@ func (e *Executor) StreamCommandExecution(ctx context.Context, action models.Cmd
 
 	return err
 }
+
+// streamDrainTimeout is how long a streamed command's output may stay quiet after the
+// shell has exited before the stream ends, for pipes held open by background children
+const streamDrainTimeout = time.Second
+
+// drainingReader reads a command's output pipe, giving up once the command has exited
+// and no output arrives within streamDrainTimeout
+type drainingReader struct {
+	file   *os.File
+	exited <-chan struct{}
+}
+
+func (r drainingReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.exited:
+		if err := r.file.SetReadDeadline(time.Now().Add(streamDrainTimeout)); err != nil {
+			return 0, io.EOF
+		}
+	default:
+	}
+	n, err := r.file.Read(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// statusMessage describes a command that has been running for elapsed
+func statusMessage(elapsed time.Duration) string {
+	return fmt.Sprintf("still running after %s", elapsed)
+}
+
+// shellCommand prepares a command to be run by the configured shell, subject to server.max_memory_gb
+func (e *Executor) shellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, e.shell, "-c", e.memoryLimitPrefix()+command)
+	killProcessGroupOnCancel(cmd)
+	return cmd
+}
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envNamePattern matches the variable names a dotenv file may set
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// loadEnvFile adds the variables of a dotenv file to the session environment, so that they are
+// set for this and all later commands
+func (e *Executor) loadEnvFile(path string) error {
+	if err := e.SecurityCheck(path); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(e.resolvePath(path))
+	if err != nil {
+		return fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+	vars, err := parseDotenv(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse env file %s: %w", path, err)
+	}
+
+	e.setSessionVariables(vars)
+	return nil
+}
+
+// parseDotenv parses the NAME=value lines of a dotenv file. Blank lines, comments and an "export "
+// prefix are ignored. Single-quoted values are literal, double-quoted values may span lines and
+// contain escapes, and unquoted values end at an inline " #" comment. Variables are not expanded.
+func parseDotenv(content string) ([]string, error) {
+	var vars []string
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("line %d: expected NAME=value", lineNumber)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single-quoted value", lineNumber)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			// Keep reading lines until the closing quote
+			quoted := value[1:]
+			for {
+				unquoted, closed := unquoteDotenv(quoted)
+				if closed {
+					value = unquoted
+					break
+				}
+				if i+1 == len(lines) {
+					return nil, fmt.Errorf("line %d: unterminated double-quoted value", lineNumber)
+				}
+				i++
+				quoted += "\n" + lines[i]
+			}
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+
+		vars = append(vars, name+"="+value)
+	}
+	return vars, nil
+}
+
+// unquoteDotenv decodes a double-quoted value up to its closing quote, reporting whether it was found
+func unquoteDotenv(quoted string) (string, bool) {
+	var value strings.Builder
+	for i := 0; i < len(quoted); i++ {
+		switch c := quoted[i]; {
+		case c == '"':
+			return value.String(), true
+		case c == '\\' && i+1 < len(quoted):
+			i++
+			switch quoted[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case 't':
+				value.WriteByte('\t')
+			case 'r':
+				value.WriteByte('\r')
+			default:
+				value.WriteByte(quoted[i])
+			}
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,136 @@
+package executor
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Names reported for the detected source encoding of a file
+const (
+	encodingUTF8    = "utf-8"
+	encodingUTF8BOM = "utf-8-sig"
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+	encodingCP1252  = "windows-1252"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// sniffBOM returns the encoding indicated by a byte order mark at the start of data,
+// or an empty string if there is none.
+func sniffBOM(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return encodingUTF8BOM
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return encodingUTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return encodingUTF16BE
+	default:
+		return ""
+	}
+}
+
+// detectEncoding determines the source encoding of file content. This is a guess rather than
+// real detection: a BOM takes precedence; otherwise valid UTF-8 is assumed to be UTF-8 and
+// anything else is treated as Windows-1252, the usual legacy encoding of Western text, which
+// decodes every byte. Other legacy encodings, like Shift JIS or KOI8-R, come out garbled.
+func detectEncoding(data []byte) (string, encoding.Encoding) {
+	switch sniffBOM(data) {
+	case encodingUTF8BOM:
+		return encodingUTF8BOM, unicode.UTF8BOM
+	case encodingUTF16LE:
+		return encodingUTF16LE, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case encodingUTF16BE:
+		return encodingUTF16BE, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	}
+
+	if utf8.Valid(data) {
+		return encodingUTF8, nil
+	}
+	return encodingCP1252, charmap.Windows1252
+}
+
+// decodeToUTF8 transcodes file content to UTF-8 and returns it along with the detected source encoding
+func decodeToUTF8(data []byte) (string, string, error) {
+	name, enc := detectEncoding(data)
+	if enc == nil {
+		return string(data), name, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", name, err
+	}
+	return string(decoded), name, nil
+}
+
+// commandOutputEncoding returns the name of the encoding command output is in: server.command_output_encoding,
+// or else the charset of the session's locale (LC_ALL, LC_CTYPE, LANG), or else UTF-8
+func (e *Executor) commandOutputEncoding() string {
+	if name := e.config.Get().Server.CommandOutputEncoding; name != "" {
+		return name
+	}
+
+	locale := make(map[string]string)
+	for _, entry := range e.sessionEnvironment() {
+		if name, value, ok := strings.Cut(entry, "="); ok && value != "" {
+			locale[name] = value
+		}
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value, ok := locale[name]; ok {
+			// language_TERRITORY.charset@modifier
+			_, charset, found := strings.Cut(value, ".")
+			if !found {
+				break
+			}
+			charset, _, _ = strings.Cut(charset, "@")
+			return charset
+		}
+	}
+	return encodingUTF8
+}
+
+// decodeCommandOutput transcodes command output from the named encoding to UTF-8.
+// Invalid sequences and unknown encodings never fail: bytes that can't be decoded become U+FFFD.
+func (e *Executor) decodeCommandOutput(data []byte, name string) string {
+	enc := lookupEncoding(name)
+	if enc == nil {
+		if !strings.EqualFold(name, encodingUTF8) && !strings.EqualFold(name, "utf8") {
+			e.logger.Warnf("Unknown command output encoding %q, assuming UTF-8", name)
+		}
+		return strings.ToValidUTF8(string(data), "\uFFFD")
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return strings.ToValidUTF8(string(data), "\uFFFD")
+	}
+	return string(decoded)
+}
+
+// lookupEncoding finds an encoding by its IANA or WHATWG name, returning nil for UTF-8 or unknown names
+func lookupEncoding(name string) encoding.Encoding {
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		if enc, err = htmlindex.Get(name); err != nil {
+			return nil
+		}
+	}
+	if enc == unicode.UTF8 {
+		return nil
+	}
+	return enc
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -18,21 +21,58 @@ import (
 
 // Executor handles action execution
 type Executor struct {
-	config       *config.Config
+	config       *config.Holder
 	logger       *logrus.Logger
 	workingDir   string
 	username     string
 	userID       int
+	shell        string
 	startTime    time.Time
 	lastExecTime time.Time
 	mu           sync.RWMutex
 	tracer       trace.Tracer
+	metrics      *executorMetrics
+
+	// actions maps action types to their parsers and handlers, see RegisterAction
+	actions   map[string]registeredAction
+	actionsMu sync.RWMutex
+
+	// sessionEnv is the environment exported by the last command, see sessionEnvironment
+	sessionEnv []string
+	// cwd is the directory the last command finished in, see sessionCwd
+	cwd string
+	// history lists the commands run in the session, oldest first
+	history []string
+
+	// running maps the command IDs (PIDs) of running commands to their state, see trackCommand
+	running map[int]*runningCommand
+	// interruptGrace overrides defaultInterruptGrace, see InterruptCommand
+	interruptGrace time.Duration
+
+	// ioSampler computes the current IO throughput reported in system stats; nil when disabled
+	ioSampler *ioSampler
+
+	// diskUsage reports filesystem usage for a path, replaceable in tests
+	diskUsage func(path string) (*disk.UsageStat, error)
+
+	// fileOps bounds concurrent file reads, uploads and archive downloads; nil means unbounded
+	fileOps chan struct{}
+	// fileLocks serializes edits of the same file, see lockFile
+	fileLocks pathLocks
+
+	// fileViewerURL is the URL of the file viewer started alongside the runtime, reported in server info
+	fileViewerURL string
+
+	// readiness holds the last CheckReadiness results, reused for readinessCacheTTL after readinessAt
+	readiness   []models.ReadinessCheck
+	readinessAt time.Time
+	readinessMu sync.Mutex
 }
 
 // New creates a new executor
 func New(cfg *config.Config, logger *logrus.Logger) (*Executor, error) {
 	executor := &Executor{
-		config:       cfg,
+		config:       config.NewHolder(cfg),
 		logger:       logger,
 		workingDir:   cfg.Server.WorkingDir,
 		username:     cfg.Server.Username,
@@ -40,6 +80,24 @@ func New(cfg *config.Config, logger *logrus.Logger) (*Executor, error) {
 		startTime:    time.Now(),
 		lastExecTime: time.Now(),
 		tracer:       otel.Tracer("openhands-runtime"),
+		diskUsage:    disk.Usage,
+
+		actions: make(map[string]registeredAction),
+	}
+	executor.registerBuiltinActions()
+
+	metrics, err := newExecutorMetrics(otel.Meter("openhands-runtime"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create executor metrics: %w", err)
+	}
+	executor.metrics = metrics
+
+	if cfg.Server.MaxConcurrentFileOps > 0 {
+		executor.fileOps = make(chan struct{}, cfg.Server.MaxConcurrentFileOps)
+	}
+
+	if err := executor.initShell(); err != nil {
+		return nil, err
 	}
 
 	if err := executor.initWorkingDirectory(); err != nil {
@@ -50,9 +108,34 @@ func New(cfg *config.Config, logger *logrus.Logger) (*Executor, error) {
 		logger.Warnf("Failed to initialize user: %v", err)
 	}
 
+	if cfg.Server.IOSampleIntervalSec > 0 {
+		executor.startIOSampler(time.Duration(cfg.Server.IOSampleIntervalSec) * time.Second)
+	}
+
+	if cfg.Server.PersistSessionState {
+		if err := executor.loadSessionState(); err != nil {
+			logger.Warnf("Failed to restore session state: %v", err)
+		}
+	}
+
 	return executor, nil
 }
 
+// initShell resolves the shell used to run commands, defaulting to bash
+func (e *Executor) initShell() error {
+	shell := e.config.Get().Server.Shell
+	if shell == "" {
+		shell = "bash"
+	}
+
+	path, err := exec.LookPath(shell)
+	if err != nil {
+		return fmt.Errorf("configured shell %q not found: %w", shell, err)
+	}
+	e.shell = path
+	return nil
+}
+
 // initWorkingDirectory initializes the working directory
 func (e *Executor) initWorkingDirectory() error {
 	// Check if the working directory exists, create it if it doesn't
@@ -75,16 +158,51 @@ func (e *Executor) initUser() error {
 	return nil
 }
 
+// Config returns the holder of the executor's configuration, whose hot-reloadable settings may be reloaded at runtime
+func (e *Executor) Config() *config.Holder {
+	return e.config
+}
+
 // Close cleans up resources, including the persistent bash session
+// When server.persist_session_state is set, it checkpoints the session state for the next start.
 func (e *Executor) Close() error {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-	// Add cleanup logic here
+	if e.ioSampler != nil {
+		e.ioSampler.Stop()
+	}
+	if e.config.Get().Server.PersistSessionState {
+		if err := e.saveSessionState(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // ExecuteAction executes an action and returns an observation
+// If the action carries an ID, the observation echoes it back as its cause.
+// Content larger than max_observation_content_bytes is truncated.
 func (e *Executor) ExecuteAction(ctx context.Context, actionMap map[string]interface{}) (interface{}, error) {
+	observation, err := e.executeAction(ctx, actionMap)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxBytes := e.config.Get().Server.MaxObservationContentBytes; maxBytes > 0 {
+		observation = models.WithTruncatedContent(observation, maxBytes)
+	}
+
+	if id, ok := models.ActionID(actionMap); ok {
+		observation = models.WithCause(observation, id)
+	}
+	return observation, nil
+}
+
+// ActionDisabled reports whether server.disabled_actions lists the action type
+func (e *Executor) ActionDisabled(actionType string) bool {
+	return slices.Contains(e.config.Get().Server.DisabledActions, actionType)
+}
+
+// executeAction dispatches an action to its handler
+func (e *Executor) executeAction(ctx context.Context, actionMap map[string]interface{}) (interface{}, error) {
 	ctx, span := e.tracer.Start(ctx, "execute_action")
 	defer span.End()
 
@@ -92,7 +210,7 @@ func (e *Executor) ExecuteAction(ctx context.Context, actionMap map[string]inter
 	e.lastExecTime = time.Now()
 	e.mu.Unlock()
 
-	action, err := models.ParseAction(actionMap)
+	action, unparsed, err := e.ParseAction(actionMap)
 	if err != nil {
 		span.RecordError(err)
 		return models.NewErrorObservation(
@@ -100,27 +218,23 @@ func (e *Executor) ExecuteAction(ctx context.Context, actionMap map[string]inter
 			"ActionParsingError",
 		), nil
 	}
+	for key, value := range unparsed {
+		e.logger.Debugf("Unknown or malformed action arg %q kept in the action's args: %v", key, value)
+	}
+
+	// ParseAction has already checked that the action type is a string
+	actionType := actionMap["action"].(string)
+	span.SetAttributes(attribute.String("action.type", actionType))
 
-	if actionType, ok := actionMap["action"].(string); ok {
-		span.SetAttributes(attribute.String("action.type", actionType))
-	}
-
-	switch a := action.(type) {
-	case models.CmdRunAction:
-		return e.executeCmdRun(ctx, a)
-	case models.FileReadAction:
-		return e.executeFileRead(ctx, a)
-	case models.FileWriteAction:
-		return e.executeFileWrite(ctx, a)
-	case models.FileEditAction:
-		return e.executeFileEdit(ctx, a)
-	case models.IPythonRunCellAction:
-		return e.executeIPython(ctx, a)
-	case models.BrowseURLAction:
-		return e.executeBrowseURL(ctx, a)
-	case models.BrowseInteractiveAction:
-		return e.executeBrowseInteractive(ctx, a)
-	default:
+	if e.ActionDisabled(actionType) {
+		return models.NewErrorObservation(
+			fmt.Sprintf("Action %q is disabled on this runtime", actionType),
+			"ActionDisabledError",
+		), nil
+	}
+
+	handler, ok := e.actionHandler(actionType)
+	if !ok {
 		err := fmt.Errorf("unsupported action type: %T", action)
 		span.RecordError(err)
 		return models.NewErrorObservation(
@@ -128,29 +242,44 @@ func (e *Executor) ExecuteAction(ctx context.Context, actionMap map[string]inter
 			"UnsupportedActionError",
 		), nil
 	}
+
+	observation, err := handler(ctx, action)
+	if fileOperationActions[actionType] {
+		_, failed := observation.(models.Observation[models.ErrorExtras])
+		e.metrics.countFileOperation(ctx, actionType, err == nil && !failed)
+	}
+	return observation, err
 }
 
-// RunCommand executes a command and returns the result
+// RunCommand executes a command and returns the result, killing the command if ctx is cancelled
 // This is a simplified wrapper for MCP usage
-func (e *Executor) RunCommand(command string) (*models.Observation[models.CmdOutputExtras], error) {
-	ctx := context.Background()
-	
+func (e *Executor) RunCommand(ctx context.Context, command string) (*models.Observation[models.CmdOutputExtras], error) {
+	return e.RunCommandWithStatus(ctx, command, nil)
+}
+
+// RunCommandWithStatus is RunCommand, also calling status, if not nil, with a description of the
+// command every server.stream_status_interval_seconds while it runs. Output larger than
+// max_observation_content_bytes is truncated.
+func (e *Executor) RunCommandWithStatus(ctx context.Context, command string, status func(message string)) (*models.Observation[models.CmdOutputExtras], error) {
 	// Create a CmdRunAction
 	action := models.CmdRunAction{
 		Command: command,
 		Cwd:     e.workingDir,
 	}
-	
+
 	// Execute the action
-	result, err := e.executeCmdRun(ctx, action)
+	result, err := e.runCmd(ctx, action, status)
 	if err != nil {
 		return nil, err
 	}
-	
+	if maxBytes := e.config.Get().Server.MaxObservationContentBytes; maxBytes > 0 {
+		result = models.WithTruncatedContent(result, maxBytes)
+	}
+
 	// Convert result to CmdOutputObservation
 	if obs, ok := result.(models.Observation[models.CmdOutputExtras]); ok {
 		return &obs, nil
 	}
-	
+
 	return nil, fmt.Errorf("unexpected result type: %T", result)
 }
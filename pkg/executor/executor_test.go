@@ -1,20 +1,36 @@
 package executor
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"image"
+	"image/png"
 	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/denysvitali/openhands-runtime-go/internal/models"
 	"github.com/denysvitali/openhands-runtime-go/pkg/config"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
-func newTestExecutor(t *testing.T) *Executor {
+func newTestExecutor(t testing.TB) *Executor {
+	return newTestExecutorWithConfig(t, nil)
+}
+
+// newTestExecutorWithConfig returns a test executor whose config is modified by configure, if not nil
+func newTestExecutorWithConfig(t testing.TB, configure func(cfg *config.Config)) *Executor {
 	cfg := &config.Config{
 		Server: config.ServerConfig{
 			WorkingDir: t.TempDir(),
@@ -22,6 +38,9 @@ func newTestExecutor(t *testing.T) *Executor {
 			UserID:     os.Getuid(),
 		},
 	}
+	if configure != nil {
+		configure(cfg)
+	}
 	logger := logrus.New()
 	logger.SetOutput(io.Discard) // Discard logs during tests
 
@@ -30,6 +49,15 @@ func newTestExecutor(t *testing.T) *Executor {
 	return executor
 }
 
+// reloadConfig changes the hot-reloadable settings of a test executor's config like a config file change would
+func reloadConfig(t testing.TB, executor *Executor, configure func(cfg *config.Config)) {
+	t.Helper()
+	next := *executor.config.Get()
+	configure(&next)
+	_, ignored := executor.config.Reload(&next)
+	require.Empty(t, ignored, "settings requiring a restart must be set with newTestExecutorWithConfig")
+}
+
 func TestExecuteCmdRun(t *testing.T) {
 	executor := newTestExecutor(t)
 	ctx := context.Background()
@@ -69,6 +97,29 @@ func TestExecuteCmdRun(t *testing.T) {
 		assert.Equal(t, 0, cmdObs.Extras.ExitCode)
 	})
 
+	t.Run("working dir follows cd", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(filepath.Join(executor.workingDir, "cd_target"), 0755))
+
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "cd cd_target && pwd"})
+		require.NoError(t, err)
+
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok)
+		assert.Equal(t, filepath.Join(executor.workingDir, "cd_target")+"\n", cmdObs.Content)
+		assert.Equal(t, filepath.Join(executor.workingDir, "cd_target"), cmdObs.Extras.WorkingDir)
+	})
+
+	t.Run("working dir of failing command", func(t *testing.T) {
+		// Starts where the previous command's cd left the session
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "exit 3"})
+		require.NoError(t, err)
+
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok)
+		assert.Equal(t, 3, cmdObs.Extras.ExitCode)
+		assert.Equal(t, filepath.Join(executor.workingDir, "cd_target"), cmdObs.Extras.WorkingDir)
+	})
+
 	t.Run("command with absolute cwd", func(t *testing.T) {
 		// Create a temporary directory and a file in it
 		absTestDir := t.TempDir()
@@ -160,3 +211,886 @@ func TestExecuteAction_CmdRun(t *testing.T) {
 	// In the new system, commandID is directly in the Extras struct instead of a map
 	assert.NotEmpty(t, cmdObs.Extras.CommandID) // Should have a non-empty command ID
 }
+
+func TestExecuteAction_TruncatesLargeContent(t *testing.T) {
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.MaxObservationContentBytes = 200
+	})
+	ctx := context.Background()
+
+	obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+		"action": "run",
+		"args":   map[string]interface{}{"command": "head -c 5000 /dev/zero | tr '\\0' 'a'"},
+	})
+	require.NoError(t, err)
+
+	cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+	require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+	assert.LessOrEqual(t, len(cmdObs.Content), 200)
+	assert.Contains(t, cmdObs.Content, models.TruncationMarker)
+	assert.True(t, cmdObs.Extras.Truncated)
+	assert.Equal(t, 0, cmdObs.Extras.ExitCode)
+}
+
+func TestExecuteAction_KeepsImagesWhole(t *testing.T) {
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.MaxObservationContentBytes = 1024
+	})
+
+	// Random pixels keep the PNG well above the limit once compressed
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	_, _ = rand.New(rand.NewSource(1)).Read(img.Pix)
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	require.Greater(t, buf.Len(), 1024)
+	path := filepath.Join(executor.workingDir, "noise.png")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	obs, err := executor.ExecuteAction(context.Background(), map[string]interface{}{
+		"action": "read",
+		"args":   map[string]interface{}{"path": path},
+	})
+	require.NoError(t, err)
+
+	readObs, ok := obs.(models.Observation[models.FileReadExtras])
+	require.True(t, ok, "expected FileReadObservation, got %T", obs)
+	assert.False(t, readObs.Extras.Truncated)
+
+	encoded, found := strings.CutPrefix(readObs.Content, "data:image/png;base64,")
+	require.True(t, found, "expected a PNG data URL")
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	_, err = png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+}
+
+func TestExecuteCmdRun_ConfiguredShell(t *testing.T) {
+	newExecutorWithShell := func(t *testing.T, shell string) (*Executor, error) {
+		cfg := &config.Config{
+			Server: config.ServerConfig{
+				WorkingDir: t.TempDir(),
+				Username:   "testuser",
+				UserID:     os.Getuid(),
+				Shell:      shell,
+			},
+		}
+		logger := logrus.New()
+		logger.SetOutput(io.Discard)
+		return New(cfg, logger)
+	}
+
+	t.Run("sh", func(t *testing.T) {
+		executor, err := newExecutorWithShell(t, "sh")
+		assert.NoError(t, err)
+
+		obs, err := executor.executeCmdRun(context.Background(), models.CmdRunAction{Command: "echo hello from sh"})
+		assert.NoError(t, err)
+
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		assert.True(t, ok)
+		assert.Contains(t, cmdObs.Content, "hello from sh")
+		assert.Equal(t, 0, cmdObs.Extras.ExitCode)
+	})
+
+	t.Run("missing shell", func(t *testing.T) {
+		_, err := newExecutorWithShell(t, "shell_that_does_not_exist_qwertyuiop")
+		assert.ErrorContains(t, err, `configured shell "shell_that_does_not_exist_qwertyuiop" not found`)
+	})
+}
+
+func TestExecuteAction_CmdErrorOnNonzero(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+	actionMap := map[string]interface{}{
+		"action":  "run",
+		"command": "echo partial output; exit 3",
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, actionMap)
+		assert.NoError(t, err)
+
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		assert.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+		assert.Equal(t, 3, cmdObs.Extras.ExitCode)
+		assert.Contains(t, cmdObs.Content, "partial output")
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.CmdErrorOnNonzero = true })
+		defer reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.CmdErrorOnNonzero = false })
+
+		obs, err := executor.ExecuteAction(ctx, actionMap)
+		assert.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		assert.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "error", errObs.Observation)
+		assert.Equal(t, "NonZeroExitCode", errObs.Extras.ErrorID)
+		assert.Contains(t, errObs.Content, "partial output")
+		assert.Contains(t, errObs.Content, "[Command exited with code 3]")
+	})
+}
+
+// echoAction is a custom action used to test action registration
+type echoAction struct {
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+func TestRegisterAction(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	RegisterAction(executor, "test_echo", func(ctx context.Context, action echoAction) (interface{}, error) {
+		return models.BasicObservation{Observation: "test_echo", Content: action.Message}, nil
+	})
+
+	t.Run("custom action is dispatched", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+			"action": "test_echo",
+			"args":   map[string]interface{}{"message": "hello"},
+		})
+		require.NoError(t, err)
+
+		basicObs, ok := obs.(models.BasicObservation)
+		require.True(t, ok, "expected BasicObservation, got %T", obs)
+		assert.Equal(t, "test_echo", basicObs.Observation)
+		assert.Equal(t, "hello", basicObs.Content)
+	})
+
+	t.Run("unregistered action is unsupported", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{"action": "test_unknown"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "UnsupportedActionError", errObs.Extras.ErrorID)
+	})
+
+	t.Run("other executors don't know the action", func(t *testing.T) {
+		other := newTestExecutor(t)
+		action, _, err := other.ParseAction(map[string]interface{}{"action": "test_echo", "message": "hello"})
+		require.NoError(t, err)
+		_, ok := action.(models.Action)
+		assert.True(t, ok, "expected the base Action, got %T", action)
+
+		obs, err := other.ExecuteAction(ctx, map[string]interface{}{"action": "test_echo", "message": "hello"})
+		require.NoError(t, err)
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "UnsupportedActionError", errObs.Extras.ErrorID)
+	})
+}
+
+func TestExecuteAction_LenientParsing(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	obs, err := executor.ExecuteAction(context.Background(), map[string]interface{}{
+		"action": "run",
+		"args": map[string]interface{}{
+			"command":       "echo lenient",
+			"is_static":     "not a bool",
+			"hard_timeout":  []interface{}{1, 2},
+			"future_option": map[string]interface{}{"nested": true},
+		},
+	})
+	require.NoError(t, err)
+
+	cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+	require.True(t, ok, "expected CmdOutputObservation, got %T: %+v", obs, obs)
+	assert.Equal(t, "lenient\n", cmdObs.Content)
+	assert.Equal(t, 0, cmdObs.Extras.ExitCode)
+}
+
+func TestExecuteAction_NullObservation(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	t.Run("no-op action is echoed", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+			"action": "finish",
+			"args":   map[string]interface{}{"final_thought": "done"},
+		})
+		require.NoError(t, err)
+
+		data, err := json.Marshal(obs)
+		require.NoError(t, err)
+		var shape map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &shape))
+
+		assert.Equal(t, "null", shape["observation"])
+		assert.Equal(t, "", shape["content"])
+		assert.Equal(t, map[string]interface{}{
+			"action": map[string]interface{}{"action": "finish", "final_thought": "done"},
+		}, shape["extras"])
+	})
+
+	t.Run("unhandled browser interaction", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+			"action": "browse_interactive",
+			"args":   map[string]interface{}{"browser_id": "b1"},
+		})
+		require.NoError(t, err)
+
+		nullObs, ok := obs.(models.Observation[models.NullExtras])
+		require.True(t, ok, "expected NullObservation, got %T", obs)
+		assert.Equal(t, "null", nullObs.Observation)
+		assert.Equal(t, models.BrowseInteractiveAction{Action: "browse_interactive", BrowserID: "b1"}, nullObs.Extras.Action)
+	})
+
+	t.Run("malformed action is still an error", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{"action": "finish", "args": "not a map"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "ActionParsingError", errObs.Extras.ErrorID)
+	})
+}
+
+func TestExecuteAction_ThinkAndRecall(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	t.Run("think", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+			"action": "think",
+			"args":   map[string]interface{}{"thought": "check the tests first"},
+		})
+		require.NoError(t, err)
+
+		thinkObs, ok := obs.(models.Observation[models.ThinkExtras])
+		require.True(t, ok, "expected think observation, got %T", obs)
+		assert.Equal(t, "think", thinkObs.Observation)
+		assert.Equal(t, "Your thought has been logged.", thinkObs.Content)
+		assert.Equal(t, "check the tests first", thinkObs.Extras.Thought)
+	})
+
+	t.Run("recall", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+			"action": "recall",
+			"args":   map[string]interface{}{"query": "fix the bug", "recall_type": "workspace_context"},
+		})
+		require.NoError(t, err)
+
+		recallObs, ok := obs.(models.Observation[models.RecallExtras])
+		require.True(t, ok, "expected recall observation, got %T", obs)
+		assert.Equal(t, "recall", recallObs.Observation)
+		assert.Equal(t, "fix the bug", recallObs.Extras.Query)
+		assert.Equal(t, "workspace_context", recallObs.Extras.RecallType)
+	})
+}
+
+func TestExecuteCmdRun_ExportsPersist(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	_, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "export GREETING=hello"})
+	require.NoError(t, err)
+
+	obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "echo $GREETING"})
+	require.NoError(t, err)
+
+	cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+	require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+	assert.Equal(t, "hello", strings.TrimSpace(cmdObs.Content))
+}
+
+func TestInterruptCommand_Escalates(t *testing.T) {
+	executor := newTestExecutor(t)
+	executor.interruptGrace = 200 * time.Millisecond
+	ctx := context.Background()
+
+	result := make(chan interface{}, 1)
+	go func() {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "trap '' INT; sleep 30"})
+		assert.NoError(t, err)
+		result <- obs
+	}()
+
+	var pid int
+	require.Eventually(t, func() bool {
+		executor.mu.RLock()
+		defer executor.mu.RUnlock()
+		for running := range executor.running {
+			pid = running
+			return true
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+
+	signal, err := executor.InterruptCommand(pid, "INT")
+	require.NoError(t, err)
+	assert.Equal(t, "TERM", signal, "SIGINT is trapped, so the command should only stop at SIGTERM")
+
+	select {
+	case obs := <-result:
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+		assert.NotEqual(t, 0, cmdObs.Extras.ExitCode)
+	case <-time.After(5 * time.Second):
+		t.Fatal("command was not killed")
+	}
+
+	_, err = executor.InterruptCommand(pid, "INT")
+	assert.ErrorIs(t, err, ErrCommandNotRunning)
+	_, err = executor.InterruptCommand(pid, "HUP")
+	assert.ErrorIs(t, err, ErrUnsupportedSignal)
+}
+
+func TestExecuteCmdRun_TimestampOutput(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	t.Run("disabled by default", func(t *testing.T) {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "echo a"})
+		require.NoError(t, err)
+		assert.Empty(t, obs.(models.Observation[models.CmdOutputExtras]).Extras.Timeline)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.TimestampCommandOutput = true })
+		defer reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.TimestampCommandOutput = false })
+
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "echo a; sleep 0.05; echo b >&2; sleep 0.05; printf c"})
+		require.NoError(t, err)
+
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+		timeline := cmdObs.Extras.Timeline
+		require.Len(t, timeline, 3)
+		assert.Equal(t, models.OutputLine{Time: timeline[0].Time, Stream: "stdout", Line: "a"}, timeline[0])
+		assert.Equal(t, models.OutputLine{Time: timeline[1].Time, Stream: "stderr", Line: "b"}, timeline[1])
+		assert.Equal(t, models.OutputLine{Time: timeline[2].Time, Stream: "stdout", Line: "c"}, timeline[2])
+		assert.True(t, timeline[0].Time.Before(timeline[1].Time), "timestamps must increase")
+		assert.True(t, timeline[1].Time.Before(timeline[2].Time), "timestamps must increase")
+	})
+}
+
+func TestExecuteCmdRun_SpanEvents(t *testing.T) {
+	executor := newTestExecutor(t)
+	recorder := tracetest.NewSpanRecorder()
+	executor.tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	_, err := executor.executeCmdRun(context.Background(), models.CmdRunAction{Command: "echo hello; exit 3"})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	events := spans[0].Events()
+
+	var names []string
+	for _, event := range events {
+		names = append(names, event.Name)
+	}
+	require.Equal(t, []string{"send_command", "capture_start", "capture_complete"}, names)
+	for i := 1; i < len(events); i++ {
+		assert.False(t, events[i].Time.Before(events[i-1].Time), "event times must not decrease")
+	}
+	assert.Contains(t, events[1].Attributes, attribute.Int("output.bytes", len("hello\n")))
+	assert.Contains(t, events[2].Attributes, attribute.Int("exit_code", 3))
+}
+
+func TestSessionState_SaveAndLoad(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state", "session.json")
+	withStatePath := func(cfg *config.Config) { cfg.Server.SessionStatePath = statePath }
+	executor := newTestExecutorWithConfig(t, withStatePath)
+	ctx := context.Background()
+
+	require.NoError(t, os.Mkdir(filepath.Join(executor.workingDir, "project"), 0755))
+	_, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "export GREETING=hello && cd project"})
+	require.NoError(t, err)
+	require.NoError(t, executor.saveSessionState())
+
+	restored := newTestExecutorWithConfig(t, withStatePath)
+	require.NoError(t, restored.loadSessionState())
+
+	assert.Equal(t, filepath.Join(executor.workingDir, "project"), restored.sessionCwd())
+	assert.Equal(t, []string{"export GREETING=hello && cd project"}, restored.history)
+	assert.Contains(t, restored.sessionEnvironment(), "GREETING=hello")
+
+	t.Run("commands start in the restored directory", func(t *testing.T) {
+		obs, err := restored.executeCmdRun(ctx, models.CmdRunAction{Command: "pwd"})
+		require.NoError(t, err)
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+		assert.Equal(t, filepath.Join(executor.workingDir, "project")+"\n", cmdObs.Content)
+	})
+
+	t.Run("state file defaults outside the workspace", func(t *testing.T) {
+		fresh := newTestExecutor(t)
+		assert.False(t, isWithinDir(fresh.workingDir, fresh.sessionStatePath()), fresh.sessionStatePath())
+	})
+
+	t.Run("missing state file is not an error", func(t *testing.T) {
+		fresh := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+			cfg.Server.SessionStatePath = filepath.Join(t.TempDir(), "missing.json")
+		})
+		require.NoError(t, fresh.loadSessionState())
+		assert.Equal(t, fresh.workingDir, fresh.sessionCwd())
+	})
+}
+
+func TestExecuteAction_LogToFile(t *testing.T) {
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.MaxObservationContentBytes = 1024
+	})
+	ctx := context.Background()
+
+	obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+		"action": "run",
+		"args": map[string]interface{}{
+			"command":     "seq 1 100000",
+			"log_to_file": "logs/build.log",
+		},
+	})
+	require.NoError(t, err)
+
+	cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+	require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+	assert.True(t, cmdObs.Extras.Truncated)
+	assert.Equal(t, "logs/build.log", cmdObs.Extras.LogFile)
+
+	logged, err := os.ReadFile(filepath.Join(executor.workingDir, "logs/build.log"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(logged)), "\n")
+	require.Len(t, lines, 100000)
+	assert.Equal(t, "1", lines[0])
+	assert.Equal(t, "100000", lines[99999])
+
+	t.Run("log file outside the workspace is rejected", func(t *testing.T) {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "echo hi", LogToFile: "../outside.log"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "SecurityError", errObs.Extras.ErrorID)
+	})
+}
+
+func TestExecuteCmdRun_StripANSI(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+	command := `printf '\033[1;31merror\033[0m: \033]0;title\007see [docs] (x)\n'`
+
+	t.Run("enabled", func(t *testing.T) {
+		reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.StripANSI = true })
+		defer reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.StripANSI = false })
+
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: command})
+		require.NoError(t, err)
+		assert.Equal(t, "error: see [docs] (x)\n", obs.(models.Observation[models.CmdOutputExtras]).Content)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: command})
+		require.NoError(t, err)
+		assert.Contains(t, obs.(models.Observation[models.CmdOutputExtras]).Content, "\x1b[1;31m")
+	})
+}
+
+// Commands complete when the shell exits rather than when a prompt sentinel is seen,
+// so an rc file that changes PS1 or installs its own EXIT trap can't make them hang.
+func TestExecuteCmdRun_CustomShellRC(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	rcFile := filepath.Join(t.TempDir(), "bashrc")
+	require.NoError(t, os.WriteFile(rcFile, []byte("PS1='custom> '\nPROMPT_COMMAND='echo prompt'\ntrap 'echo rc-exit' EXIT\n"), 0644))
+	executor.sessionEnv = append(executor.sessionEnvironment(), "BASH_ENV="+rcFile)
+	require.NoError(t, os.Mkdir(filepath.Join(executor.workingDir, "sub"), 0755))
+
+	done := make(chan interface{}, 1)
+	go func() {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "cd sub && echo $PS1"})
+		assert.NoError(t, err)
+		done <- obs
+	}()
+
+	select {
+	case obs := <-done:
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+		assert.Equal(t, "custom>\n", cmdObs.Content)
+		assert.Equal(t, 0, cmdObs.Extras.ExitCode)
+		assert.Equal(t, filepath.Join(executor.workingDir, "sub"), cmdObs.Extras.WorkingDir, "the runtime's EXIT trap must win over the rc's")
+	case <-time.After(5 * time.Second):
+		t.Fatal("command completion was not detected")
+	}
+}
+
+func TestExecuteCmdRun_Killed(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		command string
+	}{
+		{"child killed", "echo started; sh -c 'kill -9 $$'"},
+		{"shell killed", "echo started; kill -9 $$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: tt.command})
+			require.NoError(t, err)
+
+			cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+			require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+			assert.True(t, cmdObs.Extras.Killed)
+			assert.Contains(t, cmdObs.Content, "started")
+			assert.Contains(t, cmdObs.Content, "[Process killed (possibly out of memory)]")
+		})
+	}
+
+	t.Run("timeout is not reported as killed", func(t *testing.T) {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "sleep 5", HardTimeout: 1})
+		require.NoError(t, err)
+
+		cmdObs := obs.(models.Observation[models.CmdOutputExtras])
+		assert.False(t, cmdObs.Extras.Killed)
+		assert.NotContains(t, cmdObs.Content, "possibly out of memory")
+	})
+}
+
+func TestExecuteCmdRun_OutputEncoding(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+	// "café" in Latin-1, which is not valid UTF-8
+	command := `printf 'caf\351'`
+
+	run := func(t *testing.T) string {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: command})
+		require.NoError(t, err)
+		return obs.(models.Observation[models.CmdOutputExtras]).Content
+	}
+
+	t.Run("invalid UTF-8 is replaced", func(t *testing.T) {
+		assert.Equal(t, "caf�", run(t))
+	})
+
+	t.Run("configured encoding", func(t *testing.T) {
+		reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.CommandOutputEncoding = "ISO-8859-1" })
+		defer reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.CommandOutputEncoding = "" })
+		assert.Equal(t, "café", run(t))
+	})
+
+	t.Run("session locale", func(t *testing.T) {
+		_, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "export LANG=fr_FR.ISO-8859-1"})
+		require.NoError(t, err)
+		assert.Equal(t, "café", run(t))
+	})
+}
+
+func TestExecuteCmdRun_HereDoc(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		command string
+		output  string
+	}{
+		{
+			name:    "followed by more commands",
+			command: "cat > notes.txt <<'EOF'\nfirst line\ntrap 'echo injected' EXIT\nexit 3\nEOF\nwc -l < notes.txt",
+			output:  "3\n",
+		},
+		{
+			name:    "delimiter on the last line",
+			command: "cat > notes.txt <<'EOF'\nfirst line\ntrap 'echo injected' EXIT\nexit 3\nEOF",
+			output:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan interface{}, 1)
+			go func() {
+				obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: tt.command})
+				assert.NoError(t, err)
+				done <- obs
+			}()
+
+			select {
+			case obs := <-done:
+				cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+				require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+				assert.Equal(t, tt.output, cmdObs.Content)
+				assert.Equal(t, 0, cmdObs.Extras.ExitCode)
+				assert.Equal(t, executor.workingDir, cmdObs.Extras.WorkingDir)
+			case <-time.After(5 * time.Second):
+				t.Fatal("command completion was not detected")
+			}
+
+			data, err := os.ReadFile(filepath.Join(executor.workingDir, "notes.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "first line\ntrap 'echo injected' EXIT\nexit 3\n", string(data))
+		})
+	}
+}
+
+func TestExecuteCmdRun_EnvFile(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	dotenv := `# database settings
+DB_HOST=localhost # inline comment
+export DB_USER='admin # not a comment'
+DB_PASS="p@ss \"quoted\""
+GREETING="hello
+world"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, ".env"), []byte(dotenv), 0644))
+
+	run := func(t *testing.T, action models.CmdRunAction) models.Observation[models.CmdOutputExtras] {
+		obs, err := executor.executeCmdRun(ctx, action)
+		require.NoError(t, err)
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+		return cmdObs
+	}
+
+	obs := run(t, models.CmdRunAction{Command: `echo "$DB_HOST|$DB_USER|$DB_PASS"`, EnvFile: ".env"})
+	assert.Equal(t, `localhost|admin # not a comment|p@ss "quoted"`+"\n", obs.Content)
+
+	obs = run(t, models.CmdRunAction{Command: `echo "$GREETING"`})
+	assert.Equal(t, "hello\nworld\n", obs.Content, "loaded variables should persist for later commands")
+
+	t.Run("streamed command", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "stream.env"), []byte("STREAMED=yes\n"), 0644))
+
+		outputChan := make(chan StreamEvent, 10)
+		require.NoError(t, executor.StreamCommandExecution(ctx, models.CmdRunAction{Command: `echo "$STREAMED"`, EnvFile: "stream.env"}, outputChan))
+		var output []string
+		for event := range outputChan {
+			if event.Type == StreamOutput {
+				output = append(output, event.Data)
+			}
+		}
+		assert.Equal(t, []string{"yes\n"}, output)
+
+		outputChan = make(chan StreamEvent, 10)
+		assert.Error(t, executor.StreamCommandExecution(ctx, models.CmdRunAction{Command: "true", EnvFile: "missing.env"}, outputChan))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: "true", EnvFile: "missing.env"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "EnvFileError", errObs.Extras.ErrorID)
+	})
+
+	t.Run("invalid line", func(t *testing.T) {
+		_, err := parseDotenv("VALID=1\nnot a variable\n")
+		assert.ErrorContains(t, err, "line 2")
+	})
+}
+
+func TestStreamCommandExecution_NoEchoOrPrompt(t *testing.T) {
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.StripANSI = true
+	})
+	ctx := context.Background()
+
+	rcFile := filepath.Join(t.TempDir(), "bashrc")
+	require.NoError(t, os.WriteFile(rcFile, []byte("PS1='custom> '\n"), 0644))
+	executor.sessionEnv = append(executor.sessionEnvironment(), "BASH_ENV="+rcFile)
+
+	command := `printf '\033[32mgreen\033[0m\n'; echo done`
+	outputChan := make(chan StreamEvent, 10)
+	require.NoError(t, executor.StreamCommandExecution(ctx, models.CmdRunAction{Command: command}, outputChan))
+
+	started := <-outputChan
+	assert.Equal(t, StreamStarted, started.Type)
+	assert.Positive(t, started.CommandID)
+
+	var lines []string
+	for event := range outputChan {
+		lines = append(lines, event.Data)
+	}
+	assert.Equal(t, []string{"green\n", "done\n"}, lines)
+
+	batch, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: command})
+	require.NoError(t, err)
+	cmdObs, ok := batch.(models.Observation[models.CmdOutputExtras])
+	require.True(t, ok, "expected CmdOutputObservation, got %T", batch)
+	assert.Equal(t, strings.Join(lines, ""), cmdObs.Content, "streamed and batch output should match")
+}
+
+func TestStreamCommandExecution_Finishes(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	t.Run("background child holds the pipes", func(t *testing.T) {
+		outputChan := make(chan StreamEvent, 10)
+		start := time.Now()
+		require.NoError(t, executor.StreamCommandExecution(context.Background(), models.CmdRunAction{Command: "sleep 5 & echo hi"}, outputChan))
+		assert.Less(t, time.Since(start), 4*time.Second, "the stream should end once the shell exits")
+
+		var lines []string
+		for event := range outputChan {
+			if event.Type == StreamOutput {
+				lines = append(lines, event.Data)
+			}
+		}
+		assert.Equal(t, []string{"hi\n"}, lines)
+	})
+
+	t.Run("consumer stops reading", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		outputChan := make(chan StreamEvent)
+		done := make(chan error, 1)
+		go func() {
+			done <- executor.StreamCommandExecution(ctx, models.CmdRunAction{Command: "yes"}, outputChan)
+		}()
+
+		started := <-outputChan
+		require.Equal(t, StreamStarted, started.Type)
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("StreamCommandExecution did not return after the context was cancelled")
+		}
+	})
+}
+
+func TestExecuteCmdRun_SummarizeInstall(t *testing.T) {
+	// A fake pip that prints pip's usual progress output, or fails for a missing package
+	bin := t.TempDir()
+	fakePip := `#!/bin/sh
+if [ "$2" = "missing-pkg" ]; then
+	echo "ERROR: Could not find a version that satisfies the requirement missing-pkg" >&2
+	exit 1
+fi
+echo "Collecting requests"
+echo "  Downloading requests-2.31.0-py3-none-any.whl (62 kB)"
+echo "Collecting urllib3<3,>=1.21.1"
+echo "  Downloading urllib3-2.0.7-py3-none-any.whl (124 kB)"
+echo "Installing collected packages: urllib3, requests"
+echo "Successfully installed requests-2.31.0 urllib3-2.0.7"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "pip"), []byte(fakePip), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.SummarizeInstallOutput = true
+	})
+	ctx := context.Background()
+
+	run := func(t *testing.T, command string) models.Observation[models.CmdOutputExtras] {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: command})
+		require.NoError(t, err)
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+		return cmdObs
+	}
+
+	t.Run("success", func(t *testing.T) {
+		obs := run(t, "pip install requests")
+
+		assert.Equal(t, "pip install succeeded: requests-2.31.0, urllib3-2.0.7 (6 lines of output omitted)", obs.Content)
+		assert.Equal(t, &models.InstallInfo{
+			Manager:  "pip",
+			Packages: []string{"requests-2.31.0", "urllib3-2.0.7"},
+			Success:  true,
+		}, obs.Extras.Install)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		obs := run(t, "pip install missing-pkg")
+
+		assert.Equal(t, 1, obs.Extras.ExitCode)
+		assert.Equal(t, "pip install failed with exit code 1:\nERROR: Could not find a version that satisfies the requirement missing-pkg", obs.Content)
+		assert.Equal(t, &models.InstallInfo{Manager: "pip", Packages: []string{"missing-pkg"}}, obs.Extras.Install)
+	})
+
+	t.Run("other commands are untouched", func(t *testing.T) {
+		obs := run(t, "echo pip install requests")
+
+		assert.Equal(t, "pip install requests\n", obs.Content)
+		assert.Nil(t, obs.Extras.Install)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.SummarizeInstallOutput = false })
+		defer reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.SummarizeInstallOutput = true })
+
+		obs := run(t, "pip install requests")
+		assert.Contains(t, obs.Content, "Collecting requests")
+		assert.Nil(t, obs.Extras.Install)
+	})
+}
+
+func TestDetectInstallCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		manager  string
+		packages []string
+	}{
+		{"pip install requests flask", "pip", []string{"requests", "flask"}},
+		{"python3 -m pip install -r requirements.txt numpy", "pip", []string{"numpy"}},
+		{"uv pip install --upgrade httpx", "pip", []string{"httpx"}},
+		{"cd app && npm install --save-dev jest", "npm", []string{"jest"}},
+		{"yarn add react", "yarn", []string{"react"}},
+		{"sudo DEBIAN_FRONTEND=noninteractive apt-get install -y curl git", "apt", []string{"curl", "git"}},
+		{"pip3.12 install rich", "pip", []string{"rich"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			install, ok := detectInstallCommand(tt.command)
+			require.True(t, ok)
+			assert.Equal(t, tt.manager, install.manager)
+			assert.Equal(t, tt.packages, install.packages)
+		})
+	}
+
+	for _, command := range []string{"echo pip install x", "pip list", "npm run build", "apt-get update"} {
+		_, ok := detectInstallCommand(command)
+		assert.False(t, ok, command)
+	}
+}
+
+func TestStreamCommandExecution_StatusMarkers(t *testing.T) {
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.StreamStatusIntervalSec = 1
+	})
+	ctx := context.Background()
+
+	outputChan := make(chan StreamEvent, 10)
+	require.NoError(t, executor.StreamCommandExecution(ctx, models.CmdRunAction{Command: "echo start; sleep 2.5; echo done"}, outputChan))
+
+	var output, statuses []string
+	for event := range outputChan {
+		switch event.Type {
+		case StreamOutput:
+			output = append(output, event.Data)
+		case StreamStatus:
+			statuses = append(statuses, event.Data)
+		}
+	}
+	assert.Equal(t, []string{"start\n", "done\n"}, output)
+	require.NotEmpty(t, statuses)
+	assert.Equal(t, "still running after 1s", statuses[0])
+
+	t.Run("chatty command", func(t *testing.T) {
+		outputChan := make(chan StreamEvent, 100)
+		command := "for i in $(seq 12); do echo $i; sleep 0.2; done"
+		require.NoError(t, executor.StreamCommandExecution(ctx, models.CmdRunAction{Command: command}, outputChan))
+
+		var lines int
+		for event := range outputChan {
+			switch event.Type {
+			case StreamOutput:
+				lines++
+			case StreamStatus:
+				t.Errorf("unexpected status event %q between lines of output", event.Data)
+			}
+		}
+		assert.Equal(t, 12, lines)
+	})
+}
@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// pathLocks serializes read-modify-write operations on the same file, so that concurrent edits
+// can't read the same content and overwrite each other's changes. The zero value is ready to use.
+type pathLocks struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+// pathLock is the lock of one path, removed from pathLocks once nobody holds or waits for it
+type pathLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock blocks until no other operation holds path and returns the function releasing it
+func (l *pathLocks) lock(path string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*pathLock)
+	}
+	pl, ok := l.locks[path]
+	if !ok {
+		pl = &pathLock{}
+		l.locks[path] = pl
+	}
+	pl.refs++
+	l.mu.Unlock()
+
+	pl.Lock()
+	return func() {
+		pl.Unlock()
+
+		l.mu.Lock()
+		pl.refs--
+		if pl.refs == 0 {
+			delete(l.locks, path)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// lockFile locks the resolved path of a file for the duration of a read-modify-write operation.
+// Symbolic links are resolved so that edits through different links to one file are serialized too.
+func (e *Executor) lockFile(resolvedPath string) (unlock func()) {
+	if target, err := filepath.EvalSymlinks(resolvedPath); err == nil {
+		resolvedPath = target
+	}
+	return e.fileLocks.lock(resolvedPath)
+}
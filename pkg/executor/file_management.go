@@ -3,6 +3,7 @@ package executor
 import (
 	"archive/zip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -15,58 +16,137 @@ import (
 	"github.com/denysvitali/openhands-runtime-go/internal/models"
 )
 
+// ErrTooManyFileOps is returned when the concurrent file operation limit is reached
+var ErrTooManyFileOps = errors.New("too many concurrent file operations")
+
+// ErrNotRegularFile is returned when a file operation needs a regular file but the path is not one
+var ErrNotRegularFile = errors.New("not a regular file")
+
+// acquireFileOp reserves a file operation slot without blocking.
+// The returned release function must be called once the operation completes.
+func (e *Executor) acquireFileOp() (func(), error) {
+	if e.fileOps == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case e.fileOps <- struct{}{}:
+		return func() { <-e.fileOps }, nil
+	default:
+		return nil, ErrTooManyFileOps
+	}
+}
+
 // validatePathSecurity checks for directory traversal attacks and other security issues
 func (e *Executor) validatePathSecurity(path string) error {
 	// TODO: Implement something meaningful considering that the runtime environment is already sandboxed
 	return nil
 }
 
-// ListFiles lists files in a directory
-func (e *Executor) ListFiles(ctx context.Context, path string, recursive bool) ([]models.FileInfo, error) {
+// defaultListMaxResults caps the entries ListFiles returns when ListOptions.MaxResults is not set
+const defaultListMaxResults = 10000
+
+// errListLimit stops the walk once ListFiles has collected its maximum number of entries
+var errListLimit = errors.New("list result limit reached")
+
+// ListOptions controls how ListFiles walks a directory
+type ListOptions struct {
+	Recursive  bool
+	MaxDepth   int // Levels below the directory to list when recursive, 0 for unlimited. Deeper directories are listed but not entered.
+	MaxResults int // Maximum number of entries, 0 for defaultListMaxResults
+	// RespectGitignore leaves out .git and the paths ignored by the .gitignore files in and below the directory
+	RespectGitignore bool
+}
+
+// ListFiles lists files in a directory. It reports whether the listing was cut at opts.MaxResults.
+func (e *Executor) ListFiles(ctx context.Context, path string, opts ListOptions) ([]models.FileInfo, bool, error) {
 	_, span := e.tracer.Start(ctx, "list_files")
 	defer span.End()
 
 	span.SetAttributes(
 		attribute.String("path", path),
-		attribute.Bool("recursive", recursive),
+		attribute.Bool("recursive", opts.Recursive),
+		attribute.Int("max_depth", opts.MaxDepth),
 	)
 
 	if err := e.validatePathSecurity(path); err != nil {
 		span.RecordError(err)
-		return nil, err
+		return nil, false, err
+	}
+
+	maxResults := opts.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultListMaxResults
 	}
 
 	resolvedPath := e.resolvePath(path)
 	var files []models.FileInfo
+	truncated := false
+
+	var ignore *gitignoreMatcher
+	if opts.RespectGitignore {
+		ignore = newGitignoreMatcher(resolvedPath)
+		ignore.loadDir(resolvedPath)
+	}
+	skip := func(path string, isDir bool) bool {
+		return ignore != nil && ((isDir && filepath.Base(path) == ".git") || ignore.ignored(path, isDir))
+	}
 
-	if recursive {
+	if opts.Recursive {
 		err := filepath.Walk(resolvedPath, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
+			// Like the non-recursive listing, only list what's inside the directory
+			if path == resolvedPath && info.IsDir() {
+				return nil
+			}
+			if skip(path, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if len(files) == maxResults {
+				truncated = true
+				return errListLimit
+			}
 			files = append(files, models.FileInfo{
 				Path:  e.toRelativePath(path),
 				IsDir: info.IsDir(),
 				Size:  info.Size(),
 			})
+			if info.IsDir() && opts.MaxDepth > 0 && pathDepth(resolvedPath, path) >= opts.MaxDepth {
+				return filepath.SkipDir
+			}
+			if ignore != nil && info.IsDir() {
+				ignore.loadDir(path)
+			}
 			return nil
 		})
-		if err != nil {
+		if err != nil && !errors.Is(err, errListLimit) {
 			span.RecordError(err)
-			return nil, err
+			return nil, false, err
 		}
 	} else {
 		dirEntries, err := os.ReadDir(resolvedPath)
 		if err != nil {
 			span.RecordError(err)
-			return nil, err
+			return nil, false, err
 		}
 
 		for _, entry := range dirEntries {
+			if skip(filepath.Join(resolvedPath, entry.Name()), entry.IsDir()) {
+				continue
+			}
+			if len(files) == maxResults {
+				truncated = true
+				break
+			}
 			info, err := entry.Info()
 			if err != nil {
 				span.RecordError(err)
-				return nil, err
+				return nil, false, err
 			}
 			files = append(files, models.FileInfo{
 				Path:  e.toRelativePath(filepath.Join(resolvedPath, entry.Name())),
@@ -76,7 +156,49 @@ func (e *Executor) ListFiles(ctx context.Context, path string, recursive bool) (
 		}
 	}
 
-	return files, nil
+	return files, truncated, nil
+}
+
+// pathDepth returns how many levels path is below root, 0 for root itself
+func pathDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// executeListFiles lists a directory for the agent, with one line per entry in the content
+// and the structured entries in the extras
+func (e *Executor) executeListFiles(ctx context.Context, action models.ListFilesAction) (interface{}, error) {
+	files, truncated, err := e.ListFiles(ctx, action.Path, ListOptions{
+		Recursive:        action.Recursive,
+		MaxDepth:         action.MaxDepth,
+		MaxResults:       action.MaxResults,
+		RespectGitignore: action.RespectGitignore,
+	})
+	if err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Cannot list %s: %v", action.Path, err), "FileListError"), nil
+	}
+	if files == nil {
+		files = []models.FileInfo{}
+	}
+
+	var content strings.Builder
+	for _, file := range files {
+		if file.IsDir {
+			fmt.Fprintf(&content, "%s/\n", file.Path)
+		} else {
+			fmt.Fprintf(&content, "%s (%d bytes)\n", file.Path, file.Size)
+		}
+	}
+	if truncated {
+		fmt.Fprintf(&content, "[Listing truncated at %d entries]\n", len(files))
+	}
+
+	observation := models.NewListFilesObservation(content.String(), e.observationPath(action.Path), files)
+	observation.Extras.Truncated = truncated
+	return observation, nil
 }
 
 // ListFileNames lists file names in a directory as strings (matching Python implementation)
@@ -131,12 +253,20 @@ func (e *Executor) ListFileNames(ctx context.Context, path string) ([]string, er
 }
 
 // UploadFile handles file uploads
-func (e *Executor) UploadFile(ctx context.Context, path string, content []byte) error {
+func (e *Executor) UploadFile(ctx context.Context, path string, content []byte) (err error) {
 	_, span := e.tracer.Start(ctx, "upload_file")
 	defer span.End()
+	defer func() { e.metrics.countFileOperation(ctx, "upload", err == nil) }()
 
 	span.SetAttributes(attribute.String("path", path))
 
+	release, err := e.acquireFileOp()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer release()
+
 	if err := e.validatePathSecurity(path); err != nil {
 		span.RecordError(err)
 		return err
@@ -149,11 +279,12 @@ func (e *Executor) UploadFile(ctx context.Context, path string, content []byte)
 		return err
 	}
 
-	if err := os.WriteFile(resolvedPath, content, 0644); err != nil {
+	if err := writeFileAtomic(resolvedPath, content, 0644); err != nil {
 		span.RecordError(err)
 		return err
 	}
 
+	e.metrics.recordFileSize(ctx, "upload", int64(len(content)))
 	return nil
 }
 
@@ -177,8 +308,8 @@ func (e *Executor) DownloadFile(ctx context.Context, path string) ([]byte, error
 		return nil, err
 	}
 
-	if fileInfo.Size() > e.config.Server.MaxFileSize {
-		err := fmt.Errorf("file size (%d bytes) exceeds maximum allowed size (%d bytes)", fileInfo.Size(), e.config.Server.MaxFileSize)
+	if maxSize := e.config.Get().Server.MaxFileSize; fileInfo.Size() > maxSize {
+		err := fmt.Errorf("file size (%d bytes) exceeds maximum allowed size (%d bytes)", fileInfo.Size(), maxSize)
 		span.RecordError(err)
 		return nil, err
 	}
@@ -192,6 +323,49 @@ func (e *Executor) DownloadFile(ctx context.Context, path string) ([]byte, error
 	return content, nil
 }
 
+// ServeFile opens the regular file at path and passes it to serve, which may seek within it to
+// answer ranged requests. It returns ErrNotRegularFile for directories and other special files.
+func (e *Executor) ServeFile(ctx context.Context, path string, serve func(content io.ReadSeeker, info os.FileInfo)) (err error) {
+	_, span := e.tracer.Start(ctx, "serve_file")
+	defer span.End()
+	defer func() { e.metrics.countFileOperation(ctx, "download", err == nil) }()
+
+	span.SetAttributes(attribute.String("path", path))
+
+	release, err := e.acquireFileOp()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer release()
+
+	if err := e.validatePathSecurity(path); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	file, err := os.Open(e.resolvePath(path))
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		span.RecordError(ErrNotRegularFile)
+		return ErrNotRegularFile
+	}
+
+	serve(file, info)
+	e.metrics.recordFileSize(ctx, "download", info.Size())
+	return nil
+}
+
 // StreamZipArchive creates a zip archive of the specified path and streams it to the writer
 func (e *Executor) StreamZipArchive(ctx context.Context, path string, writer io.Writer) error {
 	_, span := e.tracer.Start(ctx, "stream_zip_archive")
@@ -252,6 +426,11 @@ func (e *Executor) StreamZipArchive(ctx context.Context, path string, writer io.
 			return err
 		}
 
+		// Store symlinks as links rather than copies of their targets
+		if info.Mode()&os.ModeSymlink != 0 {
+			return writeZipSymlink(zipWriter, header, filePath)
+		}
+
 		// Create file entry
 		zipFileWriter, err := zipWriter.CreateHeader(header)
 		if err != nil {
@@ -283,21 +462,91 @@ func (e *Executor) StreamZipArchive(ctx context.Context, path string, writer io.
 	return nil
 }
 
-// StreamZipArchiveMultiple creates a zip archive from multiple paths and streams it to the writer
-func (e *Executor) StreamZipArchiveMultiple(ctx context.Context, paths []string, writer io.Writer) error {
+// ArchiveStats counts the regular files under paths and their total uncompressed size,
+// as StreamZipArchiveMultiple would archive them
+func (e *Executor) ArchiveStats(ctx context.Context, paths []string) (models.ArchiveStats, error) {
+	_, span := e.tracer.Start(ctx, "archive_stats")
+	defer span.End()
+
+	span.SetAttributes(attribute.StringSlice("paths", paths))
+
+	var stats models.ArchiveStats
+	for _, path := range paths {
+		if err := e.validatePathSecurity(path); err != nil {
+			span.RecordError(err)
+			return models.ArchiveStats{}, err
+		}
+
+		err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() {
+				stats.FileCount++
+				stats.TotalBytes += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			span.RecordError(err)
+			return models.ArchiveStats{}, err
+		}
+	}
+	return stats, nil
+}
+
+// writeZipSymlink adds a symlink entry to a zip archive. As in Info-ZIP, the entry's content is the
+// link target and its mode, already set from the file info in header, marks it as a symlink.
+func writeZipSymlink(zipWriter *zip.Writer, header *zip.FileHeader, linkPath string) error {
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		return err
+	}
+	header.Method = zip.Store
+	entry, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(entry, filepath.ToSlash(target))
+	return err
+}
+
+// StreamZipArchiveMultiple creates a zip archive from multiple paths and streams it to the writer.
+// Up to server.zip_workers files are compressed in parallel; entries are written in walk order regardless.
+func (e *Executor) StreamZipArchiveMultiple(ctx context.Context, paths []string, writer io.Writer) (err error) {
 	_, span := e.tracer.Start(ctx, "stream_zip_archive_multiple")
 	defer span.End()
+	defer func() { e.metrics.countFileOperation(ctx, "archive", err == nil) }()
 
 	span.SetAttributes(attribute.StringSlice("paths", paths))
 
+	release, err := e.acquireFileOp()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer release()
+
+	// Count the archive bytes once the zip writer has flushed its central directory
+	counter := &countingWriter{w: writer}
+	defer func() { e.metrics.archiveBytes.Add(ctx, counter.n) }()
+
 	// Create a new zip writer that writes directly to the provided writer
-	zipWriter := zip.NewWriter(writer)
+	zipWriter := zip.NewWriter(counter)
 	defer func() {
 		if err := zipWriter.Close(); err != nil {
 			span.RecordError(fmt.Errorf("failed to close zip writer: %w", err))
 		}
 	}()
 
+	streamer := newZipStreamer(zipWriter, e.config.Get().Server.ZipWorkers)
+	defer func() {
+		if closeErr := streamer.close(); err == nil && closeErr != nil {
+			span.RecordError(closeErr)
+			err = closeErr
+		}
+	}()
+
 	// Process each path
 	for _, path := range paths {
 		if err := e.validatePathSecurity(path); err != nil {
@@ -328,8 +577,7 @@ func (e *Executor) StreamZipArchiveMultiple(ctx context.Context, paths []string,
 						Name:     baseName + "/",
 						Modified: info.ModTime(),
 					}
-					_, err := zipWriter.CreateHeader(header)
-					return err
+					return streamer.add(header, filePath, info)
 				}
 				// For single file, use the base name
 				relativePath = baseName
@@ -354,36 +602,11 @@ func (e *Executor) StreamZipArchiveMultiple(ctx context.Context, paths []string,
 			header.Modified = info.ModTime()
 
 			// If it's a directory, add trailing slash
-			if info.IsDir() {
-				if !strings.HasSuffix(header.Name, "/") {
-					header.Name += "/"
-				}
-				// Create directory entry
-				_, err := zipWriter.CreateHeader(header)
-				return err
-			}
-
-			// Create file entry
-			zipFileWriter, err := zipWriter.CreateHeader(header)
-			if err != nil {
-				return err
-			}
-
-			// Open the file to copy its contents
-			file, err := os.Open(filePath)
-			if err != nil {
-				return err
+			if info.IsDir() && !strings.HasSuffix(header.Name, "/") {
+				header.Name += "/"
 			}
-			defer func() {
-				if closeErr := file.Close(); closeErr != nil {
-					// Log error but don't override the main error
-					span.RecordError(fmt.Errorf("failed to close file %s: %w", filePath, closeErr))
-				}
-			}()
 
-			// Copy file contents to zip
-			_, err = io.Copy(zipFileWriter, file)
-			return err
+			return streamer.add(header, filePath, info)
 		})
 
 		if err != nil {
@@ -36,7 +36,8 @@ func (e *Executor) readFileInitialChunk(path string) ([]byte, int, error) {
 }
 
 // isChunkPotentiallyBinary checks if a given byte slice (chunk) is potentially binary.
-// It does this by looking for non-printable ASCII characters, excluding tab, newline, and carriage return.
+// It does this by looking for NUL bytes and control characters, excluding tab, newline, and carriage return.
+// Bytes above 127 are not counted, since they appear in UTF-8 and Latin-1 encoded text.
 func isChunkPotentiallyBinary(chunk []byte, n int) bool {
 	// Count the number of non-printable characters
 	nonPrintableCount := 0
@@ -49,9 +50,14 @@ func isChunkPotentiallyBinary(chunk []byte, n int) bool {
 			continue
 		}
 
+		// NUL bytes never appear in text files outside of UTF-16/32
+		if char == 0 {
+			return true
+		}
+
 		totalCount++
-		// Consider non-ASCII and control characters as indicators of binary content
-		if char < 32 || char > 126 {
+		// Consider control characters as indicators of binary content
+		if char < 32 || char == 127 {
 			nonPrintableCount++
 		}
 	}
@@ -94,7 +100,9 @@ func (e *Executor) handleMediaType(ctx context.Context, path string, action mode
 		// Format as data URL
 		mediaContent := fmt.Sprintf("data:%s;base64,%s", mimeType, encoded)
 
-		return models.NewFileReadObservation(mediaContent, action.Path), true, nil
+		observation := models.NewFileReadObservation(mediaContent, e.observationPath(action.Path))
+		observation.Extras.Media = true
+		return observation, true, nil
 	}
 	return models.Observation[models.FileReadExtras]{}, false, nil
 }
@@ -107,6 +115,13 @@ func (e *Executor) executeFileRead(ctx context.Context, action models.FileReadAc
 	span.SetAttributes(attribute.String("path", action.Path))
 	e.logger.Infof("Reading file: %s", action.Path)
 
+	release, err := e.acquireFileOp()
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("cannot read %s: %w", action.Path, err)
+	}
+	defer release()
+
 	// Security check
 	if err := e.SecurityCheck(action.Path); err != nil {
 		return models.NewErrorObservation(fmt.Sprintf("Security error: %v", err), "SecurityError"), nil
@@ -150,7 +165,10 @@ func (e *Executor) executeFileRead(ctx context.Context, action models.FileReadAc
 		return models.NewErrorObservation(errorMsg, "FileReadError"), nil
 	}
 
-	if isChunkPotentiallyBinary(buffer, n) {
+	// A UTF-16 BOM is a strong text indicator, but the interleaved NUL bytes would trip the binary check
+	bom := sniffBOM(buffer[:n])
+	isUTF16 := bom == encodingUTF16LE || bom == encodingUTF16BE
+	if !isUTF16 && isChunkPotentiallyBinary(buffer, n) {
 		e.logger.Warnf("Binary file detected: %s", path)
 		span.SetAttributes(attribute.Bool("is_binary_file", true))
 		return models.NewErrorObservation("ERROR_BINARY_FILE", "BinaryFileError"), nil
@@ -165,10 +183,26 @@ func (e *Executor) executeFileRead(ctx context.Context, action models.FileReadAc
 		return models.NewErrorObservation(errorMsg, "FileReadError"), nil
 	}
 
-	// Convert to string and handle line ranges
-	contentStr := string(content)
+	// Transcode to UTF-8 and handle line ranges
+	contentStr, sourceEncoding, err := decodeToUTF8(content)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Error decoding file %s as %s: %v", path, sourceEncoding, err)
+		e.logger.Errorf(errorMsg)
+		span.RecordError(err)
+		return models.NewErrorObservation(errorMsg, "FileReadError"), nil
+	}
+	span.SetAttributes(attribute.String("encoding", sourceEncoding))
+	e.metrics.recordFileSize(ctx, "read", int64(len(content)))
+
+	lines := strings.Split(contentStr, "\n")
+	totalLines := len(lines)
+	if strings.HasSuffix(contentStr, "\n") {
+		// A trailing newline terminates the last line rather than starting a new one
+		totalLines--
+	}
+
+	truncated := false
 	if action.Start > 0 || action.End > 0 {
-		lines := strings.Split(contentStr, "\n")
 		start := action.Start
 		end := action.End
 
@@ -189,10 +223,23 @@ func (e *Executor) executeFileRead(ctx context.Context, action models.FileReadAc
 		} else {
 			e.logger.Warnf("Invalid line range: start=%d, end=%d, total lines=%d", start, end, len(lines))
 		}
-	}
-
-	e.logger.Debugf("Successfully read file: %s (%d bytes)", path, len(contentStr))
-	return models.NewFileReadObservation(contentStr, action.Path), nil
+	} else if maxLines := e.config.Get().Server.MaxReadLines; maxLines > 0 && totalLines > maxLines {
+		// Without an explicit range, cap the read so the agent can page through the rest
+		e.logger.Debugf("Truncating read of %s to %d of %d lines", path, maxLines, totalLines)
+		contentStr = strings.Join(lines[:maxLines], "\n")
+		truncated = true
+	}
+	span.SetAttributes(
+		attribute.Int("total_lines", totalLines),
+		attribute.Bool("truncated", truncated),
+	)
+
+	e.logger.Debugf("Successfully read file: %s (%d bytes, %s)", path, len(contentStr), sourceEncoding)
+	observation := models.NewFileReadObservation(contentStr, e.observationPath(action.Path))
+	observation.Extras.Encoding = sourceEncoding
+	observation.Extras.TotalLines = totalLines
+	observation.Extras.Truncated = truncated
+	return observation, nil
 }
 
 // executeFileWrite writes to a file
@@ -209,6 +256,12 @@ func (e *Executor) executeFileWrite(ctx context.Context, action models.FileWrite
 	}
 
 	path := e.resolvePath(action.Path)
+	defer e.lockFile(path)()
+
+	newFileMode, err := parseFileMode(action.Mode)
+	if err != nil {
+		return models.NewErrorObservation(err.Error(), "InvalidFileMode"), nil
+	}
 
 	// Create directories if they don't exist
 	dirPath := filepath.Dir(path)
@@ -220,26 +273,20 @@ func (e *Executor) executeFileWrite(ctx context.Context, action models.FileWrite
 	}
 
 	// Check if the file exists and get its permissions
-	var fileMode os.FileMode = 0644
+	fileMode := newFileMode
 	fileExists := false
 
 	if fileInfo, err := os.Stat(path); err == nil {
 		fileExists = true
 		fileMode = fileInfo.Mode().Perm()
-
-		// Get file ownership info (UID/GID)
-		// This requires syscall functions and varies by OS
-		// For simplicity, we're skipping the ownership handling here
-		// but it would be implemented with syscall.Stat_t on Linux
 	}
 
 	// Handle the different write modes
-	var err error
 	content := action.Contents
 
 	if fileExists {
 		// For existing files, we need to handle insert/replace logic
-		_, readErr := os.ReadFile(path)
+		existing, readErr := os.ReadFile(path)
 		if readErr != nil {
 			errorMsg := fmt.Sprintf("Failed to read existing file %s for modification: %v", path, readErr)
 			e.logger.Errorf(errorMsg)
@@ -247,13 +294,21 @@ func (e *Executor) executeFileWrite(ctx context.Context, action models.FileWrite
 			return models.NewErrorObservation(errorMsg, "FileWriteError"), nil
 		}
 
-		// Simple file overwrite
+		// Simple file overwrite, keeping whether the file ends with a newline
 		// In a more complex implementation, we could add support for line-based
 		// insertions and replacements using Start/End fields if added to the model
+		content = preserveTrailingNewline(string(existing), content, detectLineEnding(string(existing)))
+
+		if err := e.backupFile(path, existing); err != nil {
+			errorMsg := fmt.Sprintf("Failed to back up %s: %v", path, err)
+			e.logger.Errorf(errorMsg)
+			span.RecordError(err)
+			return models.NewErrorObservation(errorMsg, "BackupError"), nil
+		}
 	}
 
-	// Write the content to the file
-	err = os.WriteFile(path, []byte(content), fileMode)
+	// Write the content to the file, keeping the permissions and ownership of an existing one
+	err = writeFileAtomic(path, []byte(content), fileMode)
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to write to file %s: %v", path, err)
 		e.logger.Errorf(errorMsg)
@@ -261,21 +316,13 @@ func (e *Executor) executeFileWrite(ctx context.Context, action models.FileWrite
 		return models.NewErrorObservation(errorMsg, "FileWriteError"), nil
 	}
 
-	// Restore original permissions and ownership if the file existed before
-	if fileExists {
-		if chmodErr := os.Chmod(path, fileMode); chmodErr != nil {
-			e.logger.Warnf("Failed to restore permissions for %s: %v", path, chmodErr)
-		}
-
-		// Here we would restore ownership (UID/GID) if implemented
-	}
-
+	e.metrics.recordFileSize(ctx, "write", int64(len(content)))
 	e.logger.Infof("Successfully wrote to file: %s", path)
-	return models.NewFileWriteObservation("", action.Path), nil
+	return models.NewFileWriteObservation("", e.observationPath(action.Path)), nil
 }
 
-// executeFileCreate creates a new file and returns FileWriteObservation for new files
-func (e *Executor) executeFileCreate(ctx context.Context, path, content string) (interface{}, error) {
+// executeFileCreate creates a new file with the given permissions and returns FileWriteObservation for new files
+func (e *Executor) executeFileCreate(ctx context.Context, path, content string, mode os.FileMode) (interface{}, error) {
 	_, span := e.tracer.Start(ctx, "file_create")
 	defer span.End()
 
@@ -295,13 +342,13 @@ func (e *Executor) executeFileCreate(ctx context.Context, path, content string)
 	}
 
 	// Write file
-	if err := os.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
+	if err := writeFileAtomic(resolvedPath, []byte(content), mode); err != nil {
 		span.RecordError(err)
 		return models.NewErrorObservation(fmt.Sprintf("Failed to create file %s: %v", path, err), "FileCreateError"), nil
 	}
 
 	// Use FileWriteObservation for new file creation to avoid the assertion error
-	return models.NewFileWriteObservation(fmt.Sprintf("The file %s has been edited.", path), path), nil
+	return models.NewFileWriteObservation(fmt.Sprintf("The file %s has been edited.", path), e.observationPath(path)), nil
 }
 
 // executeFileEdit performs file edits using different approaches based on the action command
@@ -312,11 +359,21 @@ func (e *Executor) executeFileEdit(ctx context.Context, action models.FileEditAc
 	span.SetAttributes(attribute.String("path", action.Path))
 	span.SetAttributes(attribute.String("command", action.Command))
 
+	// Security check
+	if err := e.SecurityCheck(action.Path); err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Security error: %v", err), "SecurityError"), nil
+	}
+
 	path := e.resolvePath(action.Path)
 
+	mode, err := parseFileMode(action.Mode)
+	if err != nil {
+		return models.NewErrorObservation(err.Error(), "InvalidFileMode"), nil
+	}
+
 	// Handle LLM-based editing when content is provided
 	if action.Content != "" {
-		return e.executeLLMBasedEdit(ctx, action)
+		return e.executeLLMBasedEdit(ctx, action, mode)
 	}
 
 	// Handle ACI-based editing with specific commands
@@ -331,7 +388,7 @@ func (e *Executor) executeFileEdit(ctx context.Context, action models.FileEditAc
 		})
 	case "create":
 		// Create a new file with the provided content
-		return e.executeFileCreate(ctx, action.Path, action.FileText)
+		return e.executeFileCreate(ctx, action.Path, action.FileText, mode)
 	case "str_replace":
 		if action.OldStr == "" {
 			return models.NewErrorObservation("String replace requires non-empty old_str", "FileEditError"), nil
@@ -344,21 +401,32 @@ func (e *Executor) executeFileEdit(ctx context.Context, action models.FileEditAc
 		}
 		e.logger.Infof("Inserting text at line %d in %s", *action.InsertLine, action.Path)
 		return e.executeInsert(ctx, action.Path, *action.InsertLine, action.NewStr)
+	case "yaml_set":
+		if action.KeyPath == "" {
+			return models.NewErrorObservation("yaml_set requires key_path", "FileEditError"), nil
+		}
+		e.logger.Infof("Setting %s in %s", action.KeyPath, action.Path)
+		return e.executeYAMLSet(ctx, action.Path, action.KeyPath, action.Value)
 	case "undo_edit":
-		// TODO: Implement undo functionality
-		return models.NewErrorObservation("Undo edit not yet implemented", "UnsupportedEditCommand"), nil
+		if !e.config.Get().Server.BackupOnEdit {
+			return models.NewErrorObservation("Undo edit requires server.backup_on_edit to be enabled", "UnsupportedEditCommand"), nil
+		}
+		e.logger.Infof("Undoing last edit of %s", action.Path)
+		return e.executeUndoEdit(ctx, action.Path)
 	default:
 		// Unknown command
 		return models.NewErrorObservation(fmt.Sprintf("Unsupported file edit command: %s", action.Command), "UnsupportedEditCommand"), nil
 	}
 }
 
-// executeLLMBasedEdit handles LLM-based file editing using content, start, and end fields
-func (e *Executor) executeLLMBasedEdit(ctx context.Context, action models.FileEditAction) (interface{}, error) {
+// executeLLMBasedEdit handles LLM-based file editing using content, start, and end fields.
+// A file that doesn't exist yet is created with the given permissions.
+func (e *Executor) executeLLMBasedEdit(ctx context.Context, action models.FileEditAction, mode os.FileMode) (interface{}, error) {
 	_, span := e.tracer.Start(ctx, "llm_based_edit")
 	defer span.End()
 
 	resolvedPath := e.resolvePath(action.Path)
+	defer e.lockFile(resolvedPath)()
 
 	// Check if file exists
 	originalContent := ""
@@ -372,7 +440,7 @@ func (e *Executor) executeLLMBasedEdit(ctx context.Context, action models.FileEd
 			return models.NewErrorObservation(fmt.Sprintf("Failed to create directory for %s: %v", action.Path, err), "FileEditError"), nil
 		}
 
-		if err := os.WriteFile(resolvedPath, []byte(action.Content), 0644); err != nil {
+		if err := writeFileAtomic(resolvedPath, []byte(action.Content), mode); err != nil {
 			return models.NewErrorObservation(fmt.Sprintf("Failed to create file %s: %v", action.Path, err), "FileEditError"), nil
 		}
 
@@ -381,7 +449,7 @@ func (e *Executor) executeLLMBasedEdit(ctx context.Context, action models.FileEd
 
 		return models.NewFileEditObservation(
 			diff,
-			action.Path,
+			e.observationPath(action.Path),
 			"",             // old_content
 			action.Content, // new_content
 			"llm_edit",
@@ -394,9 +462,12 @@ func (e *Executor) executeLLMBasedEdit(ctx context.Context, action models.FileEd
 		return models.NewErrorObservation(fmt.Sprintf("Failed to read file %s: %v", action.Path, err), "FileEditError"), nil
 	}
 	originalContent = string(content)
+	lineEnding := detectLineEnding(originalContent)
+	originalText := normalizeLineEndings(originalContent)
+	editText := normalizeLineEndings(action.Content)
 
 	// Handle line-based editing
-	lines := strings.Split(originalContent, "\n")
+	lines := strings.Split(originalText, "\n")
 	totalLines := len(lines)
 
 	// Validate start and end parameters
@@ -419,11 +490,11 @@ func (e *Executor) executeLLMBasedEdit(ctx context.Context, action models.FileEd
 		), nil
 	}
 
-	var newContent string
+	var newText string
 
 	if start == -1 {
 		// Append to end of file
-		newContent = originalContent + "\n" + action.Content
+		newText = originalText + "\n" + editText
 	} else {
 		// Replace lines in range [start, end]
 		startIdx := start - 1 // Convert to 0-based
@@ -434,7 +505,7 @@ func (e *Executor) executeLLMBasedEdit(ctx context.Context, action models.FileEd
 		}
 
 		// Split content into lines for insertion
-		contentLines := strings.Split(action.Content, "\n")
+		contentLines := strings.Split(editText, "\n")
 
 		// Build new content
 		newLines := make([]string, 0)
@@ -442,22 +513,29 @@ func (e *Executor) executeLLMBasedEdit(ctx context.Context, action models.FileEd
 		newLines = append(newLines, contentLines...)
 		newLines = append(newLines, lines[endIdx:]...)
 
-		newContent = strings.Join(newLines, "\n")
+		newText = strings.Join(newLines, "\n")
+	}
+	newText = preserveTrailingNewline(originalText, newText, "\n")
+	newContent := restoreLineEndings(newText, lineEnding)
+
+	if err := e.backupFile(resolvedPath, content); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to back up %s: %v", action.Path, err), "BackupError"), nil
 	}
 
 	// Write the new content
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
+	if err := writeFileAtomic(resolvedPath, []byte(newContent), 0644); err != nil {
 		return models.NewErrorObservation(fmt.Sprintf("Failed to write to file %s: %v", action.Path, err), "FileEditError"), nil
 	}
 
 	// Generate diff
-	diff := e.generateDiff(originalContent, newContent, action.Path)
+	diff := e.generateDiff(originalText, newText, action.Path)
 
 	e.logger.Infof("Successfully edited file: %s", action.Path)
 
 	return models.NewFileEditObservation(
 		diff,
-		action.Path,
+		e.observationPath(action.Path),
 		originalContent,
 		newContent,
 		"llm_edit",
@@ -470,6 +548,7 @@ func (e *Executor) executeInsert(ctx context.Context, path string, insertLine in
 	defer span.End()
 
 	resolvedPath := e.resolvePath(path)
+	defer e.lockFile(resolvedPath)()
 
 	// Check if file exists
 	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
@@ -483,7 +562,9 @@ func (e *Executor) executeInsert(ctx context.Context, path string, insertLine in
 	}
 
 	originalContent := string(content)
-	lines := strings.Split(originalContent, "\n")
+	lineEnding := detectLineEnding(originalContent)
+	originalText := normalizeLineEndings(originalContent)
+	lines := strings.Split(originalText, "\n")
 
 	// Validate insert line
 	if insertLine < 0 || insertLine > len(lines) {
@@ -496,24 +577,30 @@ func (e *Executor) executeInsert(ctx context.Context, path string, insertLine in
 	// Insert the new string after the specified line
 	newLines := make([]string, 0, len(lines)+1)
 	newLines = append(newLines, lines[:insertLine]...)
-	newLines = append(newLines, newStr)
+	newLines = append(newLines, normalizeLineEndings(newStr))
 	newLines = append(newLines, lines[insertLine:]...)
 
-	newContent := strings.Join(newLines, "\n")
+	newText := preserveTrailingNewline(originalText, strings.Join(newLines, "\n"), "\n")
+	newContent := restoreLineEndings(newText, lineEnding)
+
+	if err := e.backupFile(resolvedPath, content); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to back up %s: %v", path, err), "BackupError"), nil
+	}
 
 	// Write the modified content
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
+	if err := writeFileAtomic(resolvedPath, []byte(newContent), 0644); err != nil {
 		return models.NewErrorObservation(fmt.Sprintf("Failed to write to file %s: %v", path, err), "FileEditError"), nil
 	}
 
 	// Generate diff
-	diff := e.generateDiff(originalContent, newContent, path)
+	diff := e.generateDiff(originalText, newText, path)
 
 	e.logger.Infof("Successfully inserted text at line %d in %s", insertLine, path)
 
 	return models.NewFileEditObservation(
 		diff,
-		path,
+		e.observationPath(path),
 		originalContent,
 		newContent,
 		"insert",
@@ -526,6 +613,7 @@ func (e *Executor) executeStringReplace(ctx context.Context, path, oldStr, newSt
 	defer span.End()
 
 	resolvedPath := e.resolvePath(path)
+	defer e.lockFile(resolvedPath)()
 
 	// Check if file exists
 	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
@@ -540,35 +628,96 @@ func (e *Executor) executeStringReplace(ctx context.Context, path, oldStr, newSt
 	}
 
 	oldContent := string(content)
+	lineEnding := detectLineEnding(oldContent)
+	oldText := normalizeLineEndings(oldContent)
 
-	// Replace string
-	newContent := strings.ReplaceAll(oldContent, oldStr, newStr)
+	// Replace string, matching regardless of the file's line ending style
+	newText := strings.ReplaceAll(oldText, normalizeLineEndings(oldStr), normalizeLineEndings(newStr))
 
 	// Check if content changed
-	if oldContent == newContent {
+	if oldText == newText {
 		return models.NewErrorObservation(fmt.Sprintf("String '%s' not found in %s", oldStr, path), "StringNotFound"), nil
 	}
 
+	newText = preserveTrailingNewline(oldText, newText, "\n")
+	newContent := restoreLineEndings(newText, lineEnding)
+
+	if err := e.backupFile(resolvedPath, content); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to back up %s: %v", path, err), "BackupError"), nil
+	}
+
 	// Write modified content back to file
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
+	if err := writeFileAtomic(resolvedPath, []byte(newContent), 0644); err != nil {
 		span.RecordError(err)
 		return models.NewErrorObservation(fmt.Sprintf("Failed to write changes to %s: %v", path, err), "FileEditError"), nil
 	}
 
 	// Generate diff
-	diff := e.generateDiff(oldContent, newContent, path)
+	diff := e.generateDiff(oldText, newText, path)
 
 	e.logger.Infof("Successfully replaced string in %s", path)
 
 	return models.NewFileEditObservation(
 		diff,
-		path,
+		e.observationPath(path),
 		oldContent,
 		newContent,
 		"str_replace",
 	), nil
 }
 
+// executeUndoEdit restores a file from its most recent backup, see backupFile. The backup is
+// consumed, so repeated undos step back through earlier edits.
+func (e *Executor) executeUndoEdit(ctx context.Context, path string) (interface{}, error) {
+	_, span := e.tracer.Start(ctx, "undo_edit")
+	defer span.End()
+
+	resolvedPath := e.resolvePath(path)
+	defer e.lockFile(resolvedPath)()
+
+	backup, err := e.latestBackup(resolvedPath)
+	if err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to find backups of %s: %v", path, err), "FileEditError"), nil
+	}
+	if backup == "" {
+		return models.NewErrorObservation(fmt.Sprintf("No edit history found for %s", path), "FileEditError"), nil
+	}
+
+	restored, err := os.ReadFile(backup)
+	if err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to read backup of %s: %v", path, err), "FileEditError"), nil
+	}
+
+	// The file may have been deleted since it was edited
+	current, err := os.ReadFile(resolvedPath)
+	if err != nil && !os.IsNotExist(err) {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to read file %s: %v", path, err), "FileEditError"), nil
+	}
+
+	if err := writeFileAtomic(resolvedPath, restored, 0644); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to restore %s: %v", path, err), "FileEditError"), nil
+	}
+	if err := os.Remove(backup); err != nil {
+		e.logger.Warnf("Failed to remove restored backup %s: %v", backup, err)
+	}
+
+	oldContent, newContent := string(current), string(restored)
+	e.logger.Infof("Restored %s from %s", path, backup)
+
+	return models.NewFileEditObservation(
+		e.generateDiff(normalizeLineEndings(oldContent), normalizeLineEndings(newContent), path),
+		e.observationPath(path),
+		oldContent,
+		newContent,
+		"undo_edit",
+	), nil
+}
+
 // generateDiff creates a simple diff representation between old and new content
 func (e *Executor) generateDiff(oldContent, newContent, filename string) string {
 	if oldContent == newContent {
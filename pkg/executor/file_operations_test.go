@@ -0,0 +1,1270 @@
+package executor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+)
+
+func TestExecuteFileRead_Encoding(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	t.Run("utf-16le with BOM", func(t *testing.T) {
+		// "héllo\n" encoded as UTF-16LE with a leading BOM
+		data := []byte{0xFF, 0xFE, 'h', 0x00, 0xE9, 0x00, 'l', 0x00, 'l', 0x00, 'o', 0x00, '\n', 0x00}
+		require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "utf16.txt"), data, 0644))
+
+		obs, err := executor.executeFileRead(ctx, models.FileReadAction{Path: "utf16.txt"})
+		require.NoError(t, err)
+
+		readObs, ok := obs.(models.Observation[models.FileReadExtras])
+		require.True(t, ok, "expected FileReadObservation, got %T", obs)
+		assert.Equal(t, "héllo\n", readObs.Content)
+		assert.Equal(t, "utf-16le", readObs.Extras.Encoding)
+	})
+
+	t.Run("windows-1252", func(t *testing.T) {
+		// "“café” crème" in Windows-1252, which is not valid UTF-8
+		data := []byte("\x93caf\xe9\x94 cr\xe8me")
+		require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "cp1252.txt"), data, 0644))
+
+		obs, err := executor.executeFileRead(ctx, models.FileReadAction{Path: "cp1252.txt"})
+		require.NoError(t, err)
+
+		readObs, ok := obs.(models.Observation[models.FileReadExtras])
+		require.True(t, ok, "expected FileReadObservation, got %T", obs)
+		assert.Equal(t, "“café” crème", readObs.Content)
+		assert.Equal(t, "windows-1252", readObs.Extras.Encoding)
+	})
+
+	t.Run("utf-8", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "utf8.txt"), []byte("plain text"), 0644))
+
+		obs, err := executor.executeFileRead(ctx, models.FileReadAction{Path: "utf8.txt"})
+		require.NoError(t, err)
+
+		readObs, ok := obs.(models.Observation[models.FileReadExtras])
+		require.True(t, ok, "expected FileReadObservation, got %T", obs)
+		assert.Equal(t, "plain text", readObs.Content)
+		assert.Equal(t, "utf-8", readObs.Extras.Encoding)
+	})
+}
+
+func TestExecuteFileRead_MaxReadLines(t *testing.T) {
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.MaxReadLines = 3
+	})
+	ctx := context.Background()
+
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "large.txt"), []byte("1\n2\n3\n4\n5\n"), 0644))
+
+	t.Run("read without range is capped", func(t *testing.T) {
+		obs, err := executor.executeFileRead(ctx, models.FileReadAction{Path: "large.txt"})
+		require.NoError(t, err)
+
+		readObs, ok := obs.(models.Observation[models.FileReadExtras])
+		require.True(t, ok, "expected FileReadObservation, got %T", obs)
+		assert.Equal(t, "1\n2\n3", readObs.Content)
+		assert.Equal(t, 5, readObs.Extras.TotalLines)
+		assert.True(t, readObs.Extras.Truncated)
+	})
+
+	t.Run("explicit range is not capped", func(t *testing.T) {
+		obs, err := executor.executeFileRead(ctx, models.FileReadAction{Path: "large.txt", Start: 2, End: 5})
+		require.NoError(t, err)
+
+		readObs, ok := obs.(models.Observation[models.FileReadExtras])
+		require.True(t, ok, "expected FileReadObservation, got %T", obs)
+		assert.Equal(t, "2\n3\n4\n5", readObs.Content)
+		assert.Equal(t, 5, readObs.Extras.TotalLines)
+		assert.False(t, readObs.Extras.Truncated)
+	})
+}
+
+func TestFileOperations_Symlinks(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	outsideDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.Symlink(outsideDir, filepath.Join(executor.workingDir, "escape")))
+
+	t.Run("read through escaping symlink is rejected", func(t *testing.T) {
+		obs, err := executor.executeFileRead(ctx, models.FileReadAction{Path: "escape/secret.txt"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "SecurityError", errObs.Extras.ErrorID)
+	})
+
+	t.Run("write through escaping symlink is rejected", func(t *testing.T) {
+		obs, err := executor.executeFileWrite(ctx, models.FileWriteAction{Path: "escape/new.txt", Contents: "data"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "SecurityError", errObs.Extras.ErrorID)
+		assert.NoFileExists(t, filepath.Join(outsideDir, "new.txt"))
+	})
+
+	t.Run("edit through dangling escaping symlink is rejected", func(t *testing.T) {
+		require.NoError(t, os.Symlink(filepath.Join(outsideDir, "missing.txt"), filepath.Join(executor.workingDir, "dangling.txt")))
+
+		obs, err := executor.executeFileEdit(ctx, models.FileEditAction{Path: "dangling.txt", Command: "create", FileText: "data"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "SecurityError", errObs.Extras.ErrorID)
+		assert.NoFileExists(t, filepath.Join(outsideDir, "missing.txt"))
+	})
+
+	t.Run("new file in new directory is allowed", func(t *testing.T) {
+		obs, err := executor.executeFileWrite(ctx, models.FileWriteAction{Path: "nested/dir/new.txt", Contents: "data"})
+		require.NoError(t, err)
+
+		_, ok := obs.(models.Observation[models.FileWriteExtras])
+		require.True(t, ok, "expected FileWriteObservation, got %T", obs)
+		content, err := os.ReadFile(filepath.Join(executor.workingDir, "nested/dir/new.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(content))
+	})
+
+	t.Run("escaping symlink is allowed with allow_absolute_paths", func(t *testing.T) {
+		reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.AllowAbsolutePaths = true })
+		defer reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.AllowAbsolutePaths = false })
+
+		obs, err := executor.executeFileRead(ctx, models.FileReadAction{Path: "escape/secret.txt"})
+		require.NoError(t, err)
+
+		readObs, ok := obs.(models.Observation[models.FileReadExtras])
+		require.True(t, ok, "expected FileReadObservation, got %T", obs)
+		assert.Equal(t, "secret", readObs.Content)
+	})
+}
+
+func TestExecuteFileRead_FileSizeMetric(t *testing.T) {
+	executor := newTestExecutor(t)
+	reader := sdkmetric.NewManualReader()
+	metrics, err := newExecutorMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+	require.NoError(t, err)
+	executor.metrics = metrics
+	ctx := context.Background()
+
+	// 2000 bytes falls in the (1KiB, 16KiB] bucket
+	content := strings.Repeat("a", 1999) + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "sized.txt"), []byte(content), 0644))
+
+	_, err = executor.executeFileRead(ctx, models.FileReadAction{Path: "sized.txt"})
+	require.NoError(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	var histogram metricdata.Histogram[int64]
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name == "runtime.file.size" {
+			histogram = m.Data.(metricdata.Histogram[int64])
+		}
+	}
+	require.Len(t, histogram.DataPoints, 1)
+
+	point := histogram.DataPoints[0]
+	operation, _ := point.Attributes.Value("operation")
+	assert.Equal(t, "read", operation.AsString())
+	assert.Equal(t, uint64(1), point.Count)
+	assert.Equal(t, int64(2000), point.Sum)
+	assert.Equal(t, uint64(1), point.BucketCounts[1])
+}
+
+func TestExecuteAction_FileOperationMetric(t *testing.T) {
+	executor := newTestExecutor(t)
+	reader := sdkmetric.NewManualReader()
+	metrics, err := newExecutorMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+	require.NoError(t, err)
+	executor.metrics = metrics
+	ctx := context.Background()
+
+	for _, action := range []map[string]interface{}{
+		{"action": "write", "args": map[string]interface{}{"path": "counted.txt", "content": "hello"}},
+		{"action": "read", "args": map[string]interface{}{"path": "counted.txt"}},
+		{"action": "read", "args": map[string]interface{}{"path": "missing.txt"}},
+		{"action": "run", "args": map[string]interface{}{"command": "true"}},
+	} {
+		_, err := executor.ExecuteAction(ctx, action)
+		require.NoError(t, err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	counts := map[string]int64{}
+	var commandDurations metricdata.Histogram[float64]
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		switch m.Name {
+		case "runtime.file.operations":
+			for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				operation, _ := point.Attributes.Value("operation")
+				success, _ := point.Attributes.Value("success")
+				counts[fmt.Sprintf("%s/%t", operation.AsString(), success.AsBool())] = point.Value
+			}
+		case "runtime.command.duration":
+			commandDurations = m.Data.(metricdata.Histogram[float64])
+		}
+	}
+
+	// The command is not a file operation, but its duration is recorded
+	assert.Equal(t, map[string]int64{"write/true": 1, "read/true": 1, "read/false": 1}, counts)
+	require.Len(t, commandDurations.DataPoints, 1)
+	assert.Equal(t, uint64(1), commandDurations.DataPoints[0].Count)
+}
+
+func TestExecuteFilePatch(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	writeJSON := func(t *testing.T, name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, name), []byte(content), 0644))
+	}
+	readJSON := func(t *testing.T, name string) map[string]interface{} {
+		data, err := os.ReadFile(filepath.Join(executor.workingDir, name))
+		require.NoError(t, err)
+		var doc map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &doc))
+		return doc
+	}
+	patchAction := func(name, patch string) models.FilePatchAction {
+		return models.FilePatchAction{Path: name, Patch: json.RawMessage(patch)}
+	}
+
+	t.Run("add", func(t *testing.T) {
+		writeJSON(t, "add.json", `{"name": "app"}`+"\n")
+
+		obs, err := executor.executeFilePatch(ctx, patchAction("add.json", `[{"op": "add", "path": "/port", "value": 8080}]`))
+		require.NoError(t, err)
+
+		editObs, ok := obs.(models.Observation[models.FileEditExtras])
+		require.True(t, ok, "expected FileEditObservation, got %T", obs)
+		assert.Contains(t, editObs.Content, `+  "port": 8080`)
+		assert.Equal(t, map[string]interface{}{"name": "app", "port": float64(8080)}, readJSON(t, "add.json"))
+		assert.True(t, strings.HasSuffix(editObs.Extras.NewContent, "}\n"), "trailing newline should be preserved")
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		writeJSON(t, "replace.json", `{"name": "app", "debug": false}`)
+
+		obs, err := executor.executeFilePatch(ctx, patchAction("replace.json", `[{"op": "replace", "path": "/debug", "value": true}]`))
+		require.NoError(t, err)
+
+		_, ok := obs.(models.Observation[models.FileEditExtras])
+		require.True(t, ok, "expected FileEditObservation, got %T", obs)
+		assert.Equal(t, map[string]interface{}{"name": "app", "debug": true}, readJSON(t, "replace.json"))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		writeJSON(t, "remove.json", `{"name": "app", "legacy": {"enabled": true}}`)
+
+		obs, err := executor.executeFilePatch(ctx, patchAction("remove.json", `[{"op": "remove", "path": "/legacy"}]`))
+		require.NoError(t, err)
+
+		_, ok := obs.(models.Observation[models.FileEditExtras])
+		require.True(t, ok, "expected FileEditObservation, got %T", obs)
+		assert.Equal(t, map[string]interface{}{"name": "app"}, readJSON(t, "remove.json"))
+	})
+
+	t.Run("non-JSON file is rejected", func(t *testing.T) {
+		writeJSON(t, "config.yaml", "name: app\n")
+
+		obs, err := executor.executeFilePatch(ctx, patchAction("config.yaml", `[{"op": "add", "path": "/port", "value": 8080}]`))
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "FilePatchError", errObs.Extras.ErrorID)
+	})
+
+	t.Run("invalid patch leaves the file untouched", func(t *testing.T) {
+		original := `{"name": "app"}`
+		writeJSON(t, "invalid.json", original)
+
+		obs, err := executor.executeFilePatch(ctx, patchAction("invalid.json", `[{"op": "remove", "path": "/missing"}]`))
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "InvalidPatch", errObs.Extras.ErrorID)
+		data, err := os.ReadFile(filepath.Join(executor.workingDir, "invalid.json"))
+		require.NoError(t, err)
+		assert.Equal(t, original, string(data))
+	})
+}
+
+func TestExecutePatch(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	write := func(t *testing.T, name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, name), []byte(content), 0644))
+	}
+	read := func(t *testing.T, name string) string {
+		data, err := os.ReadFile(filepath.Join(executor.workingDir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("clean apply", func(t *testing.T) {
+		write(t, "greeting.txt", "hello\nworld\n")
+		patch := `diff --git a/greeting.txt b/greeting.txt
+--- a/greeting.txt
++++ b/greeting.txt
+@@ -1,2 +1,2 @@
+ hello
+-world
++there
+diff --git a/added.txt b/added.txt
+new file mode 100644
+--- /dev/null
++++ b/added.txt
+@@ -0,0 +1 @@
++new
+`
+
+		obs, err := executor.executePatch(ctx, models.PatchAction{Patch: patch})
+		require.NoError(t, err)
+
+		patchObs, ok := obs.(models.Observation[models.PatchExtras])
+		require.True(t, ok, "expected PatchObservation, got %T", obs)
+		assert.Equal(t, []string{"greeting.txt", "added.txt"}, patchObs.Extras.ChangedFiles)
+		assert.Empty(t, patchObs.Extras.RejectedHunks)
+		assert.Equal(t, "hello\nthere\n", read(t, "greeting.txt"))
+		assert.Equal(t, "new\n", read(t, "added.txt"))
+	})
+
+	t.Run("conflicting hunk", func(t *testing.T) {
+		write(t, "letters.txt", "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n")
+		// Without a/ and b/ prefixes; the second hunk expects a line the file doesn't have
+		patch := `--- letters.txt
++++ letters.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+@@ -8,3 +8,3 @@
+ h
+-x
++I
+ j
+`
+
+		obs, err := executor.executePatch(ctx, models.PatchAction{Patch: patch})
+		require.NoError(t, err)
+
+		patchObs, ok := obs.(models.Observation[models.PatchExtras])
+		require.True(t, ok, "expected PatchObservation, got %T", obs)
+		assert.Equal(t, []string{"letters.txt"}, patchObs.Extras.ChangedFiles)
+		require.Len(t, patchObs.Extras.RejectedHunks, 1)
+		assert.Equal(t, "letters.txt", patchObs.Extras.RejectedHunks[0].Path)
+		assert.Contains(t, patchObs.Extras.RejectedHunks[0].Hunks, "-x\n+I\n")
+		assert.Contains(t, patchObs.Content, "Rejected hunks of letters.txt")
+
+		// The first hunk still applies, and no .rej file is left behind
+		assert.Equal(t, "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\n", read(t, "letters.txt"))
+		assert.NoFileExists(t, filepath.Join(executor.workingDir, "letters.txt.rej"))
+	})
+
+	t.Run("invalid patch", func(t *testing.T) {
+		obs, err := executor.executePatch(ctx, models.PatchAction{Patch: "--- a/x\n+++ b/x\n@@ -1,3 +1,3 @@\n a\n-b\n"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "InvalidPatch", errObs.Extras.ErrorID)
+		assert.Contains(t, errObs.Content, "corrupt patch")
+	})
+
+	t.Run("path outside the directory", func(t *testing.T) {
+		obs, err := executor.executePatch(ctx, models.PatchAction{Patch: "--- a/../escape.txt\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n"})
+		require.NoError(t, err)
+
+		_, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.NoFileExists(t, filepath.Join(filepath.Dir(executor.workingDir), "escape.txt"))
+	})
+
+	t.Run("subdirectory of a repository", func(t *testing.T) {
+		require.NoError(t, exec.Command("git", "init", "-q", executor.workingDir).Run())
+		require.NoError(t, os.Mkdir(filepath.Join(executor.workingDir, "sub"), 0755))
+		write(t, "sub/a.txt", "x\n")
+		// Paths in a git diff would otherwise be taken relative to the root of the repository,
+		// and the file skipped as outside the subdirectory
+		patch := "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-x\n+y\n"
+
+		obs, err := executor.executePatch(ctx, models.PatchAction{Patch: patch, Path: "sub"})
+		require.NoError(t, err)
+
+		patchObs, ok := obs.(models.Observation[models.PatchExtras])
+		require.True(t, ok, "expected PatchObservation, got %T", obs)
+		assert.Equal(t, []string{"a.txt"}, patchObs.Extras.ChangedFiles)
+		assert.Equal(t, "y\n", read(t, "sub/a.txt"))
+	})
+}
+
+func TestExecutePatch_BackupOnEdit(t *testing.T) {
+	backupDir := t.TempDir()
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.BackupOnEdit = true
+		cfg.Server.BackupDir = backupDir
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "greeting.txt"), []byte("hello\n"), 0644))
+
+	patch := "--- a/greeting.txt\n+++ b/greeting.txt\n@@ -1 +1 @@\n-hello\n+bye\n--- /dev/null\n+++ b/added.txt\n@@ -0,0 +1 @@\n+new\n"
+	obs, err := executor.executePatch(context.Background(), models.PatchAction{Patch: patch})
+	require.NoError(t, err)
+	_, ok := obs.(models.Observation[models.PatchExtras])
+	require.True(t, ok, "expected PatchObservation, got %T", obs)
+
+	backup, err := executor.latestBackup(filepath.Join(executor.workingDir, "greeting.txt"))
+	require.NoError(t, err)
+	content, err := os.ReadFile(backup)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(content))
+
+	backup, err = executor.latestBackup(filepath.Join(executor.workingDir, "added.txt"))
+	require.NoError(t, err)
+	assert.Empty(t, backup, "new files have nothing to back up")
+}
+
+func TestExecuteFileEdit_YAMLSet(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	original := `# Service configuration
+server:
+  host: localhost
+  port: 8000 # default port
+logging:
+  level: info
+`
+	yamlSet := func(t *testing.T, name, keyPath string, value interface{}) interface{} {
+		obs, err := executor.executeFileEdit(ctx, models.FileEditAction{Path: name, Command: "yaml_set", KeyPath: keyPath, Value: value})
+		require.NoError(t, err)
+		return obs
+	}
+
+	t.Run("nested key", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "nested.yaml"), []byte(original), 0644))
+
+		obs := yamlSet(t, "nested.yaml", "server.port", float64(9000))
+
+		editObs, ok := obs.(models.Observation[models.FileEditExtras])
+		require.True(t, ok, "expected FileEditObservation, got %T", obs)
+		assert.Contains(t, editObs.Content, "+  port: 9000 # default port")
+		assert.Equal(t, strings.Replace(original, "8000", "9000", 1), editObs.Extras.NewContent)
+	})
+
+	t.Run("new key", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "new.yaml"), []byte(original), 0644))
+
+		obs := yamlSet(t, "new.yaml", "server.tls.enabled", true)
+
+		_, ok := obs.(models.Observation[models.FileEditExtras])
+		require.True(t, ok, "expected FileEditObservation, got %T", obs)
+		content, err := os.ReadFile(filepath.Join(executor.workingDir, "new.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, `# Service configuration
+server:
+  host: localhost
+  port: 8000 # default port
+  tls:
+    enabled: true
+logging:
+  level: info
+`, string(content))
+	})
+
+	t.Run("invalid YAML is rejected", func(t *testing.T) {
+		invalid := "server:\n  port: [8000\n"
+		require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "invalid.yaml"), []byte(invalid), 0644))
+
+		obs := yamlSet(t, "invalid.yaml", "server.port", float64(9000))
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "InvalidYAML", errObs.Extras.ErrorID)
+		content, err := os.ReadFile(filepath.Join(executor.workingDir, "invalid.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, invalid, string(content))
+	})
+}
+
+func TestExecuteAction_ListFiles(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	require.NoError(t, os.Mkdir(filepath.Join(executor.workingDir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "src", "main.go"), []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "README.md"), []byte("# app"), 0644))
+
+	obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+		"action": "list_files",
+		"args":   map[string]interface{}{"path": ".", "recursive": true},
+	})
+	require.NoError(t, err)
+
+	listObs, ok := obs.(models.Observation[models.ListFilesExtras])
+	require.True(t, ok, "expected ListFilesObservation, got %T", obs)
+	assert.Equal(t, "list_files", listObs.Observation)
+	files := make(map[string]models.FileInfo)
+	for _, file := range listObs.Extras.Files {
+		files[file.Path] = file
+	}
+	assert.Equal(t, models.FileInfo{Path: "README.md", Size: 5}, files["README.md"])
+	assert.True(t, files["src"].IsDir)
+	assert.Equal(t, models.FileInfo{Path: filepath.Join("src", "main.go"), Size: 13}, files[filepath.Join("src", "main.go")])
+	assert.Contains(t, listObs.Content, "src/\n")
+	assert.Contains(t, listObs.Content, "README.md (5 bytes)\n")
+
+	t.Run("missing directory", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+			"action": "list_files",
+			"args":   map[string]interface{}{"path": "missing"},
+		})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "FileListError", errObs.Extras.ErrorID)
+	})
+}
+
+func TestExecuteSearchFiles(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(executor.workingDir, "pkg", "api"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "pkg", "api", "handler.go"), []byte("package api\n\nfunc Handle() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "pkg", "api", "handler_test.go"), []byte("package api\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "main.go"), []byte("package main\n\nfunc main() { api.Handle() }\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "blob.bin"), []byte("Handle\x00\x01\x02"), 0644))
+
+	search := func(t *testing.T, args map[string]interface{}) models.Observation[models.SearchExtras] {
+		args["action"] = "search"
+		obs, err := executor.ExecuteAction(ctx, args)
+		require.NoError(t, err)
+		searchObs, ok := obs.(models.Observation[models.SearchExtras])
+		require.True(t, ok, "expected SearchObservation, got %T", obs)
+		return searchObs
+	}
+
+	t.Run("file names", func(t *testing.T) {
+		obs := search(t, map[string]interface{}{"query": "*_test.go"})
+
+		assert.Equal(t, []models.SearchMatch{{Path: filepath.Join("pkg", "api", "handler_test.go")}}, obs.Extras.Matches)
+		assert.False(t, obs.Extras.Truncated)
+	})
+
+	t.Run("content", func(t *testing.T) {
+		obs := search(t, map[string]interface{}{"query": "Handle()", "content": true})
+
+		assert.ElementsMatch(t, []models.SearchMatch{
+			{Path: filepath.Join("pkg", "api", "handler.go"), Line: 3, Text: "func Handle() {}"},
+			{Path: "main.go", Line: 3, Text: "func main() { api.Handle() }"},
+		}, obs.Extras.Matches)
+		assert.Contains(t, obs.Content, "main.go:3: func main() { api.Handle() }")
+	})
+
+	t.Run("max results", func(t *testing.T) {
+		obs := search(t, map[string]interface{}{"query": "package", "content": true, "max_results": 1})
+
+		assert.Len(t, obs.Extras.Matches, 1)
+		assert.True(t, obs.Extras.Truncated)
+	})
+}
+
+func TestExecuteGrep(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	write := func(name, content string) {
+		path := filepath.Join(executor.workingDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write("server.go", "package main\n\n// Start runs the server\nfunc Start() error {\n\treturn nil\n}\n")
+	write("client/client.go", "package client\n\nfunc Connect() error {\n\treturn nil\n}\n")
+	write("client/client_test.go", "package client\n\nfunc TestConnect(t *testing.T) {}\n")
+	write("build/gen.go", "package build\n\nfunc Generate() error { return nil }\n")
+	write(".gitignore", "build/\n")
+
+	grep := func(t *testing.T, args map[string]interface{}) models.Observation[models.GrepExtras] {
+		args["action"] = "grep"
+		obs, err := executor.ExecuteAction(ctx, args)
+		require.NoError(t, err)
+		grepObs, ok := obs.(models.Observation[models.GrepExtras])
+		require.True(t, ok, "expected GrepObservation, got %T", obs)
+		return grepObs
+	}
+
+	t.Run("regex with context", func(t *testing.T) {
+		obs := grep(t, map[string]interface{}{"pattern": `^func \w+\(\) error`, "context_lines": 1})
+
+		assert.Equal(t, []models.GrepMatch{
+			{Path: filepath.Join("build", "gen.go"), Line: 3, Text: "func Generate() error { return nil }", Before: []string{""}},
+			{Path: filepath.Join("client", "client.go"), Line: 3, Text: "func Connect() error {", Before: []string{""}, After: []string{"\treturn nil"}},
+			{Path: "server.go", Line: 4, Text: "func Start() error {", Before: []string{"// Start runs the server"}, After: []string{"\treturn nil"}},
+		}, obs.Extras.Matches)
+		assert.Contains(t, obs.Content, "server.go-3-// Start runs the server\nserver.go:4:func Start() error {\nserver.go-5-\treturn nil\n")
+	})
+
+	t.Run("ignore case and filters", func(t *testing.T) {
+		obs := grep(t, map[string]interface{}{
+			"pattern":     "connect",
+			"ignore_case": true,
+			"include":     []interface{}{"*.go"},
+			"exclude":     []interface{}{"*_test.go"},
+		})
+
+		require.Len(t, obs.Extras.Matches, 1)
+		assert.Equal(t, filepath.Join("client", "client.go"), obs.Extras.Matches[0].Path)
+	})
+
+	t.Run("respects gitignore", func(t *testing.T) {
+		obs := grep(t, map[string]interface{}{"pattern": "^package", "respect_gitignore": true})
+
+		for _, m := range obs.Extras.Matches {
+			assert.NotEqual(t, filepath.Join("build", "gen.go"), m.Path)
+		}
+		assert.Len(t, obs.Extras.Matches, 3)
+	})
+
+	t.Run("stops at max results", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			write(filepath.Join("many", fmt.Sprintf("file%02d.txt", i)), "needle\nneedle\nhay\n")
+		}
+
+		obs := grep(t, map[string]interface{}{"pattern": "needle", "path": "many", "max_results": 5})
+
+		assert.Len(t, obs.Extras.Matches, 5)
+		assert.True(t, obs.Extras.Truncated)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{"action": "grep", "pattern": "("})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "GrepError", errObs.Extras.ErrorID)
+	})
+}
+
+func TestObservationPath(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	absPath := filepath.Join(executor.workingDir, "docs", "notes.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(absPath), 0755))
+	require.NoError(t, os.WriteFile(absPath, []byte("hello\n"), 0644))
+
+	t.Run("absolute read inside workspace", func(t *testing.T) {
+		obs, err := executor.executeFileRead(ctx, models.FileReadAction{Path: absPath})
+		require.NoError(t, err)
+
+		readObs, ok := obs.(models.Observation[models.FileReadExtras])
+		require.True(t, ok, "expected FileReadObservation, got %T", obs)
+		assert.Equal(t, filepath.Join("docs", "notes.txt"), readObs.Extras.Path)
+	})
+
+	t.Run("relative write", func(t *testing.T) {
+		obs, err := executor.executeFileWrite(ctx, models.FileWriteAction{Path: "./docs/new.txt", Contents: "new"})
+		require.NoError(t, err)
+
+		writeObs, ok := obs.(models.Observation[models.FileWriteExtras])
+		require.True(t, ok, "expected FileWriteObservation, got %T", obs)
+		assert.Equal(t, filepath.Join("docs", "new.txt"), writeObs.Extras.Path)
+	})
+
+	t.Run("outside workspace stays absolute", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "outside.txt")
+		assert.Equal(t, outside, executor.observationPath(outside))
+	})
+}
+
+func TestExecuteFileEdit_CRLF(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	path := filepath.Join(executor.workingDir, "script.bat")
+	readFile := func(t *testing.T) string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("str_replace", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("@echo off\r\necho one\r\necho two\r\n"), 0644))
+
+		obs, err := executor.executeFileEdit(ctx, models.FileEditAction{
+			Path:    "script.bat",
+			Command: "str_replace",
+			OldStr:  "echo one\necho two",
+			NewStr:  "echo 1\necho 2",
+		})
+		require.NoError(t, err)
+
+		editObs, ok := obs.(models.Observation[models.FileEditExtras])
+		require.True(t, ok, "expected FileEditObservation, got %T", obs)
+		assert.Equal(t, "@echo off\r\necho 1\r\necho 2\r\n", readFile(t))
+		assert.NotContains(t, editObs.Content, "\r")
+	})
+
+	t.Run("insert", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("@echo off\r\necho two\r\n"), 0644))
+		insertLine := 1
+
+		_, err := executor.executeFileEdit(ctx, models.FileEditAction{
+			Path:       "script.bat",
+			Command:    "insert",
+			InsertLine: &insertLine,
+			NewStr:     "echo one",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "@echo off\r\necho one\r\necho two\r\n", readFile(t))
+	})
+}
+
+func TestExecuteFileEdit_PreservesTrailingNewline(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		original string
+		oldStr   string
+		newStr   string
+		expected string
+	}{
+		{name: "with trailing newline", original: "a\nb\n", oldStr: "b\n", newStr: "c", expected: "a\nc\n"},
+		{name: "without trailing newline", original: "a\nb", oldStr: "b", newStr: "c\n", expected: "a\nc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(executor.workingDir, "edit.txt")
+			require.NoError(t, os.WriteFile(path, []byte(tt.original), 0644))
+
+			obs, err := executor.executeFileEdit(ctx, models.FileEditAction{
+				Path:    "edit.txt",
+				Command: "str_replace",
+				OldStr:  tt.oldStr,
+				NewStr:  tt.newStr,
+			})
+			require.NoError(t, err)
+			_, ok := obs.(models.Observation[models.FileEditExtras])
+			require.True(t, ok, "expected FileEditObservation, got %T", obs)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+
+		t.Run(tt.name+" write", func(t *testing.T) {
+			path := filepath.Join(executor.workingDir, "write.txt")
+			require.NoError(t, os.WriteFile(path, []byte(tt.original), 0644))
+
+			_, err := executor.executeFileWrite(ctx, models.FileWriteAction{Path: "write.txt", Contents: "a\nc\n"})
+			require.NoError(t, err)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestFileMode(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	t.Run("create", func(t *testing.T) {
+		obs, err := executor.executeFileEdit(ctx, models.FileEditAction{
+			Path:     "run.sh",
+			Command:  "create",
+			FileText: "#!/bin/sh\necho hi\n",
+			Mode:     "0700",
+		})
+		require.NoError(t, err)
+		_, ok := obs.(models.Observation[models.FileWriteExtras])
+		require.True(t, ok, "expected FileWriteObservation, got %T", obs)
+
+		info, err := os.Stat(filepath.Join(executor.workingDir, "run.sh"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	})
+
+	t.Run("write defaults to 0644", func(t *testing.T) {
+		_, err := executor.executeFileWrite(ctx, models.FileWriteAction{Path: "plain.txt", Contents: "text"})
+		require.NoError(t, err)
+
+		info, err := os.Stat(filepath.Join(executor.workingDir, "plain.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	})
+
+	t.Run("masked by the umask", func(t *testing.T) {
+		// A file created directly shows the mode the umask leaves
+		reference := filepath.Join(t.TempDir(), "reference")
+		f, err := os.OpenFile(reference, os.O_CREATE|os.O_WRONLY, 0777)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		want, err := os.Stat(reference)
+		require.NoError(t, err)
+
+		_, err = executor.executeFileWrite(ctx, models.FileWriteAction{Path: "open.sh", Contents: "#!/bin/sh\n", Mode: "0777"})
+		require.NoError(t, err)
+
+		info, err := os.Stat(filepath.Join(executor.workingDir, "open.sh"))
+		require.NoError(t, err)
+		assert.Equal(t, want.Mode().Perm(), info.Mode().Perm())
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		obs, err := executor.executeFileWrite(ctx, models.FileWriteAction{Path: "bad.txt", Contents: "text", Mode: "0999"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "InvalidFileMode", errObs.Extras.ErrorID)
+	})
+}
+
+func TestExecuteFileEdit_ConcurrentStringReplace(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+	path := filepath.Join(executor.workingDir, "shared.txt")
+
+	// Each edit replaces its own line, so a lost update shows as an unreplaced line
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+
+	var wg sync.WaitGroup
+	for i := range lines {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			obs, err := executor.executeFileEdit(ctx, models.FileEditAction{
+				Path:    "shared.txt",
+				Command: "str_replace",
+				OldStr:  fmt.Sprintf("line %d\n", i),
+				NewStr:  fmt.Sprintf("edited %d\n", i),
+			})
+			assert.NoError(t, err)
+			_, ok := obs.(models.Observation[models.FileEditExtras])
+			assert.True(t, ok, "expected FileEditObservation, got %T", obs)
+		}(i)
+	}
+	wg.Wait()
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for i := range lines {
+		assert.Contains(t, string(content), fmt.Sprintf("edited %d\n", i))
+		assert.NotContains(t, string(content), fmt.Sprintf("line %d\n", i))
+	}
+}
+
+func TestExecuteFileWrite_Atomic(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+	path := filepath.Join(executor.workingDir, "script.sh")
+
+	t.Run("keeps mode", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0755))
+
+		_, err := executor.executeFileWrite(ctx, models.FileWriteAction{Path: "script.sh", Contents: "#!/bin/sh\necho hi\n"})
+		require.NoError(t, err)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\necho hi\n", string(content))
+	})
+
+	t.Run("no partial content", func(t *testing.T) {
+		// Readers racing the writes must only ever see one of the complete contents
+		contents := []string{strings.Repeat("a", 1<<20), strings.Repeat("b", 1<<20)}
+		require.NoError(t, os.WriteFile(path, []byte(contents[0]), 0644))
+
+		done := make(chan struct{})
+		partial := make(chan int, 1)
+		go func() {
+			defer close(partial)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				data, err := os.ReadFile(path)
+				if err == nil && string(data) != contents[0] && string(data) != contents[1] {
+					partial <- len(data)
+					return
+				}
+			}
+		}()
+
+		for i := 0; i < 20; i++ {
+			_, err := executor.executeFileWrite(ctx, models.FileWriteAction{Path: "script.sh", Contents: contents[i%2]})
+			require.NoError(t, err)
+		}
+		close(done)
+		if n, ok := <-partial; ok {
+			t.Fatalf("read %d bytes of partial content", n)
+		}
+	})
+
+	t.Run("no temporary files left", func(t *testing.T) {
+		entries, err := os.ReadDir(executor.workingDir)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			assert.NotContains(t, entry.Name(), ".tmp-")
+		}
+	})
+}
+
+func TestBackupOnEdit(t *testing.T) {
+	backupDir := t.TempDir()
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.BackupOnEdit = true
+		cfg.Server.BackupDir = backupDir
+		cfg.Server.MaxBackupsPerFile = 2
+	})
+	ctx := context.Background()
+	path := filepath.Join(executor.workingDir, "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0644))
+
+	backups := func() []string {
+		matches, err := filepath.Glob(filepath.Join(backupDir, "notes.txt.*.bak"))
+		require.NoError(t, err)
+		return matches
+	}
+
+	t.Run("defaults outside the workspace", func(t *testing.T) {
+		fresh := newTestExecutor(t)
+		assert.False(t, isWithinDir(fresh.workingDir, fresh.backupDir()), fresh.backupDir())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+			cfg.Server.BackupDir = filepath.Join(t.TempDir(), "backups")
+		})
+		_, err := disabled.executeFileWrite(ctx, models.FileWriteAction{Path: "notes.txt", Contents: "first\n"})
+		require.NoError(t, err)
+		assert.NoDirExists(t, disabled.backupDir())
+
+		obs, err := disabled.executeFileEdit(ctx, models.FileEditAction{Path: "notes.txt", Command: "undo_edit"})
+		require.NoError(t, err)
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "UnsupportedEditCommand", errObs.Extras.ErrorID)
+	})
+
+	t.Run("backs up before editing", func(t *testing.T) {
+		_, err := executor.executeFileEdit(ctx, models.FileEditAction{Path: "notes.txt", Command: "str_replace", OldStr: "first", NewStr: "second"})
+		require.NoError(t, err)
+		insertLine := 1
+		_, err = executor.executeFileEdit(ctx, models.FileEditAction{Path: "notes.txt", Command: "insert", InsertLine: &insertLine, NewStr: "third"})
+		require.NoError(t, err)
+
+		matches := backups()
+		require.Len(t, matches, 2)
+		content, err := os.ReadFile(matches[0])
+		require.NoError(t, err)
+		assert.Equal(t, "first\n", string(content))
+		content, err = os.ReadFile(matches[1])
+		require.NoError(t, err)
+		assert.Equal(t, "second\n", string(content))
+	})
+
+	t.Run("undo restores backups in turn", func(t *testing.T) {
+		for _, want := range []string{"second\n", "first\n"} {
+			obs, err := executor.executeFileEdit(ctx, models.FileEditAction{Path: "notes.txt", Command: "undo_edit"})
+			require.NoError(t, err)
+			editObs, ok := obs.(models.Observation[models.FileEditExtras])
+			require.True(t, ok, "expected FileEditObservation, got %T", obs)
+			assert.Equal(t, want, editObs.Extras.NewContent)
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, want, string(content))
+		}
+
+		obs, err := executor.executeFileEdit(ctx, models.FileEditAction{Path: "notes.txt", Command: "undo_edit"})
+		require.NoError(t, err)
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "FileEditError", errObs.Extras.ErrorID)
+	})
+
+	t.Run("oldest backups are pruned", func(t *testing.T) {
+		for _, content := range []string{"a\n", "b\n", "c\n", "d\n"} {
+			_, err := executor.executeFileWrite(ctx, models.FileWriteAction{Path: "notes.txt", Contents: content})
+			require.NoError(t, err)
+		}
+
+		matches := backups()
+		require.Len(t, matches, 2)
+		content, err := os.ReadFile(matches[0])
+		require.NoError(t, err)
+		assert.Equal(t, "b\n", string(content))
+	})
+}
+
+func TestListFiles_MaxDepth(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	deep := filepath.Join(executor.workingDir, "node_modules", "a", "node_modules", "b")
+	require.NoError(t, os.MkdirAll(deep, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(deep, "index.js"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "package.json"), []byte("{}"), 0644))
+
+	paths := func(files []models.FileInfo) []string {
+		var result []string
+		for _, file := range files {
+			result = append(result, filepath.ToSlash(file.Path))
+		}
+		return result
+	}
+
+	t.Run("depth limit", func(t *testing.T) {
+		files, truncated, err := executor.ListFiles(ctx, "", ListOptions{Recursive: true, MaxDepth: 2})
+		require.NoError(t, err)
+		assert.False(t, truncated)
+		assert.ElementsMatch(t, []string{"node_modules", "node_modules/a", "package.json"}, paths(files))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		files, _, err := executor.ListFiles(ctx, "", ListOptions{Recursive: true})
+		require.NoError(t, err)
+		assert.Contains(t, paths(files), "node_modules/a/node_modules/b/index.js")
+	})
+
+	t.Run("result cap", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+			"action": "list_files",
+			"args":   map[string]interface{}{"recursive": true, "max_results": 2},
+		})
+		require.NoError(t, err)
+
+		listObs, ok := obs.(models.Observation[models.ListFilesExtras])
+		require.True(t, ok, "expected ListFilesObservation, got %T", obs)
+		assert.Len(t, listObs.Extras.Files, 2)
+		assert.True(t, listObs.Extras.Truncated)
+	})
+}
+
+func TestListFiles_RespectGitignore(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	write := func(name, content string) {
+		path := filepath.Join(executor.workingDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write(".gitignore", "node_modules/\n*.log\n")
+	write("node_modules/lib/index.js", "x")
+	write("app/debug.log", "x")
+	write("app/main.js", "x")
+	write("app/.gitignore", "!keep.log\n")
+	write("app/keep.log", "x")
+	write(".git/HEAD", "ref: refs/heads/main")
+
+	paths := func(files []models.FileInfo) []string {
+		var result []string
+		for _, file := range files {
+			result = append(result, filepath.ToSlash(file.Path))
+		}
+		return result
+	}
+
+	t.Run("recursive", func(t *testing.T) {
+		files, _, err := executor.ListFiles(ctx, "", ListOptions{Recursive: true, RespectGitignore: true})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{".gitignore", "app", "app/.gitignore", "app/main.js", "app/keep.log"}, paths(files))
+	})
+
+	t.Run("non-recursive", func(t *testing.T) {
+		files, _, err := executor.ListFiles(ctx, "", ListOptions{RespectGitignore: true})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{".gitignore", "app"}, paths(files))
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		files, _, err := executor.ListFiles(ctx, "", ListOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, paths(files), "node_modules")
+	})
+
+	t.Run("search", func(t *testing.T) {
+		obs, err := executor.ExecuteAction(ctx, map[string]interface{}{
+			"action": "search",
+			"args":   map[string]interface{}{"query": "*.js", "respect_gitignore": true},
+		})
+		require.NoError(t, err)
+
+		searchObs, ok := obs.(models.Observation[models.SearchExtras])
+		require.True(t, ok, "expected SearchObservation, got %T", obs)
+		assert.Equal(t, []models.SearchMatch{{Path: filepath.Join("app", "main.js")}}, searchObs.Extras.Matches)
+	})
+}
+
+func TestStreamZipArchiveMultiple_Symlinks(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	dir := filepath.Join(executor.workingDir, "project")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "target.txt"), []byte("content"), 0644))
+	require.NoError(t, os.Symlink("target.txt", filepath.Join(dir, "link.txt")))
+
+	var archive bytes.Buffer
+	require.NoError(t, executor.StreamZipArchiveMultiple(ctx, []string{dir}, &archive))
+
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	require.NoError(t, err)
+
+	// Extract the archive like unzip does, creating links for symlink entries
+	extracted := t.TempDir()
+	for _, file := range reader.File {
+		path := filepath.Join(extracted, filepath.FromSlash(file.Name))
+		if file.FileInfo().IsDir() {
+			require.NoError(t, os.MkdirAll(path, 0755))
+			continue
+		}
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		if file.Mode()&os.ModeSymlink != 0 {
+			require.NoError(t, os.Symlink(string(data), path))
+		} else {
+			require.NoError(t, os.WriteFile(path, data, file.Mode().Perm()))
+		}
+	}
+
+	link := filepath.Join(extracted, "project", "link.txt")
+	target, err := os.Readlink(link)
+	require.NoError(t, err, "link.txt should extract as a symlink")
+	assert.Equal(t, "target.txt", target)
+
+	data, err := os.ReadFile(link)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+}
+
+// writeZipTestTree creates a directory of files with varied sizes and content for archive tests
+func writeZipTestTree(t testing.TB, dir string, files, size int) map[string][]byte {
+	contents := make(map[string][]byte, files)
+	for i := 0; i < files; i++ {
+		name := filepath.Join(fmt.Sprintf("dir%02d", i%7), fmt.Sprintf("file%03d.txt", i))
+		data := bytes.Repeat([]byte(fmt.Sprintf("line %d of %s\n", i, name)), size/32+i)
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0644))
+		contents[filepath.ToSlash(filepath.Join(filepath.Base(dir), name))] = data
+	}
+	return contents
+}
+
+func TestStreamZipArchiveMultiple_ParallelCompression(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+
+	dir := filepath.Join(executor.workingDir, "project")
+	contents := writeZipTestTree(t, dir, 50, 4096)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.txt"), nil, 0644))
+	contents["project/empty.txt"] = []byte{}
+
+	// Workers are capped to GOMAXPROCS; raise it so the parallel path runs on small machines too
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	archiveNames := func(workers int) []string {
+		reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.ZipWorkers = workers })
+
+		var archive bytes.Buffer
+		require.NoError(t, executor.StreamZipArchiveMultiple(ctx, []string{dir}, &archive))
+
+		reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+		require.NoError(t, err)
+
+		var names []string
+		for _, file := range reader.File {
+			names = append(names, file.Name)
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := file.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(rc) // Fails on a CRC mismatch
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+			assert.Equal(t, contents[file.Name], data, "content of %s with %d workers", file.Name, workers)
+		}
+		return names
+	}
+
+	sequential := archiveNames(1)
+	parallel := archiveNames(4)
+	assert.Len(t, sequential, len(contents)+8) // Files, the root and the 7 subdirectories
+	assert.Equal(t, sequential, parallel, "entries should be written in the same order")
+}
+
+func BenchmarkStreamZipArchiveMultiple(b *testing.B) {
+	executor := newTestExecutor(b)
+	ctx := context.Background()
+
+	dir := filepath.Join(executor.workingDir, "project")
+	writeZipTestTree(b, dir, 64, 256<<10)
+
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			reloadConfig(b, executor, func(cfg *config.Config) { cfg.Server.ZipWorkers = workers })
+			for i := 0; i < b.N; i++ {
+				if err := executor.StreamZipArchiveMultiple(ctx, []string{dir}, io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
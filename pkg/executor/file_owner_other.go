@@ -0,0 +1,8 @@
+//go:build !unix
+
+package executor
+
+import "os"
+
+// copyOwnership is only supported on Unix, elsewhere files take the owner of the runtime process
+func copyOwnership(path string, info os.FileInfo) {}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package executor
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwnership gives path the owner and group of info. Only root can give a file away, so
+// failures are ignored and the file keeps the owner of the runtime process.
+func copyOwnership(path string, info os.FileInfo) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		_ = os.Chown(path, int(stat.Uid), int(stat.Gid))
+	}
+}
@@ -0,0 +1,153 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignoreRule is a single pattern line of a .gitignore file
+type gitignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool // Whether the pattern re-includes paths ("!pattern")
+	dirOnly bool // Whether the pattern only matches directories ("pattern/")
+}
+
+// gitignoreMatcher decides whether paths below a root are ignored by the .gitignore
+// files of the directories between the root and the path
+type gitignoreMatcher struct {
+	root  string
+	rules map[string][]gitignoreRule // Rules by slash-separated directory relative to root
+}
+
+// newGitignoreMatcher creates a matcher for the tree under root
+func newGitignoreMatcher(root string) *gitignoreMatcher {
+	return &gitignoreMatcher{root: root, rules: make(map[string][]gitignoreRule)}
+}
+
+// loadDir reads the .gitignore file of a directory under the root, if it has one.
+// Directories must be loaded before the paths below them are matched.
+func (m *gitignoreMatcher) loadDir(dir string) {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	rel, err := filepath.Rel(m.root, dir)
+	if err != nil {
+		return
+	}
+	m.rules[filepath.ToSlash(rel)] = parseGitignore(data)
+}
+
+// ignored reports whether a path under the root is ignored.
+// As in git, the last matching rule wins, and rules of deeper directories come last.
+func (m *gitignoreMatcher) ignored(p string, isDir bool) bool {
+	rel, err := filepath.Rel(m.root, p)
+	if err != nil || rel == "." {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	ignored := false
+	dir := "."
+	for {
+		relToDir := rel
+		if dir != "." {
+			relToDir = strings.TrimPrefix(rel, dir+"/")
+		}
+		for _, rule := range m.rules[dir] {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.pattern.MatchString(relToDir) {
+				ignored = !rule.negate
+			}
+		}
+
+		next, _, found := strings.Cut(relToDir, "/")
+		if !found {
+			return ignored
+		}
+		dir = path.Join(dir, next)
+	}
+}
+
+// parseGitignore parses the rules of a .gitignore file
+func parseGitignore(data []byte) []gitignoreRule {
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule gitignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		// Patterns without a slash match at any depth, others relative to the .gitignore's directory
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+
+		pattern, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+		rule.pattern = pattern
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// globToRegexp translates a gitignore glob into a regular expression.
+// "*" and "?" don't match slashes, while "**" matches any number of directories.
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return expr.String()
+}
@@ -0,0 +1,235 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+)
+
+// executeGrep searches the files under a directory for lines matching a regular expression
+func (e *Executor) executeGrep(ctx context.Context, action models.GrepAction) (interface{}, error) {
+	ctx, span := e.tracer.Start(ctx, "grep")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("pattern", action.Pattern),
+		attribute.String("path", action.Path),
+	)
+
+	if action.Pattern == "" {
+		return models.NewErrorObservation("Grep pattern must not be empty", "GrepError"), nil
+	}
+	expr := action.Pattern
+	if action.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Invalid grep pattern %q: %v", action.Pattern, err), "GrepError"), nil
+	}
+	if err := e.SecurityCheck(action.Path); err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Security error: %v", err), "SecurityError"), nil
+	}
+
+	maxResults := action.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultSearchMaxResults
+	}
+
+	// The walk stops once more than maxResults matches are found, so that a broad pattern doesn't
+	// collect the whole workspace
+	walkCtx, stopWalk := context.WithCancel(ctx)
+	defer stopWalk()
+
+	paths := make(chan string)
+	results := make(chan []models.GrepMatch)
+	var workers sync.WaitGroup
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for path := range paths {
+				if walkCtx.Err() != nil {
+					continue
+				}
+				if matches := e.grepFile(path, pattern, action.ContextLines, maxResults+1); len(matches) > 0 {
+					results <- matches
+				}
+			}
+		}()
+	}
+
+	walkErr := make(chan error, 1)
+	go func() {
+		defer close(paths)
+		walkErr <- e.walkGrepFiles(walkCtx, e.resolvePath(action.Path), action, paths)
+	}()
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var matches []models.GrepMatch
+	for fileMatches := range results {
+		if len(matches) > maxResults {
+			continue // Drained until the workers have stopped
+		}
+		matches = append(matches, fileMatches...)
+		if len(matches) > maxResults {
+			stopWalk()
+		}
+	}
+	truncated := len(matches) > maxResults
+	if err := <-walkErr; err != nil && !(truncated && ctx.Err() == nil && errors.Is(err, context.Canceled)) {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Cannot grep %s: %v", action.Path, err), "GrepError"), nil
+	}
+
+	// Files finish in any order, so sort for stable output
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Path != matches[j].Path {
+			return matches[i].Path < matches[j].Path
+		}
+		return matches[i].Line < matches[j].Line
+	})
+	if truncated {
+		matches = matches[:maxResults]
+	}
+	if matches == nil {
+		matches = []models.GrepMatch{}
+	}
+
+	return models.NewGrepObservation(formatGrepMatches(matches, truncated, maxResults), action.Pattern, e.observationPath(action.Path), matches, truncated), nil
+}
+
+// walkGrepFiles sends the regular files under root that pass the action's filters to paths
+func (e *Executor) walkGrepFiles(ctx context.Context, root string, action models.GrepAction, paths chan<- string) error {
+	var ignore *gitignoreMatcher
+	if action.RespectGitignore {
+		ignore = newGitignoreMatcher(root)
+	}
+
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != root && (entry.Name() == ".git" || (ignore != nil && ignore.ignored(path, true))) {
+				return filepath.SkipDir
+			}
+			if ignore != nil {
+				ignore.loadDir(path)
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || (ignore != nil && ignore.ignored(path, false)) {
+			return nil
+		}
+
+		relPath := e.toRelativePath(path)
+		if len(action.Include) > 0 && !matchesAnyGlob(action.Include, relPath) {
+			return nil
+		}
+		if matchesAnyGlob(action.Exclude, relPath) {
+			return nil
+		}
+
+		select {
+		case paths <- path:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// grepFile returns up to limit lines of a text file matching pattern with their context.
+// Binary and unreadable files never match.
+func (e *Executor) grepFile(path string, pattern *regexp.Regexp, contextLines, limit int) []models.GrepMatch {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		e.logger.Debugf("Skipping unreadable file %s in grep: %v", path, err)
+		return nil
+	}
+	if isChunkPotentiallyBinary(data, min(len(data), 1024)) {
+		return nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	contextLines = max(0, contextLines)
+	var matches []models.GrepMatch
+	relPath := e.toRelativePath(path)
+	for i, line := range lines {
+		if !pattern.MatchString(line) {
+			continue
+		}
+		match := models.GrepMatch{Path: relPath, Line: i + 1, Text: line}
+		if before := lines[max(0, i-contextLines):i]; len(before) > 0 {
+			match.Before = before
+		}
+		if after := lines[i+1 : min(len(lines), i+1+contextLines)]; len(after) > 0 {
+			match.After = after
+		}
+		matches = append(matches, match)
+		if len(matches) >= limit {
+			break
+		}
+	}
+	return matches
+}
+
+// matchesAnyGlob reports whether a slash-separated relative path or its base name matches any of globs
+func matchesAnyGlob(globs []string, relPath string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, filepath.Base(relPath)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, relPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// formatGrepMatches renders matches like grep -n: "path:line:text" for matching lines,
+// "path-line-text" for context lines, and "--" between groups when there is context
+func formatGrepMatches(matches []models.GrepMatch, truncated bool, maxResults int) string {
+	var content strings.Builder
+	for i, m := range matches {
+		hasContext := len(m.Before) > 0 || len(m.After) > 0
+		if i > 0 && hasContext {
+			content.WriteString("--\n")
+		}
+		for j, line := range m.Before {
+			fmt.Fprintf(&content, "%s-%d-%s\n", m.Path, m.Line-len(m.Before)+j, line)
+		}
+		fmt.Fprintf(&content, "%s:%d:%s\n", m.Path, m.Line, m.Text)
+		for j, line := range m.After {
+			fmt.Fprintf(&content, "%s-%d-%s\n", m.Path, m.Line+1+j, line)
+		}
+	}
+	if truncated {
+		fmt.Fprintf(&content, "[Results truncated at %d matches]\n", maxResults)
+	}
+	return content.String()
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// defaultInterruptGrace is how long InterruptCommand waits for a command to exit before escalating
+const defaultInterruptGrace = 2 * time.Second
+
+// interruptSignals is the escalation order used by InterruptCommand
+var interruptSignals = []struct {
+	name   string
+	signal syscall.Signal
+}{
+	{"INT", syscall.SIGINT},
+	{"TERM", syscall.SIGTERM},
+	{"KILL", syscall.SIGKILL},
+}
+
+var (
+	// ErrCommandNotRunning is returned by InterruptCommand for an unknown or finished command
+	ErrCommandNotRunning = errors.New("command is not running")
+	// ErrUnsupportedSignal is returned by InterruptCommand for a signal other than INT, TERM or KILL
+	ErrUnsupportedSignal = errors.New("unsupported signal")
+)
+
+// runningCommand is a command that can be interrupted with InterruptCommand
+type runningCommand struct {
+	done        chan struct{} // Closed once the command has exited
+	interrupted atomic.Bool   // Whether InterruptCommand signalled it
+}
+
+// trackCommand registers a started command so it can be interrupted by its command ID (its PID).
+// The returned function must be called once the command has been waited for; it reports
+// whether the command was interrupted.
+func (e *Executor) trackCommand(cmd *exec.Cmd) func() bool {
+	pid := cmd.Process.Pid
+	running := &runningCommand{done: make(chan struct{})}
+
+	e.mu.Lock()
+	if e.running == nil {
+		e.running = make(map[int]*runningCommand)
+	}
+	e.running[pid] = running
+	e.mu.Unlock()
+
+	return func() bool {
+		e.mu.Lock()
+		delete(e.running, pid)
+		e.mu.Unlock()
+		close(running.done)
+		return running.interrupted.Load()
+	}
+}
+
+// InterruptCommand signals the process group of a running command, starting with the given signal
+// (INT, TERM or KILL, default INT). If the command is still alive after a grace period the next
+// stronger signal is sent. It returns the name of the signal that stopped the command.
+func (e *Executor) InterruptCommand(pid int, signal string) (string, error) {
+	start, err := interruptSignalIndex(signal)
+	if err != nil {
+		return "", err
+	}
+
+	e.mu.RLock()
+	running, ok := e.running[pid]
+	grace := e.interruptGrace
+	e.mu.RUnlock()
+	if !ok {
+		return "", ErrCommandNotRunning
+	}
+	running.interrupted.Store(true)
+	if grace <= 0 {
+		grace = defaultInterruptGrace
+	}
+
+	for _, sig := range interruptSignals[start:] {
+		e.logger.Infof("Sending SIG%s to command %d", sig.name, pid)
+		if err := signalProcessGroup(pid, sig.signal); err != nil {
+			e.logger.Warnf("Failed to send SIG%s to command %d: %v", sig.name, pid, err)
+		}
+
+		select {
+		case <-running.done:
+			return sig.name, nil
+		case <-time.After(grace):
+		}
+	}
+	return "", fmt.Errorf("command %d did not exit after SIGKILL", pid)
+}
+
+// interruptSignalIndex returns the position of a signal name in interruptSignals
+func interruptSignalIndex(signal string) (int, error) {
+	if signal == "" {
+		return 0, nil
+	}
+
+	name := strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	for i, sig := range interruptSignals {
+		if sig.name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q, expected INT, TERM or KILL", ErrUnsupportedSignal, signal)
+}
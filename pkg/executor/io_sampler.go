@@ -0,0 +1,105 @@
+package executor
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// ioSampler periodically samples the cumulative IO counters of the runtime process
+// to turn them into a current throughput
+type ioSampler struct {
+	counters func() (*process.IOCountersStat, error)
+
+	mu        sync.Mutex
+	last      *process.IOCountersStat
+	lastTime  time.Time
+	readRate  float64
+	writeRate float64
+	stop      chan struct{}
+	stopOnce  sync.Once
+	stopped   chan struct{}
+}
+
+// startIOSampler samples the IO counters of the runtime process every interval
+func (e *Executor) startIOSampler(interval time.Duration) {
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		e.logger.Warnf("Failed to start IO sampling: %v", err)
+		return
+	}
+	e.ioSampler = newIOSampler(proc.IOCounters)
+	e.ioSampler.Start(interval)
+}
+
+// newIOSampler returns a sampler reading counters from the given function
+func newIOSampler(counters func() (*process.IOCountersStat, error)) *ioSampler {
+	return &ioSampler{
+		counters: counters,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start samples every interval in the background until Stop is called
+func (s *ioSampler) Start(interval time.Duration) {
+	s.sample(time.Now())
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.sample(now)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends background sampling and waits for it to finish
+func (s *ioSampler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.stopped
+	})
+}
+
+// sample reads the counters and updates the rates over the time since the previous sample
+func (s *ioSampler) sample(now time.Time) {
+	counters, err := s.counters()
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last != nil {
+		if elapsed := now.Sub(s.lastTime).Seconds(); elapsed > 0 {
+			s.readRate = counterRate(s.last.ReadBytes, counters.ReadBytes, elapsed)
+			s.writeRate = counterRate(s.last.WriteBytes, counters.WriteBytes, elapsed)
+		}
+	}
+	s.last = counters
+	s.lastTime = now
+}
+
+// Rates returns the read and write throughput in bytes per second over the last interval
+func (s *ioSampler) Rates() (read, write float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readRate, s.writeRate
+}
+
+// counterRate is the per-second increase of a cumulative counter, treating a reset as no activity
+func counterRate(previous, current uint64, elapsed float64) float64 {
+	if current < previous {
+		return 0
+	}
+	return float64(current-previous) / elapsed
+}
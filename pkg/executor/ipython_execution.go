@@ -8,7 +8,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/denysvitali/openhands-runtime-go/internal/models"
 )
@@ -29,8 +33,19 @@ func (e *Executor) executeIPython(ctx context.Context, action models.IPythonRunC
 		return models.NewErrorObservation(errorMsg, "JupyterNotInstalledError"), nil
 	}
 
-	// Create a temporary notebook file
-	tempDir, err := os.MkdirTemp("", "jupyter")
+	kernel := defaultJupyterKernel
+	if action.Kernel != "" && action.Kernel != defaultJupyterKernel.Name {
+		var errorMsg string
+		kernel, errorMsg = findJupyterKernel(ctx, action.Kernel)
+		if errorMsg != "" {
+			e.logger.Error(errorMsg)
+			return models.NewErrorObservation(errorMsg, "JupyterKernelNotFoundError"), nil
+		}
+	}
+	span.SetAttributes(attribute.String("ipython.kernel", kernel.Name))
+
+	// Create a temporary notebook file, removed by the deferred cleanup even if execution panics
+	tempDir, err := e.makeTempDir("jupyter-*")
 	if err != nil {
 		e.logger.Errorf("Failed to create temp directory: %v", err)
 		return models.NewErrorObservation(
@@ -46,7 +61,11 @@ func (e *Executor) executeIPython(ctx context.Context, action models.IPythonRunC
 
 	// Create a simple notebook with the code
 	notebookPath := filepath.Join(tempDir, "notebook.ipynb")
-	notebook := createNotebookWithCode(action.Code)
+	var setupCode string
+	if e.config.Get().Server.IPythonMatplotlibInline {
+		setupCode = matplotlibInlineSetup
+	}
+	notebook := createNotebookWithCode(action.Code, setupCode, kernel)
 
 	notebookJSON, err := json.Marshal(notebook)
 	if err != nil {
@@ -67,10 +86,20 @@ func (e *Executor) executeIPython(ctx context.Context, action models.IPythonRunC
 	}
 
 	// Execute the notebook
+	timeout := e.ipythonTimeout(action)
+	span.SetAttributes(attribute.Int("ipython.timeout_seconds", timeout))
+
+	// The process deadline leaves room for kernel startup on top of the cell timeout
+	execCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second+ipythonStartupGrace)
+	defer cancel()
+
 	outputPath := filepath.Join(tempDir, "output.ipynb")
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		execCtx,
 		"jupyter", "nbconvert", "--to", "notebook", "--execute",
-		"--ExecutePreprocessor.timeout=60",
+		fmt.Sprintf("--ExecutePreprocessor.timeout=%d", timeout),
+		"--ExecutePreprocessor.kernel_name="+kernel.Name,
+		"--allow-errors",
 		"--output", outputPath,
 		notebookPath,
 	)
@@ -79,6 +108,11 @@ func (e *Executor) executeIPython(ctx context.Context, action models.IPythonRunC
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
+		if execCtx.Err() == context.DeadlineExceeded || strings.Contains(stderr.String(), "CellTimeoutError") {
+			errorMsg := fmt.Sprintf("IPython cell execution timed out after %d seconds", timeout)
+			e.logger.Warn(errorMsg)
+			return models.NewErrorObservation(errorMsg, "IPythonTimeoutError"), nil
+		}
 		errorMsg := fmt.Sprintf("Failed to execute notebook: %v\n%s", err, stderr.String())
 		e.logger.Error(errorMsg)
 		return models.NewErrorObservation(errorMsg, "IPythonExecutionError"), nil
@@ -107,26 +141,102 @@ func (e *Executor) executeIPython(ctx context.Context, action models.IPythonRunC
 	// Extract the outputs
 	result := extractNotebookOutputs(outputNotebook)
 
-	return models.NewIPythonRunCellObservation(result, action.Code, []string{}), nil
+	imageURLs := []string{}
+	for _, output := range result.outputs {
+		if strings.HasPrefix(output.MimeType, "image/") {
+			imageURLs = append(imageURLs, fmt.Sprintf("data:%s;base64,%s", output.MimeType, output.Data))
+		}
+	}
+
+	observation := models.NewIPythonRunCellObservation(result.content, action.Code, imageURLs, result.outputs)
+	if result.errorName != "" {
+		e.logger.Infof("IPython cell raised %s: %s", result.errorName, result.errorValue)
+		span.SetAttributes(attribute.String("ipython.error", result.errorName))
+		observation.Extras.ErrorName = result.errorName
+		observation.Extras.ErrorValue = result.errorValue
+	}
+	return observation, nil
+}
+
+// ipythonStartupGrace is how long nbconvert may take beyond the cell timeout, e.g. to start the kernel
+const ipythonStartupGrace = 30 * time.Second
+
+// ipythonTimeout returns the per-cell timeout in seconds, preferring the action's own timeout
+func (e *Executor) ipythonTimeout(action models.IPythonRunCellAction) int {
+	if action.Timeout > 0 {
+		return action.Timeout
+	}
+	if timeout := e.config.Get().Server.IPythonTimeoutSec; timeout > 0 {
+		return timeout
+	}
+	return 60
+}
+
+// matplotlibInlineSetup switches matplotlib to the inline backend so that figures are captured as PNG outputs.
+// It is a no-op when matplotlib isn't installed.
+const matplotlibInlineSetup = `try:
+    get_ipython().run_line_magic("matplotlib", "inline")
+    get_ipython().run_line_magic("config", "InlineBackend.figure_formats = ['png']")
+except Exception:
+    pass`
+
+// jupyterKernel identifies the kernelspec a notebook runs with
+type jupyterKernel struct {
+	Name        string
+	DisplayName string
+	Language    string
+}
+
+// defaultJupyterKernel is used when an action doesn't select a kernel
+var defaultJupyterKernel = jupyterKernel{Name: "python3", DisplayName: "Python 3", Language: "python"}
+
+// findJupyterKernel looks up an installed kernelspec by name with `jupyter kernelspec list`.
+// When the kernel can't be used it returns a message for the agent naming the installed kernels.
+func findJupyterKernel(ctx context.Context, name string) (jupyterKernel, string) {
+	output, err := exec.CommandContext(ctx, "jupyter", "kernelspec", "list", "--json").Output()
+	if err != nil {
+		return jupyterKernel{}, fmt.Sprintf("Failed to list Jupyter kernels: %v", err)
+	}
+
+	var list struct {
+		Kernelspecs map[string]struct {
+			Spec struct {
+				DisplayName string `json:"display_name"`
+				Language    string `json:"language"`
+			} `json:"spec"`
+		} `json:"kernelspecs"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return jupyterKernel{}, fmt.Sprintf("Failed to parse Jupyter kernel list: %v", err)
+	}
+
+	spec, ok := list.Kernelspecs[name]
+	if !ok {
+		available := make([]string, 0, len(list.Kernelspecs))
+		for kernelName := range list.Kernelspecs {
+			available = append(available, kernelName)
+		}
+		sort.Strings(available)
+		return jupyterKernel{}, fmt.Sprintf("Jupyter kernel %q is not installed. Available kernels: %s", name, strings.Join(available, ", "))
+	}
+	return jupyterKernel{Name: name, DisplayName: spec.Spec.DisplayName, Language: spec.Spec.Language}, ""
 }
 
-// Utility function to create a notebook with a single code cell
-func createNotebookWithCode(code string) map[string]interface{} {
+// Utility function to create a notebook with a code cell, preceded by a setup cell when setupCode is given
+func createNotebookWithCode(code, setupCode string, kernel jupyterKernel) map[string]interface{} {
+	cells := []map[string]interface{}{}
+	if setupCode != "" {
+		cells = append(cells, newCodeCell(setupCode))
+	}
+	cells = append(cells, newCodeCell(code))
+
 	return map[string]interface{}{
-		"cells": []map[string]interface{}{
-			{
-				"cell_type":       "code",
-				"execution_count": nil,
-				"metadata":        map[string]interface{}{},
-				"source":          []string{code},
-				"outputs":         []interface{}{},
-			},
-		},
+		"cells": cells,
 		"metadata": map[string]interface{}{
 			"kernelspec": map[string]interface{}{
-				"display_name": "Python 3",
-				"language":     "python",
-				"name":         "python3",
+				"display_name": kernel.DisplayName,
+				"language":     kernel.Language,
+				"name":         kernel.Name,
 			},
 		},
 		"nbformat":       4,
@@ -134,13 +244,36 @@ func createNotebookWithCode(code string) map[string]interface{} {
 	}
 }
 
+// newCodeCell returns an unexecuted notebook code cell
+func newCodeCell(source string) map[string]interface{} {
+	return map[string]interface{}{
+		"cell_type":       "code",
+		"execution_count": nil,
+		"metadata":        map[string]interface{}{},
+		"source":          []string{source},
+		"outputs":         []interface{}{},
+	}
+}
+
+// richOutputMimeTypes lists the display data mime types captured in observation extras, in order of preference
+var richOutputMimeTypes = []string{"text/plain", "text/html", "image/png", "image/jpeg", "image/svg+xml"}
+
+// notebookOutputs holds what was extracted from an executed notebook
+type notebookOutputs struct {
+	content    string                 // Textual content for the agent
+	outputs    []models.IPythonOutput // Every output with its mime type
+	errorName  string                 // Exception class name if a cell raised
+	errorValue string                 // Exception message if a cell raised
+}
+
 // Utility function to extract outputs from a notebook
-func extractNotebookOutputs(notebook map[string]interface{}) string {
+func extractNotebookOutputs(notebook map[string]interface{}) notebookOutputs {
 	var result strings.Builder
+	var extracted notebookOutputs
 
 	cells, ok := notebook["cells"].([]interface{})
 	if !ok || len(cells) == 0 {
-		return "No output"
+		return notebookOutputs{content: "No output"}
 	}
 
 	for _, cellInterface := range cells {
@@ -161,12 +294,30 @@ func extractNotebookOutputs(notebook map[string]interface{}) string {
 			}
 
 			// Text output
-			if text, ok := output["text"].([]interface{}); ok {
-				for _, t := range text {
-					if str, ok := t.(string); ok {
-						result.WriteString(str)
+			if text, ok := output["text"]; ok {
+				str := joinNotebookText(text)
+				result.WriteString(str)
+				extracted.outputs = append(extracted.outputs, models.IPythonOutput{MimeType: "text/plain", Data: str})
+			}
+
+			// Error output, the traceback lines carry ANSI color codes
+			if outputType, _ := output["output_type"].(string); outputType == "error" {
+				extracted.errorName, _ = output["ename"].(string)
+				extracted.errorValue, _ = output["evalue"].(string)
+
+				var traceback []string
+				if lines, ok := output["traceback"].([]interface{}); ok {
+					for _, line := range lines {
+						if str, ok := line.(string); ok {
+							traceback = append(traceback, stripANSI(str))
+						}
 					}
 				}
+				if len(traceback) == 0 {
+					traceback = []string{fmt.Sprintf("%s: %s", extracted.errorName, extracted.errorValue)}
+				}
+				result.WriteString(strings.Join(traceback, "\n"))
+				result.WriteString("\n")
 			}
 
 			// Data output (like images, HTML, etc.)
@@ -190,9 +341,41 @@ func extractNotebookOutputs(notebook map[string]interface{}) string {
 				if _, ok := data["image/png"]; ok {
 					result.WriteString("[Image output was produced]\n")
 				}
+
+				for _, mimeType := range richOutputMimeTypes {
+					value, ok := data[mimeType]
+					if !ok {
+						continue
+					}
+					str := joinNotebookText(value)
+					if strings.HasPrefix(mimeType, "image/") && mimeType != "image/svg+xml" {
+						// Base64 payloads may be wrapped across lines
+						str = strings.ReplaceAll(str, "\n", "")
+					}
+					extracted.outputs = append(extracted.outputs, models.IPythonOutput{MimeType: mimeType, Data: str})
+				}
 			}
 		}
 	}
 
-	return result.String()
+	extracted.content = result.String()
+	return extracted
+}
+
+// joinNotebookText joins a notebook multiline string, which nbformat stores either as a string or a list of strings
+func joinNotebookText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var builder strings.Builder
+		for _, part := range v {
+			if str, ok := part.(string); ok {
+				builder.WriteString(str)
+			}
+		}
+		return builder.String()
+	default:
+		return ""
+	}
 }
@@ -0,0 +1,316 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+)
+
+// requireJupyter skips the test when Jupyter isn't available to execute notebooks
+func requireJupyter(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("jupyter"); err != nil {
+		t.Skip("jupyter is not installed")
+	}
+}
+
+// installFakeJupyter puts a fake jupyter executable running the given shell script first on PATH
+func installFakeJupyter(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jupyter"), []byte("#!/bin/sh\n"+script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestExecuteIPython_Timeout(t *testing.T) {
+	// Mimic nbconvert reporting a cell timeout, and fail if the timeout wasn't passed through
+	installFakeJupyter(t, `
+for arg in "$@"; do
+	[ "$arg" = "--ExecutePreprocessor.timeout=2" ] && found=1
+done
+[ -n "$found" ] || { echo "unexpected arguments: $*" >&2; exit 2; }
+echo "nbclient.exceptions.CellTimeoutError: A cell timed out while it was being executed, after 2 seconds." >&2
+exit 1
+`)
+
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.IPythonTimeoutSec = 30
+	})
+
+	obs, err := executor.executeIPython(context.Background(), models.IPythonRunCellAction{
+		Code:    "import time; time.sleep(10)",
+		Timeout: 2,
+	})
+	require.NoError(t, err)
+
+	errObs, ok := obs.(models.Observation[models.ErrorExtras])
+	require.True(t, ok, "expected ErrorObservation, got %T", obs)
+	assert.Equal(t, "IPythonTimeoutError", errObs.Extras.ErrorID)
+	assert.Contains(t, errObs.Content, "timed out after 2 seconds")
+}
+
+func TestExecuteIPython_TempDir(t *testing.T) {
+	// Record where the notebook was written and "execute" it by copying it to the output path
+	installFakeJupyter(t, `
+while [ $# -gt 1 ]; do
+	[ "$1" = "--output" ] && output="$2"
+	shift
+done
+echo "$1" > "$NOTEBOOK_RECORD"
+cp "$1" "$output"
+`)
+	record := filepath.Join(t.TempDir(), "notebook-path")
+	t.Setenv("NOTEBOOK_RECORD", record)
+
+	tempDir := filepath.Join(t.TempDir(), "scratch")
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.TempDir = tempDir
+	})
+
+	obs, err := executor.executeIPython(context.Background(), models.IPythonRunCellAction{Code: "print(1)"})
+	require.NoError(t, err)
+	_, ok := obs.(models.Observation[models.IPythonExtras])
+	require.True(t, ok, "expected IPythonRunCellObservation, got %T", obs)
+
+	recorded, err := os.ReadFile(record)
+	require.NoError(t, err)
+	notebookPath := strings.TrimSpace(string(recorded))
+	assert.Equal(t, tempDir, filepath.Dir(filepath.Dir(notebookPath)), "notebook should be written under server.temp_dir")
+	assert.NoFileExists(t, notebookPath)
+
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "notebook scratch directory should be removed")
+
+	t.Run("defaults to the working directory", func(t *testing.T) {
+		reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.TempDir = "" })
+		dir, err := executor.makeTempDir("jupyter-*")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(executor.workingDir, defaultTempDir), filepath.Dir(dir))
+	})
+}
+
+func TestExecuteIPython_Kernel(t *testing.T) {
+	// Report two installed kernels, and record the kernel nbconvert was asked to use
+	installFakeJupyter(t, `
+if [ "$1" = "kernelspec" ]; then
+	echo '{"kernelspecs": {
+		"python3": {"resource_dir": "/k/python3", "spec": {"display_name": "Python 3", "language": "python"}},
+		"conda-ml": {"resource_dir": "/k/conda-ml", "spec": {"display_name": "Python (ml)", "language": "python"}}
+	}}'
+	exit 0
+fi
+while [ $# -gt 1 ]; do
+	case "$1" in
+		--output) output="$2" ;;
+		--ExecutePreprocessor.kernel_name=*) echo "${1#*=}" > "$KERNEL_RECORD" ;;
+	esac
+	shift
+done
+cp "$1" "$output"
+`)
+	record := filepath.Join(t.TempDir(), "kernel")
+	t.Setenv("KERNEL_RECORD", record)
+
+	executor := newTestExecutor(t)
+
+	t.Run("named kernel", func(t *testing.T) {
+		obs, err := executor.executeIPython(context.Background(), models.IPythonRunCellAction{Code: "print(1)", Kernel: "conda-ml"})
+		require.NoError(t, err)
+		_, ok := obs.(models.Observation[models.IPythonExtras])
+		require.True(t, ok, "expected IPythonRunCellObservation, got %T", obs)
+
+		kernel, err := os.ReadFile(record)
+		require.NoError(t, err)
+		assert.Equal(t, "conda-ml\n", string(kernel))
+	})
+
+	t.Run("unknown kernel", func(t *testing.T) {
+		obs, err := executor.executeIPython(context.Background(), models.IPythonRunCellAction{Code: "print(1)", Kernel: "julia"})
+		require.NoError(t, err)
+
+		errObs, ok := obs.(models.Observation[models.ErrorExtras])
+		require.True(t, ok, "expected ErrorObservation, got %T", obs)
+		assert.Equal(t, "JupyterKernelNotFoundError", errObs.Extras.ErrorID)
+		assert.Contains(t, errObs.Content, "Available kernels: conda-ml, python3")
+	})
+
+	t.Run("notebook metadata", func(t *testing.T) {
+		kernel, errorMsg := findJupyterKernel(context.Background(), "conda-ml")
+		require.Empty(t, errorMsg)
+
+		notebook := createNotebookWithCode("print(1)", "", kernel)
+		metadata := notebook["metadata"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{
+			"display_name": "Python (ml)",
+			"language":     "python",
+			"name":         "conda-ml",
+		}, metadata["kernelspec"])
+	})
+}
+
+func TestIPythonTimeout(t *testing.T) {
+	executor := newTestExecutor(t)
+	assert.Equal(t, 60, executor.ipythonTimeout(models.IPythonRunCellAction{}))
+
+	reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.IPythonTimeoutSec = 120 })
+	assert.Equal(t, 120, executor.ipythonTimeout(models.IPythonRunCellAction{}))
+	assert.Equal(t, 5, executor.ipythonTimeout(models.IPythonRunCellAction{Timeout: 5}))
+}
+
+func TestCreateNotebookWithCode(t *testing.T) {
+	t.Run("without setup", func(t *testing.T) {
+		notebook := createNotebookWithCode("print(1)", "", defaultJupyterKernel)
+		cells := notebook["cells"].([]map[string]interface{})
+		require.Len(t, cells, 1)
+		assert.Equal(t, []string{"print(1)"}, cells[0]["source"])
+	})
+
+	t.Run("with matplotlib setup", func(t *testing.T) {
+		notebook := createNotebookWithCode("print(1)", matplotlibInlineSetup, defaultJupyterKernel)
+		cells := notebook["cells"].([]map[string]interface{})
+		require.Len(t, cells, 2)
+		assert.Equal(t, []string{matplotlibInlineSetup}, cells[0]["source"])
+		assert.Equal(t, []string{"print(1)"}, cells[1]["source"])
+	})
+}
+
+func TestExtractNotebookOutputs_RichOutputs(t *testing.T) {
+	notebookJSON := `{
+		"cells": [{
+			"cell_type": "code",
+			"outputs": [
+				{"output_type": "stream", "name": "stdout", "text": ["hello\n"]},
+				{"output_type": "display_data", "data": {
+					"text/plain": ["<Figure size 640x480 with 1 Axes>"],
+					"image/png": "iVBORw0KGgo=\n"
+				}},
+				{"output_type": "execute_result", "data": {
+					"text/plain": ["'<b>bold</b>'"],
+					"text/html": ["<b>bold</b>"]
+				}}
+			]
+		}]
+	}`
+	var notebook map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(notebookJSON), &notebook))
+
+	result := extractNotebookOutputs(notebook)
+
+	assert.Contains(t, result.content, "hello")
+	assert.Contains(t, result.content, "[Image output was produced]")
+	assert.Empty(t, result.errorName)
+	assert.Equal(t, []models.IPythonOutput{
+		{MimeType: "text/plain", Data: "hello\n"},
+		{MimeType: "text/plain", Data: "<Figure size 640x480 with 1 Axes>"},
+		{MimeType: "image/png", Data: "iVBORw0KGgo="},
+		{MimeType: "text/plain", Data: "'<b>bold</b>'"},
+		{MimeType: "text/html", Data: "<b>bold</b>"},
+	}, result.outputs)
+}
+
+func TestExtractNotebookOutputs_ErrorTraceback(t *testing.T) {
+	notebookJSON := `{
+		"cells": [{
+			"cell_type": "code",
+			"outputs": [{
+				"output_type": "error",
+				"ename": "ZeroDivisionError",
+				"evalue": "division by zero",
+				"traceback": [
+					"\u001b[0;31m---------------------------------------------------------------------------\u001b[0m",
+					"\u001b[0;31mZeroDivisionError\u001b[0m                         Traceback (most recent call last)",
+					"Cell \u001b[0;32mIn[1], line 1\u001b[0m\n\u001b[0;32m----> 1\u001b[0m \u001b[38;5;241m1\u001b[39m\u001b[38;5;241m/\u001b[39m\u001b[38;5;241m0\u001b[39m\n",
+					"\u001b[0;31mZeroDivisionError\u001b[0m: division by zero"
+				]
+			}]
+		}]
+	}`
+	var notebook map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(notebookJSON), &notebook))
+
+	result := extractNotebookOutputs(notebook)
+
+	assert.Equal(t, "ZeroDivisionError", result.errorName)
+	assert.Equal(t, "division by zero", result.errorValue)
+	assert.Contains(t, result.content, "Traceback (most recent call last)")
+	assert.Contains(t, result.content, "----> 1 1/0")
+	assert.Contains(t, result.content, "ZeroDivisionError: division by zero")
+	assert.NotContains(t, result.content, "\x1b")
+}
+
+func TestExecuteIPython_Traceback(t *testing.T) {
+	requireJupyter(t)
+	executor := newTestExecutor(t)
+
+	obs, err := executor.executeIPython(context.Background(), models.IPythonRunCellAction{Code: "1/0"})
+	require.NoError(t, err)
+
+	ipythonObs, ok := obs.(models.Observation[models.IPythonExtras])
+	require.True(t, ok, "expected IPythonRunCellObservation, got %T: %+v", obs, obs)
+	assert.Equal(t, "ZeroDivisionError", ipythonObs.Extras.ErrorName)
+	assert.Contains(t, ipythonObs.Content, "ZeroDivisionError")
+	assert.Contains(t, ipythonObs.Content, "Traceback")
+}
+
+func TestExecuteIPython_ImageOutput(t *testing.T) {
+	requireJupyter(t)
+	executor := newTestExecutor(t)
+
+	code := "import base64\n" +
+		"from IPython.display import Image, display\n" +
+		"png = base64.b64decode('iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg==')\n" +
+		"display(Image(data=png))"
+	obs, err := executor.executeIPython(context.Background(), models.IPythonRunCellAction{Code: code})
+	require.NoError(t, err)
+
+	ipythonObs, ok := obs.(models.Observation[models.IPythonExtras])
+	require.True(t, ok, "expected IPythonRunCellObservation, got %T: %+v", obs, obs)
+
+	var pngData string
+	for _, output := range ipythonObs.Extras.Outputs {
+		if output.MimeType == "image/png" {
+			pngData = output.Data
+		}
+	}
+	assert.Equal(t, "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg==", pngData)
+	assert.Contains(t, ipythonObs.Extras.ImageURLs, "data:image/png;base64,"+pngData)
+}
+
+func TestExecuteIPython_MatplotlibInline(t *testing.T) {
+	requireJupyter(t)
+	if err := exec.Command("python3", "-c", "import matplotlib").Run(); err != nil {
+		t.Skip("matplotlib is not installed")
+	}
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.IPythonMatplotlibInline = true
+	})
+
+	code := "import matplotlib.pyplot as plt\n" +
+		"plt.plot([1, 2, 3], [1, 4, 9])\n" +
+		"plt.show()"
+	obs, err := executor.executeIPython(context.Background(), models.IPythonRunCellAction{Code: code})
+	require.NoError(t, err)
+
+	ipythonObs, ok := obs.(models.Observation[models.IPythonExtras])
+	require.True(t, ok, "expected IPythonRunCellObservation, got %T: %+v", obs, obs)
+
+	var hasPNG bool
+	for _, output := range ipythonObs.Extras.Outputs {
+		if output.MimeType == "image/png" && output.Data != "" {
+			hasPNG = true
+		}
+	}
+	assert.True(t, hasPNG, "expected a PNG output, got %+v", ipythonObs.Extras.Outputs)
+	assert.NotEmpty(t, ipythonObs.Extras.ImageURLs)
+}
@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+)
+
+// jsonPatchIndent is the indentation used when writing a patched JSON document back
+const jsonPatchIndent = "  "
+
+// executeFilePatch applies a JSON patch to a JSON file and returns the resulting diff
+func (e *Executor) executeFilePatch(ctx context.Context, action models.FilePatchAction) (interface{}, error) {
+	_, span := e.tracer.Start(ctx, "file_patch")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("path", action.Path))
+
+	if err := e.SecurityCheck(action.Path); err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Security error: %v", err), "SecurityError"), nil
+	}
+
+	path := e.resolvePath(action.Path)
+	defer e.lockFile(path)()
+
+	fileInfo, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return models.NewErrorObservation(fmt.Sprintf("File not found: %s", action.Path), "FilePatchError"), nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to stat file %s: %v", action.Path, err), "FilePatchError"), nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to read file %s: %v", action.Path, err), "FilePatchError"), nil
+	}
+	if !json.Valid(content) {
+		return models.NewErrorObservation(fmt.Sprintf("File %s is not valid JSON", action.Path), "FilePatchError"), nil
+	}
+
+	patch, err := jsonpatch.DecodePatch(action.Patch)
+	if err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Invalid JSON patch: %v", err), "InvalidPatch"), nil
+	}
+
+	patched, err := patch.ApplyIndent(content, jsonPatchIndent)
+	if err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Failed to apply JSON patch to %s: %v", action.Path, err), "InvalidPatch"), nil
+	}
+	if bytes.HasSuffix(content, []byte("\n")) {
+		patched = append(patched, '\n')
+	}
+
+	if err := e.backupFile(path, content); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to back up %s: %v", action.Path, err), "BackupError"), nil
+	}
+
+	if err := writeFileAtomic(path, patched, fileInfo.Mode().Perm()); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to write changes to %s: %v", action.Path, err), "FilePatchError"), nil
+	}
+
+	oldContent, newContent := string(content), string(patched)
+	e.logger.Infof("Applied JSON patch to %s", action.Path)
+
+	return models.NewFileEditObservation(
+		e.generateDiff(oldContent, newContent, action.Path),
+		e.observationPath(action.Path),
+		oldContent,
+		newContent,
+		"patch_json",
+	), nil
+}
@@ -0,0 +1,47 @@
+package executor
+
+import "strings"
+
+// detectLineEnding returns the dominant line ending of content, "\r\n" or "\n".
+// Content without line breaks is treated as using "\n".
+func detectLineEnding(content string) string {
+	crlf := strings.Count(content, "\r\n")
+	lf := strings.Count(content, "\n") - crlf
+	if crlf > lf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// normalizeLineEndings converts CRLF line endings to LF, so that edits match and split lines
+// regardless of the file's line ending style
+func normalizeLineEndings(content string) string {
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
+
+// restoreLineEndings converts the LF line endings of normalized content to lineEnding
+func restoreLineEndings(content, lineEnding string) string {
+	if lineEnding == "\n" {
+		return content
+	}
+	return strings.ReplaceAll(content, "\n", lineEnding)
+}
+
+// preserveTrailingNewline makes updated end with a line break exactly when original did,
+// so that edits don't add or drop the final newline as a side effect.
+// An empty original or updated content has no such property to preserve.
+func preserveTrailingNewline(original, updated, lineEnding string) string {
+	if original == "" || updated == "" {
+		return updated
+	}
+	if strings.HasSuffix(original, "\n") {
+		if !strings.HasSuffix(updated, "\n") {
+			return updated + lineEnding
+		}
+		return updated
+	}
+	if trimmed, ok := strings.CutSuffix(updated, "\r\n"); ok {
+		return trimmed
+	}
+	return strings.TrimSuffix(updated, "\n")
+}
@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// allocationFailureMessages are printed by common runtimes and tools when an allocation fails
+var allocationFailureMessages = []string{
+	"MemoryError",
+	"Cannot allocate memory",
+	"memory exhausted",
+	"out of memory",
+	"std::bad_alloc",
+}
+
+// killedNote is appended to the output of commands killed by a SIGKILL the runtime didn't send
+const killedNote = "[Process killed (possibly out of memory)]"
+
+// sigkillExitCode is the exit code a shell reports for a child killed by SIGKILL
+const sigkillExitCode = 128 + 9
+
+// memoryLimitBytes returns the memory limit for spawned commands, or 0 when there is none
+func (e *Executor) memoryLimitBytes() uint64 {
+	if gb := e.config.Get().Server.MaxMemoryGB; gb > 0 {
+		return uint64(gb) << 30
+	}
+	return 0
+}
+
+// memoryLimitPrefix returns a shell snippet that caps the address space of the shell and everything it spawns
+// at server.max_memory_gb, or an empty string when there is no limit. Setting the limit from inside the shell
+// guarantees it is in place before the command runs; the hard limit keeps the command from raising it again.
+func (e *Executor) memoryLimitPrefix() string {
+	limit := e.memoryLimitBytes()
+	if limit == 0 {
+		return ""
+	}
+	return fmt.Sprintf("ulimit -v %d\n", limit/1024)
+}
+
+// exceededMemoryLimit reports whether a failed command most likely ran out of memory under server.max_memory_gb:
+// it was killed by a SIGKILL the runtime didn't send, or printed a known allocation failure message. Crashes
+// such as SIGSEGV or SIGABRT have too many other causes to count.
+func (e *Executor) exceededMemoryLimit(err error, killed bool, output string) bool {
+	if err == nil || e.memoryLimitBytes() == 0 {
+		return false
+	}
+	if killed {
+		return true
+	}
+	for _, message := range allocationFailureMessages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryLimitNote is appended to the output of commands that exceeded server.max_memory_gb
+func (e *Executor) memoryLimitNote() string {
+	return fmt.Sprintf("[Command exceeded the memory limit of %d GB]", e.config.Get().Server.MaxMemoryGB)
+}
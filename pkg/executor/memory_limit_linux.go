@@ -0,0 +1,22 @@
+//go:build linux
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// killedBySIGKILL reports whether a command, or the last process its shell waited for, was killed by SIGKILL
+func killedBySIGKILL(err error, exitCode int) bool {
+	if exitCode == sigkillExitCode {
+		return true
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
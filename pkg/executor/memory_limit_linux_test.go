@@ -0,0 +1,52 @@
+//go:build linux
+
+package executor
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+)
+
+func TestExecuteCmdRun_MemoryLimit(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not installed")
+	}
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.MaxMemoryGB = 1
+	})
+	ctx := context.Background()
+
+	t.Run("allocation above the limit fails", func(t *testing.T) {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: `python3 -c "x = bytearray(2 * 1024**3)"`})
+		require.NoError(t, err)
+
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+		assert.NotEqual(t, 0, cmdObs.Extras.ExitCode)
+		assert.Contains(t, cmdObs.Content, "[Command exceeded the memory limit of 1 GB]")
+	})
+
+	t.Run("crash is not reported as the limit", func(t *testing.T) {
+		err := exec.Command("sh", "-c", "kill -SEGV $$").Run()
+		require.Error(t, err)
+		assert.False(t, executor.exceededMemoryLimit(err, false, ""))
+		assert.True(t, executor.exceededMemoryLimit(err, false, "MemoryError"))
+	})
+
+	t.Run("allocation below the limit succeeds", func(t *testing.T) {
+		obs, err := executor.executeCmdRun(ctx, models.CmdRunAction{Command: `python3 -c "x = bytearray(16 * 1024**2); print('ok')"`})
+		require.NoError(t, err)
+
+		cmdObs, ok := obs.(models.Observation[models.CmdOutputExtras])
+		require.True(t, ok, "expected CmdOutputObservation, got %T", obs)
+		assert.Equal(t, 0, cmdObs.Extras.ExitCode)
+		assert.Equal(t, "ok\n", cmdObs.Content)
+	})
+}
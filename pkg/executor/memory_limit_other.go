@@ -0,0 +1,8 @@
+//go:build !linux
+
+package executor
+
+// killedBySIGKILL only recognizes the exit code shells report for a child killed by SIGKILL outside Linux
+func killedBySIGKILL(err error, exitCode int) bool {
+	return exitCode == sigkillExitCode
+}
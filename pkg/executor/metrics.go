@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// fileSizeBuckets are the histogram bucket boundaries for file sizes, in bytes
+var fileSizeBuckets = []float64{
+	1 << 10, 16 << 10, 64 << 10, 256 << 10,
+	1 << 20, 16 << 20, 64 << 20, 256 << 20,
+	1 << 30,
+}
+
+// fileOperationActions are the action types counted as file operations
+var fileOperationActions = map[string]bool{"read": true, "write": true, "edit": true, "patch_json": true}
+
+// executorMetrics holds the instruments recorded by the executor
+type executorMetrics struct {
+	fileSize        metric.Int64Histogram
+	archiveBytes    metric.Int64Counter
+	fileOperations  metric.Int64Counter
+	commandDuration metric.Float64Histogram
+}
+
+// newExecutorMetrics creates the executor's instruments from meter
+func newExecutorMetrics(meter metric.Meter) (*executorMetrics, error) {
+	fileSize, err := meter.Int64Histogram("runtime.file.size",
+		metric.WithDescription("Size of files read, written and uploaded"),
+		metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(fileSizeBuckets...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archiveBytes, err := meter.Int64Counter("runtime.archive.bytes_out",
+		metric.WithDescription("Bytes streamed out in zip archives"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	fileOperations, err := meter.Int64Counter("runtime.file.operations",
+		metric.WithDescription("File operations performed, by operation and outcome"),
+		metric.WithUnit("{operation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	commandDuration, err := meter.Float64Histogram("runtime.command.duration",
+		metric.WithDescription("Duration of shell commands, run or streamed"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &executorMetrics{
+		fileSize:        fileSize,
+		archiveBytes:    archiveBytes,
+		fileOperations:  fileOperations,
+		commandDuration: commandDuration,
+	}, nil
+}
+
+// recordFileSize records the size of a file handled by operation (read, write or upload)
+func (m *executorMetrics) recordFileSize(ctx context.Context, operation string, size int64) {
+	m.fileSize.Record(ctx, size, metric.WithAttributes(attribute.String("operation", operation)))
+}
+
+// countFileOperation counts a file operation: an action in fileOperationActions, an upload, a download or an archive
+func (m *executorMetrics) countFileOperation(ctx context.Context, operation string, success bool) {
+	m.fileOperations.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("operation", operation),
+		attribute.Bool("success", success),
+	))
+}
+
+// recordCommandDuration records the time since start for a command executed in mode (run or stream).
+// It is meant to be deferred at the start of the execution.
+func (m *executorMetrics) recordCommandDuration(ctx context.Context, mode string, start time.Time) {
+	m.commandDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attribute.String("mode", mode)))
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+)
+
+// outputTimeline records when each line of a command's output was captured
+type outputTimeline struct {
+	mu      sync.Mutex
+	lines   []models.OutputLine
+	writers []*timelineWriter
+}
+
+// writer returns a writer that passes output through to w and records its lines under stream
+func (t *outputTimeline) writer(stream string, w io.Writer) io.Writer {
+	writer := &timelineWriter{timeline: t, stream: stream, w: w}
+	t.mu.Lock()
+	t.writers = append(t.writers, writer)
+	t.mu.Unlock()
+	return writer
+}
+
+// Lines returns the recorded lines, including any unterminated last lines
+func (t *outputTimeline) Lines() []models.OutputLine {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, writer := range t.writers {
+		writer.flushLocked()
+	}
+	return t.lines
+}
+
+// timelineWriter splits the output of one stream into lines for an outputTimeline
+type timelineWriter struct {
+	timeline *outputTimeline
+	stream   string
+	w        io.Writer
+
+	// partial is the current unterminated line, started at partialTime
+	partial     []byte
+	partialTime time.Time
+}
+
+func (w *timelineWriter) Write(p []byte) (int, error) {
+	now := time.Now()
+	n, err := w.w.Write(p)
+
+	w.timeline.mu.Lock()
+	defer w.timeline.mu.Unlock()
+
+	for data := p[:n]; len(data) > 0; {
+		if w.partialTime.IsZero() {
+			w.partialTime = now
+		}
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			w.partial = append(w.partial, data...)
+			break
+		}
+		w.partial = append(w.partial, data[:end]...)
+		w.flushLocked()
+		data = data[end+1:]
+	}
+	return n, err
+}
+
+// flushLocked records the current line, if any. The timeline's mutex must be held.
+func (w *timelineWriter) flushLocked() {
+	if w.partialTime.IsZero() {
+		return
+	}
+	w.timeline.lines = append(w.timeline.lines, models.OutputLine{
+		Time:   w.partialTime,
+		Stream: w.stream,
+		Line:   string(w.partial),
+	})
+	w.partial = w.partial[:0]
+	w.partialTime = time.Time{}
+}
@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+)
+
+// installFailureTailLines is how many trailing output lines a failed install summary keeps,
+// which is where package managers print their errors
+const installFailureTailLines = 20
+
+// installCommand is a package install found in a shell command
+type installCommand struct {
+	manager  string
+	packages []string // Package arguments as given on the command line
+}
+
+// installSubcommands lists, per package manager executable, the subcommands that install packages
+var installSubcommands = map[string][]string{
+	"pip":     {"install"},
+	"npm":     {"install", "i", "add"},
+	"yarn":    {"add", "install"},
+	"pnpm":    {"add", "install", "i"},
+	"apt":     {"install"},
+	"apt-get": {"install"},
+}
+
+// pipFlagsWithValue are pip install options whose value is the next argument rather than a package
+var pipFlagsWithValue = map[string]bool{
+	"-r": true, "--requirement": true, "-c": true, "--constraint": true, "-e": true, "--editable": true,
+	"-i": true, "--index-url": true, "--extra-index-url": true, "-t": true, "--target": true,
+	"-f": true, "--find-links": true,
+}
+
+// commandSeparators splits a shell command line into its simple commands
+var commandSeparators = regexp.MustCompile(`&&|\|\||[;|\n]`)
+
+// pipVersionedName matches executables such as pip3 and pip3.12
+var pipVersionedName = regexp.MustCompile(`^pip[0-9.]*$`)
+
+// detectInstallCommand reports the first package install among the simple commands of command.
+// It understands sudo, leading variable assignments, `python -m pip` and `uv pip`.
+func detectInstallCommand(command string) (installCommand, bool) {
+	for _, segment := range commandSeparators.Split(command, -1) {
+		words := strings.Fields(segment)
+		for len(words) > 0 && (words[0] == "sudo" || words[0] == "env" || strings.Contains(words[0], "=")) {
+			words = words[1:]
+		}
+		if len(words) >= 3 && strings.HasPrefix(words[0], "python") && words[1] == "-m" {
+			words = words[2:]
+		} else if len(words) >= 2 && words[0] == "uv" {
+			words = words[1:]
+		}
+		if len(words) < 2 {
+			continue
+		}
+
+		manager := words[0]
+		if pipVersionedName.MatchString(manager) {
+			manager = "pip"
+		}
+		subcommands, ok := installSubcommands[manager]
+		if !ok || !containsString(subcommands, words[1]) {
+			continue
+		}
+		if manager == "apt-get" {
+			manager = "apt"
+		}
+
+		install := installCommand{manager: manager}
+		args := words[2:]
+		for i := 0; i < len(args); i++ {
+			if strings.HasPrefix(args[i], "-") {
+				if manager == "pip" && pipFlagsWithValue[args[i]] {
+					i++
+				}
+				continue
+			}
+			install.packages = append(install.packages, args[i])
+		}
+		return install, true
+	}
+	return installCommand{}, false
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// aptSetupLine matches the line apt prints for each package it installs
+var aptSetupLine = regexp.MustCompile(`^Setting up (\S+?)(?::\S+)? \((\S+)\)`)
+
+// installedPackages extracts the packages a successful install reports, if its manager lists them
+func installedPackages(manager, output string) []string {
+	var packages []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch manager {
+		case "pip":
+			if rest, ok := strings.CutPrefix(line, "Successfully installed "); ok {
+				packages = append(packages, strings.Fields(rest)...)
+			}
+		case "apt":
+			if match := aptSetupLine.FindStringSubmatch(line); match != nil {
+				packages = append(packages, match[1]+"="+match[2])
+			}
+		}
+	}
+	return packages
+}
+
+// summarizeInstall collapses the output of a package install into a short summary. Failures keep
+// the end of the output, where the error is.
+func summarizeInstall(install installCommand, output string, exitCode int) (string, *models.InstallInfo) {
+	info := &models.InstallInfo{Manager: install.manager, Success: exitCode == 0}
+	var lines []string
+	if trimmed := strings.TrimRight(output, "\n"); trimmed != "" {
+		lines = strings.Split(trimmed, "\n")
+	}
+
+	if exitCode != 0 {
+		tail := lines[max(0, len(lines)-installFailureTailLines):]
+		info.Packages = install.packages
+		summary := fmt.Sprintf("%s install failed with exit code %d", install.manager, exitCode)
+		if omitted := len(lines) - len(tail); omitted > 0 {
+			summary += fmt.Sprintf(" (%d earlier lines of output omitted)", omitted)
+		}
+		return summary + ":\n" + strings.Join(tail, "\n"), info
+	}
+
+	info.Packages = installedPackages(install.manager, output)
+	if len(info.Packages) == 0 {
+		info.Packages = install.packages
+	}
+	summary := fmt.Sprintf("%s install succeeded", install.manager)
+	if len(info.Packages) > 0 {
+		summary += ": " + strings.Join(info.Packages, ", ")
+	}
+	return summary + fmt.Sprintf(" (%d lines of output omitted)", len(lines)), info
+}
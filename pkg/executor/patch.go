@@ -0,0 +1,215 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+)
+
+// gitApplyInvalidPatch is the exit code of git apply for a patch it can't parse or use at all
+const gitApplyInvalidPatch = 128
+
+// Lines with which git apply --verbose reports the outcome of each file's patch
+var (
+	gitAppliedCleanly = regexp.MustCompile(`^Applied patch (.+) cleanly\.$`)
+	gitAppliedRejects = regexp.MustCompile(`^Applying patch (.+) with \d+ rejects?\.\.\.$`)
+	gitHunkApplied    = regexp.MustCompile(`^Hunk #\d+ applied cleanly\.$`)
+	gitHunkRejected   = regexp.MustCompile(`^Rejected hunk #\d+\.$`)
+	gitSkippedPatch   = regexp.MustCompile(`^Skipped patch '(.+)'\.$`)
+)
+
+// unifiedDiffNewFile matches the lines naming the new version of each file in a unified diff
+var unifiedDiffNewFile = regexp.MustCompile(`(?m)^\+\+\+ (\S+)`)
+
+// unifiedDiffFile matches the lines naming the old or new version of each file in a unified diff
+var unifiedDiffFile = regexp.MustCompile(`(?m)^(?:---|\+\+\+) (\S+)`)
+
+// executePatch applies a unified diff with git apply --reject, reporting the files it changed and the hunks it rejected
+func (e *Executor) executePatch(ctx context.Context, action models.PatchAction) (interface{}, error) {
+	ctx, span := e.tracer.Start(ctx, "patch")
+	defer span.End()
+
+	if strings.TrimSpace(action.Patch) == "" {
+		return models.NewErrorObservation("Patch must not be empty", "InvalidPatch"), nil
+	}
+
+	dir := e.workingDir
+	if action.Path != "" {
+		if err := e.SecurityCheck(action.Path); err != nil {
+			return models.NewErrorObservation(fmt.Sprintf("Security error: %v", err), "SecurityError"), nil
+		}
+		dir = e.resolvePath(action.Path)
+	}
+	span.SetAttributes(attribute.String("path", dir))
+
+	strip := patchStrip(action.Patch)
+	if action.Strip != nil {
+		strip = *action.Strip
+	}
+
+	// Hold the files like the other edits do, backing them up first when server.backup_on_edit is set
+	files := patchFiles(action.Patch, strip)
+	for _, file := range files {
+		unlock := e.lockFile(filepath.Join(dir, file))
+		defer unlock()
+	}
+	if e.config.Get().Server.BackupOnEdit {
+		for _, file := range files {
+			path := filepath.Join(dir, file)
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue // Created by the patch
+			}
+			if err := e.backupFile(path, content); err != nil {
+				span.RecordError(err)
+				return models.NewErrorObservation(fmt.Sprintf("Failed to back up %s: %v", path, err), "BackupError"), nil
+			}
+		}
+	}
+
+	// git apply refuses paths outside the directory, so the diff can't escape the workspace
+	cmd := exec.CommandContext(ctx, "git", "apply", "--reject", "--verbose", "--whitespace=nowarn", "-p"+strconv.Itoa(strip), "-")
+	cmd.Dir = dir
+	cmd.Env = gitApplyEnv(dir)
+	cmd.Stdin = strings.NewReader(action.Patch)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == gitApplyInvalidPatch:
+		return models.NewErrorObservation(fmt.Sprintf("Invalid patch: %s", strings.TrimSpace(stderr.String())), "InvalidPatch"), nil
+	case err != nil && exitErr == nil:
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to run git apply: %v", err), "PatchError"), nil
+	}
+
+	changed, rejected, skipped := parseGitApplyOutput(stderr.String())
+	var rejectedHunks []models.RejectedHunks
+	for _, path := range rejected {
+		// git apply leaves rejected hunks in a .rej file next to the file, which isn't wanted in the workspace
+		rejFile := filepath.Join(dir, path+".rej")
+		hunks, err := os.ReadFile(rejFile)
+		if err != nil {
+			e.logger.Warnf("Failed to read rejected hunks of %s: %v", path, err)
+		}
+		_ = os.Remove(rejFile)
+		rejectedHunks = append(rejectedHunks, models.RejectedHunks{Path: path, Hunks: string(hunks)})
+	}
+	span.SetAttributes(attribute.Int("files.changed", len(changed)), attribute.Int("files.rejected", len(rejected)))
+	e.logger.Infof("Applied patch in %s: %d files changed, %d with rejected hunks", dir, len(changed), len(rejected))
+
+	if len(skipped) > 0 {
+		return models.NewErrorObservation(fmt.Sprintf("git apply ignored the patches of %s\n%s",
+			strings.Join(skipped, ", "), patchSummary(changed, rejectedHunks)), "PatchError"), nil
+	}
+	return models.NewPatchObservation(patchSummary(changed, rejectedHunks), e.observationPath(dir), changed, rejectedHunks), nil
+}
+
+// gitApplyEnv returns the environment of git apply in dir. Without a repository above dir to be
+// found, the diff's paths are relative to dir rather than to the root of the repository's work
+// tree, and git apply doesn't skip those outside dir.
+func gitApplyEnv(dir string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if name != "GIT_DIR" && name != "GIT_WORK_TREE" && name != "GIT_CEILING_DIRECTORIES" {
+			env = append(env, kv)
+		}
+	}
+	return append(env, "GIT_CEILING_DIRECTORIES="+filepath.Dir(dir))
+}
+
+// patchFiles returns the paths, relative to the directory it applies in, of the files a diff touches
+// in that directory, sorted so that they are always locked in the same order
+func patchFiles(patch string, strip int) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, match := range unifiedDiffFile.FindAllStringSubmatch(patch, -1) {
+		if match[1] == "/dev/null" {
+			continue
+		}
+		components := strings.Split(match[1], "/")
+		if len(components) <= strip {
+			continue
+		}
+		file := filepath.Clean(filepath.Join(components[strip:]...))
+		if file == ".." || strings.HasPrefix(file, ".."+string(filepath.Separator)) {
+			continue // Refused by git apply
+		}
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// patchStrip guesses the number of leading path components to strip from a diff's paths: one when
+// all new file paths have the b/ prefix of git diff, and none otherwise
+func patchStrip(patch string) int {
+	matches := unifiedDiffNewFile.FindAllStringSubmatch(patch, -1)
+	for _, match := range matches {
+		if match[1] != "/dev/null" && !strings.HasPrefix(match[1], "b/") {
+			return 0
+		}
+	}
+	return 1
+}
+
+// parseGitApplyOutput returns the files git apply --verbose changed, those it rejected hunks of and
+// those it skipped
+func parseGitApplyOutput(output string) (changed, rejected, skipped []string) {
+	var current string // File with rejects whose hunks are being reported
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := gitAppliedCleanly.FindStringSubmatch(line); match != nil {
+			changed = append(changed, match[1])
+			current = ""
+		} else if match := gitAppliedRejects.FindStringSubmatch(line); match != nil {
+			rejected = append(rejected, match[1])
+			current = match[1]
+		} else if match := gitSkippedPatch.FindStringSubmatch(line); match != nil {
+			skipped = append(skipped, match[1])
+			current = ""
+		} else if current != "" && gitHunkApplied.MatchString(line) {
+			// Files are partly changed when some of their hunks apply
+			if len(changed) == 0 || changed[len(changed)-1] != current {
+				changed = append(changed, current)
+			}
+		} else if !gitHunkRejected.MatchString(line) {
+			current = ""
+		}
+	}
+	return changed, rejected, skipped
+}
+
+// patchSummary describes the outcome of a patch, with the rejected hunks in full
+func patchSummary(changed []string, rejected []models.RejectedHunks) string {
+	var summary strings.Builder
+	if len(changed) == 0 {
+		summary.WriteString("No files were changed.\n")
+	} else {
+		fmt.Fprintf(&summary, "Changed %d files: %s\n", len(changed), strings.Join(changed, ", "))
+	}
+	for _, file := range rejected {
+		fmt.Fprintf(&summary, "\nRejected hunks of %s, which don't match its current content:\n%s", file.Path, file.Hunks)
+	}
+	return summary.String()
+}
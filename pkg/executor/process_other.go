@@ -0,0 +1,21 @@
+//go:build !unix
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroupOnCancel is only supported on Unix, elsewhere only the shell itself is killed
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
+
+// signalProcessGroup is only supported on Unix, elsewhere only the shell itself is signalled
+func signalProcessGroup(pid int, sig syscall.Signal) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(sig)
+}
@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+)
+
+// ActionHandler executes a parsed action and returns its observation
+type ActionHandler func(ctx context.Context, action interface{}) (interface{}, error)
+
+// registeredAction is an action type an executor knows how to parse and execute
+type registeredAction struct {
+	parse  models.ActionParser
+	handle ActionHandler
+}
+
+// RegisterAction registers the handler for an action type on the executor.
+// The action's parameters are unmarshalled into A before being passed to handler; other
+// executors don't know the action type. Registering an existing action type replaces it.
+func RegisterAction[A any](e *Executor, actionType string, handler func(ctx context.Context, action A) (interface{}, error)) {
+	e.actionsMu.Lock()
+	defer e.actionsMu.Unlock()
+	e.actions[actionType] = registeredAction{
+		parse: models.ParserFor[A](),
+		handle: func(ctx context.Context, action interface{}) (interface{}, error) {
+			typed, ok := action.(A)
+			if !ok {
+				return nil, fmt.Errorf("action %q was parsed as %T, expected %T", actionType, action, typed)
+			}
+			return handler(ctx, typed)
+		},
+	}
+}
+
+// actionHandler returns the handler registered for an action type
+func (e *Executor) actionHandler(actionType string) (ActionHandler, bool) {
+	e.actionsMu.RLock()
+	defer e.actionsMu.RUnlock()
+	action, ok := e.actions[actionType]
+	return action.handle, ok
+}
+
+// actionParser returns the parser registered for an action type, see models.ActionParsers
+func (e *Executor) actionParser(actionType string) (models.ActionParser, bool) {
+	e.actionsMu.RLock()
+	defer e.actionsMu.RUnlock()
+	action, ok := e.actions[actionType]
+	return action.parse, ok
+}
+
+// ParseAction parses an action map into the type registered for its action type on this executor,
+// also returning the args that were left out, see models.ParseActionLenient
+func (e *Executor) ParseAction(actionMap map[string]interface{}) (action interface{}, unparsed map[string]interface{}, err error) {
+	return models.ParseActionLenient(actionMap, e.actionParser)
+}
+
+// registerBuiltinActions registers the handlers of the actions the runtime supports out of the box
+func (e *Executor) registerBuiltinActions() {
+	RegisterAction(e, "run", func(ctx context.Context, action models.CmdRunAction) (interface{}, error) {
+		observation, err := e.executeCmdRun(ctx, action)
+		if err != nil {
+			return nil, err
+		}
+		return e.errorOnNonzeroExit(observation), nil
+	})
+	RegisterAction(e, "read", e.executeFileRead)
+	RegisterAction(e, "write", e.executeFileWrite)
+	RegisterAction(e, "edit", e.executeFileEdit)
+	RegisterAction(e, "patch_json", e.executeFilePatch)
+	RegisterAction(e, "patch", e.executePatch)
+	RegisterAction(e, "list_files", e.executeListFiles)
+	RegisterAction(e, "search", e.executeSearchFiles)
+	RegisterAction(e, "grep", e.executeGrep)
+	RegisterAction(e, "run_ipython", e.executeIPython)
+	RegisterAction(e, "browse", e.executeBrowseURL)
+	RegisterAction(e, "browse_interactive", e.executeBrowseInteractive)
+	RegisterAction(e, "think", e.executeThink)
+	RegisterAction(e, "recall", e.executeRecall)
+
+	for _, actionType := range noOpActionTypes {
+		RegisterAction(e, actionType, e.executeNoOp)
+	}
+}
+
+// noOpActionTypes are OpenHands action types that have no effect on the runtime
+var noOpActionTypes = []string{"null", "message", "finish", "reject"}
+
+// executeNoOp answers an action that has no effect on the runtime with a null observation echoing it
+func (e *Executor) executeNoOp(ctx context.Context, action map[string]interface{}) (interface{}, error) {
+	e.logger.Debugf("Acknowledging no-op action %v", action["action"])
+	return models.NewNullObservation("", action), nil
+}
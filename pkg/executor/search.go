@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+)
+
+// defaultSearchMaxResults caps the matches of a search that doesn't set max_results
+const defaultSearchMaxResults = 100
+
+// errSearchLimit stops the walk once a search has collected its maximum number of matches
+var errSearchLimit = errors.New("search result limit reached")
+
+// executeSearchFiles searches the files under a directory by name or by content
+func (e *Executor) executeSearchFiles(ctx context.Context, action models.SearchFilesAction) (interface{}, error) {
+	_, span := e.tracer.Start(ctx, "search_files")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("query", action.Query),
+		attribute.String("path", action.Path),
+		attribute.Bool("content", action.Content),
+	)
+
+	if action.Query == "" {
+		return models.NewErrorObservation("Search query must not be empty", "FileSearchError"), nil
+	}
+	if err := e.SecurityCheck(action.Path); err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Security error: %v", err), "SecurityError"), nil
+	}
+
+	maxResults := action.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultSearchMaxResults
+	}
+
+	match := matchFileName(action.Query)
+	if action.Content {
+		match = e.matchFileContent(action.Query)
+	}
+
+	var matches []models.SearchMatch
+	truncated := false
+	root := e.resolvePath(action.Path)
+	var ignore *gitignoreMatcher
+	if action.RespectGitignore {
+		ignore = newGitignoreMatcher(root)
+	}
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && (entry.Name() == ".git" || (ignore != nil && ignore.ignored(path, true))) {
+				return filepath.SkipDir
+			}
+			if ignore != nil {
+				ignore.loadDir(path)
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || (ignore != nil && ignore.ignored(path, false)) {
+			return nil
+		}
+
+		for _, m := range match(path) {
+			if len(matches) == maxResults {
+				truncated = true
+				return errSearchLimit
+			}
+			m.Path = e.toRelativePath(path)
+			matches = append(matches, m)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSearchLimit) {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Cannot search %s: %v", action.Path, err), "FileSearchError"), nil
+	}
+	if matches == nil {
+		matches = []models.SearchMatch{}
+	}
+
+	var content strings.Builder
+	for _, m := range matches {
+		if action.Content {
+			fmt.Fprintf(&content, "%s:%d: %s\n", m.Path, m.Line, m.Text)
+		} else {
+			fmt.Fprintf(&content, "%s\n", m.Path)
+		}
+	}
+	if truncated {
+		fmt.Fprintf(&content, "[Results truncated at %d matches]\n", maxResults)
+	}
+
+	return models.NewSearchObservation(content.String(), action.Query, e.observationPath(action.Path), matches, truncated), nil
+}
+
+// matchFileName returns a matcher of file names against query, as a glob when it contains
+// glob metacharacters and as a case-insensitive substring otherwise
+func matchFileName(query string) func(path string) []models.SearchMatch {
+	isGlob := strings.ContainsAny(query, "*?[")
+	lowerQuery := strings.ToLower(query)
+
+	return func(path string) []models.SearchMatch {
+		name := filepath.Base(path)
+		var matched bool
+		if isGlob {
+			matched, _ = filepath.Match(query, name)
+		} else {
+			matched = strings.Contains(strings.ToLower(name), lowerQuery)
+		}
+		if !matched {
+			return nil
+		}
+		return []models.SearchMatch{{}}
+	}
+}
+
+// matchFileContent returns a matcher of the lines of text files containing query.
+// Binary and unreadable files never match.
+func (e *Executor) matchFileContent(query string) func(path string) []models.SearchMatch {
+	needle := []byte(query)
+
+	return func(path string) []models.SearchMatch {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			e.logger.Debugf("Skipping unreadable file %s in search: %v", path, err)
+			return nil
+		}
+		if isChunkPotentiallyBinary(data, min(len(data), 1024)) {
+			return nil
+		}
+
+		var matches []models.SearchMatch
+		for i, line := range bytes.Split(data, []byte("\n")) {
+			if bytes.Contains(line, needle) {
+				matches = append(matches, models.SearchMatch{
+					Line: i + 1,
+					Text: string(bytes.TrimRight(line, "\r")),
+				})
+			}
+		}
+		return matches
+	}
+}
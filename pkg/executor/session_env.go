@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// envFileEnv names the environment variable holding the file the shell writes its final environment to
+const envFileEnv = "OPENHANDS_ENV_FILE"
+
+// RedactedValue replaces the values of environment variables that look like secrets
+const RedactedValue = "[REDACTED]"
+
+// volatileEnv are variables the shell maintains itself, which are not carried over between commands
+var volatileEnv = map[string]bool{
+	cwdFileEnv: true,
+	envFileEnv: true,
+	"PWD":      true,
+	"OLDPWD":   true,
+	"SHLVL":    true,
+	"_":        true,
+}
+
+// sessionEnvironment returns the environment commands start with: the variables exported by
+// earlier commands, or just PATH and HOME before any command has run
+func (e *Executor) sessionEnvironment() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.sessionEnv == nil {
+		return []string{
+			fmt.Sprintf("PATH=%s", os.Getenv("PATH")),
+			fmt.Sprintf("HOME=%s", os.Getenv("HOME")),
+		}
+	}
+	return append([]string(nil), e.sessionEnv...)
+}
+
+// updateSessionEnvironment records the NUL-separated environment a command exited with.
+// Empty output means the shell never reported it (e.g. it was killed), so the session keeps its environment.
+func (e *Executor) updateSessionEnvironment(output []byte) {
+	env := parseEnv(output)
+	if len(env) == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sessionEnv = env
+}
+
+// setSessionVariables sets NAME=value variables in the session environment, replacing earlier values
+func (e *Executor) setSessionVariables(vars []string) {
+	env := e.sessionEnvironment()
+	for _, entry := range vars {
+		name, _, _ := strings.Cut(entry, "=")
+		replaced := false
+		for i, existing := range env {
+			if strings.HasPrefix(existing, name+"=") {
+				env[i] = entry
+				replaced = true
+			}
+		}
+		if !replaced {
+			env = append(env, entry)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sessionEnv = env
+}
+
+// parseEnv splits NUL-separated NAME=value pairs, as printed by env -0, dropping volatile variables
+func parseEnv(output []byte) []string {
+	var env []string
+	for _, entry := range bytes.Split(output, []byte{0}) {
+		name, _, ok := strings.Cut(string(entry), "=")
+		if !ok || name == "" || volatileEnv[name] {
+			continue
+		}
+		env = append(env, string(entry))
+	}
+	return env
+}
+
+// SessionEnv runs env in the command session and returns the variables it sees.
+// Values of variables whose names match server.env_redact_patterns are redacted.
+func (e *Executor) SessionEnv(ctx context.Context) (map[string]string, error) {
+	cmd := e.shellCommand(ctx, "env -0")
+	cmd.Dir = e.workingDir
+	cmd.Env = e.sessionEnvironment()
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run env: %w", err)
+	}
+
+	patterns := e.config.Get().Server.EnvRedactPatterns
+	env := make(map[string]string)
+	for _, entry := range parseEnv(output) {
+		name, value, _ := strings.Cut(entry, "=")
+		if IsSecretName(name, patterns) {
+			value = RedactedValue
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// IsSecretName reports whether a variable name matches any of the glob patterns, ignoring case
+func IsSecretName(name string, patterns []string) bool {
+	name = strings.ToUpper(name)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToUpper(pattern), name); matched {
+			return true
+		}
+	}
+	return false
+}
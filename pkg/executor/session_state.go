@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// defaultSessionStateFile is the session state file in the state directory when server.session_state_path is unset
+const defaultSessionStateFile = "session.json"
+
+// maxSessionHistory bounds the number of commands kept in the session history
+const maxSessionHistory = 1000
+
+// sessionState is the part of the command session that survives a restart when server.persist_session_state is set
+type sessionState struct {
+	Cwd     string   `json:"cwd,omitempty"`
+	Env     []string `json:"env,omitempty"` // NAME=value pairs exported by earlier commands
+	History []string `json:"history,omitempty"`
+}
+
+// sessionCwd returns the directory the last command finished in, or the working directory before any command ran.
+// Commands without a cwd of their own start there, so a cd carries over to later commands and resumed sessions.
+func (e *Executor) sessionCwd() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.cwd == "" {
+		return e.workingDir
+	}
+	return e.cwd
+}
+
+// recordCommand updates the session after a command finished in cwd. An empty cwd leaves the
+// session's directory unchanged.
+func (e *Executor) recordCommand(command, cwd string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if cwd != "" {
+		e.cwd = cwd
+	}
+	e.history = append(e.history, command)
+	if len(e.history) > maxSessionHistory {
+		e.history = e.history[len(e.history)-maxSessionHistory:]
+	}
+}
+
+// sessionStatePath returns the file the session state is persisted to
+func (e *Executor) sessionStatePath() string {
+	if path := e.config.Get().Server.SessionStatePath; path != "" {
+		return path
+	}
+	return filepath.Join(e.stateDir(), defaultSessionStateFile)
+}
+
+// stateDir returns the directory of the files the runtime keeps about a working directory. It is
+// outside the workspace, where these files could be read through downloads, searches or the file
+// viewer, under the user configuration directory or the temporary directory if there is none.
+func (e *Executor) stateDir() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(e.workingDir))
+	return filepath.Join(base, "openhands-runtime", hex.EncodeToString(sum[:8]))
+}
+
+// saveSessionState writes the cwd, exported variables and command history of the session to the state file
+func (e *Executor) saveSessionState() error {
+	cwd := e.sessionCwd()
+
+	e.mu.RLock()
+	state := sessionState{
+		Cwd:     cwd,
+		Env:     e.sessionEnv,
+		History: e.history,
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	e.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode session state: %w", err)
+	}
+
+	path := e.sessionStatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create session state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	e.logger.Infof("Saved session state to %s", path)
+	return nil
+}
+
+// loadSessionState restores the session saved by saveSessionState. A missing state file is not an error.
+func (e *Executor) loadSessionState() error {
+	path := e.sessionStatePath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read session state: %w", err)
+	}
+
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode session state %s: %w", path, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// The saved directory may be gone if the workspace changed in between
+	if info, err := os.Stat(state.Cwd); err == nil && info.IsDir() {
+		e.cwd = state.Cwd
+	}
+	if len(state.Env) > 0 {
+		e.sessionEnv = state.Env
+	}
+	e.history = state.History
+	e.logger.Infof("Restored session state from %s", path)
+	return nil
+}
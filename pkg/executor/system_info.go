@@ -1,17 +1,30 @@
 package executor
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"os/exec"
+	"strings"
+	"time"
 
+	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/shirou/gopsutil/v4/process"
 
 	"github.com/denysvitali/openhands-runtime-go/internal/models"
 )
 
+// serviceProbeTimeout bounds the check whether a service accepts connections
+const serviceProbeTimeout = 200 * time.Millisecond
+
 // GetServerInfo returns server information
 func (e *Executor) GetServerInfo() models.ServerInfo {
+	vscodeURL, jupyterURL := e.serviceURLs()
+
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
@@ -20,14 +33,161 @@ func (e *Executor) GetServerInfo() models.ServerInfo {
 		StartTime:     e.startTime,
 		LastExecTime:  e.lastExecTime,
 		WorkingDir:    e.workingDir,
-		Plugins:       e.config.Server.Plugins,
+		Plugins:       e.config.Get().Server.Plugins,
 		Username:      e.username,
 		UserID:        e.userID,
-		FileViewerURL: fmt.Sprintf("http://localhost:%d", e.config.Server.FileViewerPort),
+		FileViewerURL: e.fileViewerURL,
+		VSCodeURL:     vscodeURL,
+		JupyterURL:    jupyterURL,
 		SystemStats:   e.GetSystemStats(),
 	}
 }
 
+// serviceURLs returns the URLs of the VSCode and Jupyter servers whose plugins are enabled, if they
+// accept connections on their configured port. The runtime doesn't start them itself.
+func (e *Executor) serviceURLs() (vscodeURL, jupyterURL string) {
+	cfg := e.config.Get()
+	if e.hasPlugin("vscode") && serviceListening(cfg.Server.VSCodePort) {
+		vscodeURL = fmt.Sprintf("http://localhost:%d/?folder=%s", cfg.Server.VSCodePort, url.QueryEscape(e.workingDir))
+		if token := e.VSCodeToken(); token != "" {
+			vscodeURL += "&tkn=" + url.QueryEscape(token)
+		}
+	}
+	if e.hasPlugin("jupyter") && serviceListening(cfg.Server.JupyterPort) {
+		jupyterURL = fmt.Sprintf("http://localhost:%d", cfg.Server.JupyterPort)
+	}
+	return vscodeURL, jupyterURL
+}
+
+// serviceListening reports whether something accepts connections on a local port
+func serviceListening(port int) bool {
+	if port <= 0 {
+		return false
+	}
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", port), serviceProbeTimeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// hasPlugin reports whether the named plugin is enabled
+func (e *Executor) hasPlugin(name string) bool {
+	for _, plugin := range e.config.Get().Server.Plugins {
+		if plugin == name {
+			return true
+		}
+	}
+	return false
+}
+
+// VSCodeToken returns the connection token of the VSCode server, server.vscode_connection_token
+func (e *Executor) VSCodeToken() string {
+	return e.config.Get().Server.VSCodeConnectionToken
+}
+
+// SetFileViewerURL records the URL of the running file viewer
+func (e *Executor) SetFileViewerURL(fileViewerURL string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fileViewerURL = fileViewerURL
+}
+
+// CheckDiskSpace returns an error when the free space available to the workspace
+// is below server.min_free_disk_bytes
+func (e *Executor) CheckDiskSpace() error {
+	minFree := e.config.Get().Server.MinFreeDiskBytes
+	if minFree == 0 {
+		return nil
+	}
+
+	usage, err := e.diskUsage(e.workingDir)
+	if err != nil {
+		return fmt.Errorf("failed to get disk usage: %w", err)
+	}
+	if usage.Free < minFree {
+		return fmt.Errorf("low disk space: %d bytes free, %d required", usage.Free, minFree)
+	}
+	return nil
+}
+
+// readinessShellTimeout bounds how long the shell may take to run a no-op command in the readiness checks
+const readinessShellTimeout = 5 * time.Second
+
+// readinessCacheTTL is how long readiness results are reused, so that frequent unauthenticated
+// probes don't each spawn a shell and write to the disk
+const readinessCacheTTL = 5 * time.Second
+
+// CheckReadiness runs quick self-tests of what actions depend on: disk space, a writable workspace,
+// a responsive shell and, with the jupyter plugin, a jupyter executable.
+// Results are cached for readinessCacheTTL.
+func (e *Executor) CheckReadiness(ctx context.Context) []models.ReadinessCheck {
+	e.readinessMu.Lock()
+	defer e.readinessMu.Unlock()
+
+	if e.readiness != nil && time.Since(e.readinessAt) < readinessCacheTTL {
+		return e.readiness
+	}
+	results := e.runReadinessChecks(ctx)
+	// Checks cut short by the caller going away say nothing about the runtime
+	if ctx.Err() == nil {
+		e.readiness, e.readinessAt = results, time.Now()
+	}
+	return results
+}
+
+// runReadinessChecks runs the checks reported by CheckReadiness
+func (e *Executor) runReadinessChecks(ctx context.Context) []models.ReadinessCheck {
+	type check struct {
+		name string
+		run  func() error
+	}
+	checks := []check{
+		{"disk_space", e.CheckDiskSpace},
+		{"workspace_writable", e.checkWorkspaceWritable},
+		{"shell", func() error { return e.checkShell(ctx) }},
+	}
+	if e.hasPlugin("jupyter") {
+		checks = append(checks, check{"jupyter", func() error {
+			_, err := exec.LookPath("jupyter")
+			return err
+		}})
+	}
+
+	results := make([]models.ReadinessCheck, 0, len(checks))
+	for _, check := range checks {
+		result := models.ReadinessCheck{Name: check.name, OK: true}
+		if err := check.run(); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkWorkspaceWritable creates and removes a directory under server.temp_dir, which is in the
+// working directory by default, rather than in the working directory itself so that clients
+// watching it aren't notified of the probe
+func (e *Executor) checkWorkspaceWritable() error {
+	dir, err := e.makeTempDir(".openhands-ready-*")
+	if err != nil {
+		return fmt.Errorf("workspace is not writable: %w", err)
+	}
+	return os.Remove(dir)
+}
+
+// checkShell runs a no-op command in the configured shell
+func (e *Executor) checkShell(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, readinessShellTimeout)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, e.shell, "-c", "true").CombinedOutput(); err != nil {
+		return fmt.Errorf("shell %s did not run a command: %w %s", e.shell, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 // GetSystemStats returns system statistics using gopsutil
 func (e *Executor) GetSystemStats() models.SystemStats {
 	pid := int32(os.Getpid())
@@ -60,6 +220,21 @@ func (e *Executor) GetSystemStats() models.SystemStats {
 		cpuPercent = 0.0
 	}
 
+	// With a zero interval gopsutil reports usage since the previous call instead of blocking
+	var hostCPUPercent float64
+	if percents, err := cpu.Percent(0, false); err != nil || len(percents) == 0 {
+		e.logger.Warnf("Failed to get host CPU percent: %v", err)
+	} else {
+		hostCPUPercent = percents[0]
+	}
+
+	var perCPUPercent []float64
+	if e.config.Get().Server.PerCPUStats {
+		if perCPUPercent, err = cpu.Percent(0, true); err != nil {
+			e.logger.Warnf("Failed to get per-core CPU percent: %v", err)
+		}
+	}
+
 	memInfo, err := proc.MemoryInfo()
 	if err != nil {
 		e.logger.Warnf("Failed to get memory info: %v", err)
@@ -72,11 +247,17 @@ func (e *Executor) GetSystemStats() models.SystemStats {
 		memPercent = 0.0
 	}
 
+	hostMem, err := mem.VirtualMemory()
+	if err != nil {
+		e.logger.Warnf("Failed to get host memory: %v", err)
+		hostMem = &mem.VirtualMemoryStat{Total: 0, Available: 0}
+	}
+
 	workingDir := e.workingDir
 	if workingDir == "" {
 		workingDir = "/"
 	}
-	diskUsage, err := disk.Usage(workingDir)
+	diskUsage, err := e.diskUsage(workingDir)
 	if err != nil {
 		e.logger.Warnf("Failed to get disk usage: %v", err)
 		diskUsage = &disk.UsageStat{Total: 0, Used: 0, Free: 0, UsedPercent: 0.0}
@@ -88,12 +269,21 @@ func (e *Executor) GetSystemStats() models.SystemStats {
 		ioCounters = &process.IOCountersStat{ReadBytes: 0, WriteBytes: 0}
 	}
 
+	var readRate, writeRate float64
+	if e.ioSampler != nil {
+		readRate, writeRate = e.ioSampler.Rates()
+	}
+
 	return models.SystemStats{
-		CPUPercent: cpuPercent,
+		CPUPercent:     cpuPercent,
+		HostCPUPercent: hostCPUPercent,
+		PerCPUPercent:  perCPUPercent,
 		Memory: models.MemoryStats{
-			RSS:     memInfo.RSS,
-			VMS:     memInfo.VMS,
-			Percent: memPercent,
+			RSS:       memInfo.RSS,
+			VMS:       memInfo.VMS,
+			Percent:   memPercent,
+			Total:     hostMem.Total,
+			Available: hostMem.Available,
 		},
 		Disk: models.DiskStats{
 			Total:   diskUsage.Total,
@@ -102,8 +292,10 @@ func (e *Executor) GetSystemStats() models.SystemStats {
 			Percent: diskUsage.UsedPercent,
 		},
 		IO: models.IOStats{
-			ReadBytes:  ioCounters.ReadBytes,
-			WriteBytes: ioCounters.WriteBytes,
+			ReadBytes:        ioCounters.ReadBytes,
+			WriteBytes:       ioCounters.WriteBytes,
+			ReadBytesPerSec:  readRate,
+			WriteBytesPerSec: writeRate,
 		},
 	}
 }
@@ -0,0 +1,174 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+)
+
+func TestGetServerInfo_ServiceURLs(t *testing.T) {
+	// Only VSCode is running
+	vscode, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = vscode.Close() }()
+	notListening, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	jupyterPort := notListening.Addr().(*net.TCPAddr).Port
+	require.NoError(t, notListening.Close())
+
+	t.Run("plugins enabled", func(t *testing.T) {
+		cfg := &config.Config{
+			Server: config.ServerConfig{
+				WorkingDir:            t.TempDir(),
+				Username:              "testuser",
+				UserID:                os.Getuid(),
+				Plugins:               []string{"jupyter", "vscode"},
+				VSCodePort:            vscode.Addr().(*net.TCPAddr).Port,
+				VSCodeConnectionToken: "secret-token",
+				JupyterPort:           jupyterPort,
+			},
+		}
+		logger := logrus.New()
+		logger.SetOutput(io.Discard)
+
+		executor, err := New(cfg, logger)
+		require.NoError(t, err)
+
+		info := executor.GetServerInfo()
+		assert.Contains(t, info.VSCodeURL, fmt.Sprintf("http://localhost:%d/?folder=", cfg.Server.VSCodePort))
+		assert.Contains(t, info.VSCodeURL, "&tkn=secret-token")
+		assert.Empty(t, info.JupyterURL, "Jupyter isn't running")
+		assert.Equal(t, "secret-token", executor.VSCodeToken())
+	})
+
+	t.Run("plugins disabled", func(t *testing.T) {
+		executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+			cfg.Server.VSCodePort = vscode.Addr().(*net.TCPAddr).Port
+		})
+
+		info := executor.GetServerInfo()
+		assert.Empty(t, info.VSCodeURL)
+		assert.Empty(t, info.JupyterURL)
+		assert.Empty(t, executor.VSCodeToken())
+	})
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.MinFreeDiskBytes = 1024 * 1024
+	})
+	executor.diskUsage = func(path string) (*disk.UsageStat, error) {
+		return &disk.UsageStat{Path: path, Total: 10 * 1024 * 1024, Free: 512 * 1024}, nil
+	}
+
+	err := executor.CheckDiskSpace()
+	assert.ErrorContains(t, err, "low disk space: 524288 bytes free, 1048576 required")
+
+	executor.diskUsage = func(path string) (*disk.UsageStat, error) {
+		return &disk.UsageStat{Path: path, Total: 10 * 1024 * 1024, Free: 2 * 1024 * 1024}, nil
+	}
+	assert.NoError(t, executor.CheckDiskSpace())
+
+	reloadConfig(t, executor, func(cfg *config.Config) { cfg.Server.MinFreeDiskBytes = 0 })
+	executor.diskUsage = func(path string) (*disk.UsageStat, error) {
+		return &disk.UsageStat{Path: path}, nil
+	}
+	assert.NoError(t, executor.CheckDiskSpace(), "a zero threshold disables the check")
+}
+
+func TestCheckReadiness(t *testing.T) {
+	executor := newTestExecutor(t)
+	ctx := context.Background()
+	allOK := func(checks []models.ReadinessCheck) bool {
+		for _, check := range checks {
+			if !check.OK {
+				return false
+			}
+		}
+		return true
+	}
+
+	require.True(t, allOK(executor.CheckReadiness(ctx)))
+	entries, err := os.ReadDir(executor.workingDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.Equal(t, defaultTempDir, entry.Name(), "the workspace probe should stay in the temp dir")
+	}
+
+	// A broken shell is only noticed once the cached results expire
+	executor.shell = "no-such-shell"
+	assert.True(t, allOK(executor.CheckReadiness(ctx)))
+	executor.readinessAt = time.Now().Add(-readinessCacheTTL)
+	assert.False(t, allOK(executor.CheckReadiness(ctx)))
+}
+
+func TestGetSystemStats_CPU(t *testing.T) {
+	executor := newTestExecutorWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.PerCPUStats = true
+	})
+
+	// Keep a core busy so that both process and host usage are measurable
+	for deadline := time.Now().Add(100 * time.Millisecond); time.Now().Before(deadline); {
+	}
+
+	stats := executor.GetSystemStats()
+	assert.Greater(t, stats.CPUPercent, 0.0, "process CPU percent")
+	assert.Greater(t, stats.HostCPUPercent, 0.0, "host CPU percent")
+	assert.LessOrEqual(t, stats.HostCPUPercent, 100.0)
+	require.NotEmpty(t, stats.PerCPUPercent)
+	for _, percent := range stats.PerCPUPercent {
+		assert.GreaterOrEqual(t, percent, 0.0)
+		assert.LessOrEqual(t, percent, 100.0)
+	}
+
+	assert.Empty(t, newTestExecutor(t).GetSystemStats().PerCPUPercent)
+}
+
+func TestGetSystemStats_IORate(t *testing.T) {
+	executor := newTestExecutor(t)
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	require.NoError(t, err)
+	executor.ioSampler = newIOSampler(proc.IOCounters)
+
+	executor.ioSampler.sample(time.Now())
+	require.NoError(t, os.WriteFile(filepath.Join(executor.workingDir, "io.bin"), make([]byte, 1<<20), 0644))
+	executor.ioSampler.sample(time.Now().Add(time.Second))
+
+	stats := executor.GetSystemStats()
+	assert.Greater(t, stats.IO.WriteBytesPerSec, 0.0)
+	assert.GreaterOrEqual(t, stats.IO.WriteBytes, uint64(1<<20))
+}
+
+func TestIOSampler_StartStop(t *testing.T) {
+	var calls int
+	var mu sync.Mutex
+	sampler := newIOSampler(func() (*process.IOCountersStat, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return &process.IOCountersStat{ReadBytes: uint64(calls) * 1000}, nil
+	})
+
+	sampler.Start(10 * time.Millisecond)
+	require.Eventually(t, func() bool {
+		read, _ := sampler.Rates()
+		return read > 0
+	}, time.Second, 5*time.Millisecond)
+	sampler.Stop()
+	sampler.Stop()
+}
@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ErrTerminalUnsupported is returned by OpenTerminal on platforms without pseudo-terminals
+var ErrTerminalUnsupported = errors.New("interactive terminals are not supported on this platform")
+
+// Terminal is an interactive shell attached to a pseudo-terminal.
+// Reads return the raw terminal output; writes are delivered as keystrokes.
+type Terminal struct {
+	pty *os.File
+	cmd *exec.Cmd
+}
+
+// OpenTerminal starts an interactive shell on a new pseudo-terminal of the given size.
+// The shell starts in the session's directory with the variables exported by earlier commands.
+func (e *Executor) OpenTerminal(cols, rows uint16) (*Terminal, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+	// The shell holds its own copy of the slave side
+	defer func() { _ = slave.Close() }()
+
+	if err := setPTYSize(master, cols, rows); err != nil {
+		_ = master.Close()
+		return nil, fmt.Errorf("failed to set terminal size: %w", err)
+	}
+
+	cmd := exec.Command(e.shell, "-i")
+	cmd.Dir = e.sessionCwd()
+	cmd.Env = append(e.sessionEnvironment(), "TERM=xterm-256color")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	attachControllingTerminal(cmd)
+
+	if err := cmd.Start(); err != nil {
+		_ = master.Close()
+		return nil, fmt.Errorf("failed to start terminal shell: %w", err)
+	}
+	e.logger.Infof("Opened terminal (pid %d)", cmd.Process.Pid)
+
+	return &Terminal{pty: master, cmd: cmd}, nil
+}
+
+// Read reads terminal output. It fails once the shell has exited.
+func (t *Terminal) Read(p []byte) (int, error) {
+	return t.pty.Read(p)
+}
+
+// Write sends input to the terminal
+func (t *Terminal) Write(p []byte) (int, error) {
+	return t.pty.Write(p)
+}
+
+// Resize changes the terminal size, which the shell is notified of with SIGWINCH
+func (t *Terminal) Resize(cols, rows uint16) error {
+	return setPTYSize(t.pty, cols, rows)
+}
+
+// Close hangs up the terminal and waits for the shell to exit
+func (t *Terminal) Close() error {
+	err := t.pty.Close()
+	_ = t.cmd.Process.Kill()
+	_ = t.cmd.Wait()
+	return err
+}
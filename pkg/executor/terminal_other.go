@@ -0,0 +1,21 @@
+//go:build !linux
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+)
+
+// openPTY is only implemented on Linux
+func openPTY() (master, slave *os.File, err error) {
+	return nil, nil, ErrTerminalUnsupported
+}
+
+// setPTYSize is only implemented on Linux
+func setPTYSize(pty *os.File, cols, rows uint16) error {
+	return ErrTerminalUnsupported
+}
+
+// attachControllingTerminal is only implemented on Linux
+func attachControllingTerminal(cmd *exec.Cmd) {}
@@ -2,10 +2,23 @@ package executor
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+// ansiEscapePattern matches ANSI CSI sequences (colors, cursor movement), OSC sequences (window titles),
+// character set selection and the remaining two-byte escapes. Only sequences starting with ESC match,
+// so ordinary brackets in the text are preserved.
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[()][0-9A-Za-z]|\x1b[@-Z\\^_=>]`)
+
+// stripANSI removes ANSI escape sequences from s
+func stripANSI(s string) string {
+	return ansiEscapePattern.ReplaceAllString(s, "")
+}
+
 // resolvePath resolves a path relative to the working directory
 func (e *Executor) resolvePath(path string) string {
 	if filepath.IsAbs(path) {
@@ -14,6 +27,23 @@ func (e *Executor) resolvePath(path string) string {
 	return filepath.Join(e.workingDir, path)
 }
 
+// defaultTempDir is the scratch directory in the working directory when server.temp_dir is unset
+const defaultTempDir = ".openhands_tmp"
+
+// makeTempDir creates a new directory for scratch files under server.temp_dir, which stays off a
+// possibly small system tmpfs by default. The caller must remove it when done.
+func (e *Executor) makeTempDir(pattern string) (string, error) {
+	parent := e.config.Get().Server.TempDir
+	if parent == "" {
+		parent = defaultTempDir
+	}
+	parent = e.resolvePath(parent)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(parent, pattern)
+}
+
 // toRelativePath converts an absolute path to a path relative to the working directory
 func (e *Executor) toRelativePath(path string) string {
 	relPath, err := filepath.Rel(e.workingDir, path)
@@ -23,6 +53,16 @@ func (e *Executor) toRelativePath(path string) string {
 	return relPath
 }
 
+// observationPath converts the path of an action into the form reported in observations:
+// relative to the working directory when inside it, and absolute otherwise
+func (e *Executor) observationPath(path string) string {
+	resolved := filepath.Clean(e.resolvePath(path))
+	if !isWithinDir(e.workingDir, resolved) {
+		return resolved
+	}
+	return e.toRelativePath(resolved)
+}
+
 // SecurityCheck performs security validation on file paths
 func (e *Executor) SecurityCheck(path string) error {
 	// Check for path traversal attacks
@@ -31,7 +71,8 @@ func (e *Executor) SecurityCheck(path string) error {
 	}
 
 	// Check for absolute paths outside workspace
-	if filepath.IsAbs(path) && !strings.HasPrefix(path, e.workingDir) {
+	allowOutside := e.config.Get().Server.AllowAbsolutePaths
+	if !allowOutside && filepath.IsAbs(path) && !isWithinDir(e.workingDir, filepath.Clean(path)) {
 		return fmt.Errorf("access denied: path outside workspace: %s", path)
 	}
 
@@ -43,9 +84,80 @@ func (e *Executor) SecurityCheck(path string) error {
 		}
 	}
 
+	// Check that symlinks don't lead outside the workspace
+	if !allowOutside {
+		resolved, err := resolveSymlinks(e.resolvePath(path))
+		if err != nil {
+			return fmt.Errorf("failed to resolve path %s: %w", path, err)
+		}
+		root, err := resolveSymlinks(e.workingDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve workspace %s: %w", e.workingDir, err)
+		}
+		if !isWithinDir(root, resolved) {
+			return fmt.Errorf("access denied: path resolves outside workspace: %s", path)
+		}
+	}
+
 	return nil
 }
 
+// maxSymlinkDepth bounds symlink resolution to guard against loops
+const maxSymlinkDepth = 40
+
+// resolveSymlinks resolves all symlinks in an absolute path.
+// Trailing components that don't exist yet (e.g. a file about to be created) are
+// appended to their nearest existing ancestor, so only the parent gets resolved.
+func resolveSymlinks(path string) (string, error) {
+	return resolveSymlinksDepth(filepath.Clean(path), 0)
+}
+
+func resolveSymlinksDepth(path string, depth int) (string, error) {
+	if depth > maxSymlinkDepth {
+		return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+	}
+
+	var missing []string
+	current := path
+	for {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		// A dangling symlink still determines where a write would land
+		if info, lstatErr := os.Lstat(current); lstatErr == nil && info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(current)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(current), target)
+			}
+			return resolveSymlinksDepth(filepath.Join(append([]string{target}, missing...)...), depth+1)
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path, nil
+		}
+		missing = append([]string{filepath.Base(current)}, missing...)
+		current = parent
+	}
+}
+
+// isWithinDir reports whether path is dir itself or located below it
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // sanitizeCommand performs basic command sanitization
 func (e *Executor) sanitizeCommand(command string) error {
 	// Check for dangerous command patterns
@@ -68,3 +180,19 @@ func (e *Executor) sanitizeCommand(command string) error {
 
 	return nil
 }
+
+// defaultFileMode is the permissions of files created without an explicit mode
+const defaultFileMode os.FileMode = 0644
+
+// parseFileMode parses the octal permissions of a file to create, like "0755" or "600".
+// An empty mode is defaultFileMode. Like any file creation, the mode is masked by the process umask.
+func parseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return defaultFileMode, nil
+	}
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm > uint64(os.ModePerm) {
+		return 0, fmt.Errorf("invalid file mode %q: expected octal permissions between 0000 and 0777", mode)
+	}
+	return os.FileMode(perm), nil
+}
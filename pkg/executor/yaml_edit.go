@@ -0,0 +1,144 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+)
+
+// yamlEditIndent is the indentation used when writing an edited YAML document back
+const yamlEditIndent = 2
+
+// executeYAMLSet sets the value at a dotted key path in a YAML file, preserving comments and key order
+func (e *Executor) executeYAMLSet(ctx context.Context, path, keyPath string, value interface{}) (interface{}, error) {
+	_, span := e.tracer.Start(ctx, "yaml_set")
+	defer span.End()
+
+	resolvedPath := e.resolvePath(path)
+	defer e.lockFile(resolvedPath)()
+
+	fileInfo, err := os.Stat(resolvedPath)
+	if os.IsNotExist(err) {
+		return models.NewErrorObservation(fmt.Sprintf("File not found: %s", path), "FileEditError"), nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to stat file %s: %v", path, err), "FileEditError"), nil
+	}
+
+	content, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to read file %s: %v", path, err), "FileEditError"), nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("File %s is not valid YAML: %v", path, err), "InvalidYAML"), nil
+	}
+	if doc.Kind == 0 {
+		// Empty file
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Failed to encode value for %s: %v", keyPath, err), "FileEditError"), nil
+	}
+
+	if err := setYAMLPath(doc.Content[0], strings.Split(keyPath, "."), &valueNode); err != nil {
+		return models.NewErrorObservation(fmt.Sprintf("Failed to set %s in %s: %v", keyPath, path, err), "FileEditError"), nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(yamlEditIndent)
+	if err := encoder.Encode(&doc); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to encode YAML for %s: %v", path, err), "FileEditError"), nil
+	}
+	if err := encoder.Close(); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to encode YAML for %s: %v", path, err), "FileEditError"), nil
+	}
+
+	if err := e.backupFile(resolvedPath, content); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to back up %s: %v", path, err), "BackupError"), nil
+	}
+
+	if err := writeFileAtomic(resolvedPath, buf.Bytes(), fileInfo.Mode().Perm()); err != nil {
+		span.RecordError(err)
+		return models.NewErrorObservation(fmt.Sprintf("Failed to write changes to %s: %v", path, err), "FileEditError"), nil
+	}
+
+	oldContent, newContent := string(content), buf.String()
+	e.logger.Infof("Successfully set %s in %s", keyPath, path)
+
+	return models.NewFileEditObservation(
+		e.generateDiff(oldContent, newContent, path),
+		e.observationPath(path),
+		oldContent,
+		newContent,
+		"yaml_set",
+	), nil
+}
+
+// setYAMLPath replaces the node at keys below node with value, creating missing mapping keys.
+// Numeric keys index into sequences. Comments attached to a replaced value are kept.
+func setYAMLPath(node *yaml.Node, keys []string, value *yaml.Node) error {
+	key := keys[0]
+	last := len(keys) == 1
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != key {
+				continue
+			}
+			if last {
+				replaceYAMLValue(node.Content[i+1], value)
+				return nil
+			}
+			return setYAMLPath(node.Content[i+1], keys[1:], value)
+		}
+
+		child := value
+		if !last {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			if err := setYAMLPath(child, keys[1:], value); err != nil {
+				return err
+			}
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		return nil
+
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(node.Content) {
+			return fmt.Errorf("invalid sequence index %q", key)
+		}
+		if last {
+			replaceYAMLValue(node.Content[index], value)
+			return nil
+		}
+		return setYAMLPath(node.Content[index], keys[1:], value)
+
+	default:
+		return fmt.Errorf("cannot set %q below a scalar value", key)
+	}
+}
+
+// replaceYAMLValue overwrites target with value while keeping target's comments
+func replaceYAMLValue(target, value *yaml.Node) {
+	headComment, lineComment, footComment := target.HeadComment, target.LineComment, target.FootComment
+	*target = *value
+	target.HeadComment, target.LineComment, target.FootComment = headComment, lineComment, footComment
+}
@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// maxPrecompressedFileSize is the largest file compressed ahead of time. The compressed data is
+// held in memory until its turn to be written, so larger files are compressed as they are written.
+const maxPrecompressedFileSize = 32 << 20
+
+// zipCompressionLevel matches the level of archive/zip's own Deflate compressor
+const zipCompressionLevel = 5
+
+// flateWriters reuses compressors across files, as archive/zip does for its own
+var flateWriters sync.Pool
+
+// zipEntry is a file, directory or symlink waiting to be written to the archive
+type zipEntry struct {
+	header *zip.FileHeader
+	path   string
+	info   os.FileInfo
+	// compressed receives the file's compressed content when it is compressed ahead of time
+	compressed chan compressedFile
+}
+
+// compressedFile is the Deflate-compressed content of a file
+type compressedFile struct {
+	data []byte
+	crc  uint32
+	size uint64
+	err  error
+}
+
+// zipStreamer writes entries to a zip archive in the order they are added, while compressing the
+// content of up to workers files in parallel. With fewer than two workers, files are compressed
+// one at a time as they are written. Workers beyond GOMAXPROCS add nothing, so they are capped to it.
+type zipStreamer struct {
+	zipWriter *zip.Writer
+	workers   int
+	entries   chan *zipEntry
+	slots     chan struct{} // Bounds the files compressed ahead of time
+	done      chan struct{} // Closed once the writer stops
+	err       error         // Why the writer stopped early; only read after done is closed
+}
+
+// newZipStreamer starts writing the entries added to the streamer to zipWriter
+func newZipStreamer(zipWriter *zip.Writer, workers int) *zipStreamer {
+	workers = min(workers, runtime.GOMAXPROCS(0))
+	z := &zipStreamer{
+		zipWriter: zipWriter,
+		workers:   workers,
+		entries:   make(chan *zipEntry, max(workers, 1)),
+		slots:     make(chan struct{}, max(workers, 1)),
+		done:      make(chan struct{}),
+	}
+	go z.run()
+	return z
+}
+
+// add queues an entry for the file at path, starting its compression if workers are free.
+// It returns the writer's error if writing has failed.
+func (z *zipStreamer) add(header *zip.FileHeader, path string, info os.FileInfo) error {
+	entry := &zipEntry{header: header, path: path, info: info}
+	if z.workers > 1 && info.Mode().IsRegular() && info.Size() <= maxPrecompressedFileSize {
+		select {
+		case z.slots <- struct{}{}:
+		case <-z.done:
+			return z.err
+		}
+		entry.compressed = make(chan compressedFile, 1)
+		go func() { entry.compressed <- compressFile(path) }()
+	}
+
+	select {
+	case z.entries <- entry:
+		return nil
+	case <-z.done:
+		return z.err
+	}
+}
+
+// close waits for the queued entries to be written and returns the first error writing them
+func (z *zipStreamer) close() error {
+	close(z.entries)
+	<-z.done
+	return z.err
+}
+
+// run writes the queued entries in order until the queue is closed or writing fails
+func (z *zipStreamer) run() {
+	defer close(z.done)
+	for entry := range z.entries {
+		if err := z.write(entry); err != nil {
+			z.err = err
+			return
+		}
+	}
+}
+
+// write adds an entry to the archive
+func (z *zipStreamer) write(entry *zipEntry) error {
+	switch {
+	case entry.info.IsDir():
+		_, err := z.zipWriter.CreateHeader(entry.header)
+		return err
+
+	case entry.info.Mode()&os.ModeSymlink != 0:
+		// Store symlinks as links rather than copies of their targets
+		return writeZipSymlink(z.zipWriter, entry.header, entry.path)
+
+	case entry.compressed != nil:
+		compressed := <-entry.compressed
+		<-z.slots
+		if compressed.err != nil {
+			return compressed.err
+		}
+		entry.header.Method = zip.Deflate
+		entry.header.CRC32 = compressed.crc
+		entry.header.CompressedSize64 = uint64(len(compressed.data))
+		entry.header.UncompressedSize64 = compressed.size
+		w, err := z.zipWriter.CreateRaw(entry.header)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(compressed.data)
+		return err
+
+	default:
+		w, err := z.zipWriter.CreateHeader(entry.header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(entry.path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		_, err = io.Copy(w, file)
+		return err
+	}
+}
+
+// compressFile Deflate-compresses the content of a file, computing its CRC-32 on the way
+func compressFile(path string) compressedFile {
+	file, err := os.Open(path)
+	if err != nil {
+		return compressedFile{err: err}
+	}
+	defer func() { _ = file.Close() }()
+
+	var data bytes.Buffer
+	compressor, ok := flateWriters.Get().(*flate.Writer)
+	if ok {
+		compressor.Reset(&data)
+	} else if compressor, err = flate.NewWriter(&data, zipCompressionLevel); err != nil {
+		return compressedFile{err: err}
+	}
+	defer flateWriters.Put(compressor)
+
+	crc := crc32.NewIEEE()
+	size, err := io.Copy(io.MultiWriter(compressor, crc), file)
+	if err != nil {
+		return compressedFile{err: fmt.Errorf("failed to compress %s: %w", path, err)}
+	}
+	if err := compressor.Close(); err != nil {
+		return compressedFile{err: fmt.Errorf("failed to compress %s: %w", path, err)}
+	}
+	return compressedFile{data: data.Bytes(), crc: crc.Sum32(), size: uint64(size)}
+}
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/sirupsen/logrus"
+)
+
+// cancelledNotificationMethod is the method of the notifications clients send to cancel a request
+const cancelledNotificationMethod = "notifications/cancelled"
+
+// toolCallKeyField is the metadata field through which a tool call's key goes from the hook that
+// sees its request id to the middleware that runs its handler. mcp-go gives neither both.
+const toolCallKeyField = "openhands.tool_call_key"
+
+// errToolCallCancelled is the cause of the cancellation of a tool call cancelled by its client
+var errToolCallCancelled = errors.New("tool call cancelled by the client")
+
+// toolCalls tracks the running tool calls, so that clients can cancel them
+type toolCalls struct {
+	logger  *logrus.Logger
+	mu      sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+}
+
+// newToolCalls creates a tracker for running tool calls
+func newToolCalls(logger *logrus.Logger) *toolCalls {
+	return &toolCalls{
+		logger:  logger,
+		cancels: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// toolCallKey identifies a request by its JSON-RPC id, which is only unique within the client's session
+func toolCallKey(ctx context.Context, id any) string {
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return sessionID + " " + mcp.NewRequestId(id).String()
+}
+
+// beforeCallTool passes the tool call's key to the middleware
+func (c *toolCalls) beforeCallTool(ctx context.Context, id any, request *mcp.CallToolRequest) {
+	if request.Params.Meta == nil {
+		request.Params.Meta = &mcp.Meta{}
+	}
+	if request.Params.Meta.AdditionalFields == nil {
+		request.Params.Meta.AdditionalFields = make(map[string]any)
+	}
+	request.Params.Meta.AdditionalFields[toolCallKeyField] = toolCallKey(ctx, id)
+}
+
+// middleware runs tool handlers with a context cancelled by a cancellation notification for their call
+func (c *toolCalls) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var key string
+		if meta := request.Params.Meta; meta != nil {
+			key, _ = meta.AdditionalFields[toolCallKeyField].(string)
+			delete(meta.AdditionalFields, toolCallKeyField)
+		}
+		if key == "" {
+			return next(ctx, request)
+		}
+
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		c.mu.Lock()
+		c.cancels[key] = cancel
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.cancels, key)
+			c.mu.Unlock()
+		}()
+
+		result, err := next(ctx, request)
+		if cause := context.Cause(ctx); errors.Is(cause, errToolCallCancelled) {
+			return mcp.NewToolResultError(cause.Error()), nil
+		}
+		return result, err
+	}
+}
+
+// handleCancelled cancels the tool call named by a cancellation notification, if it is still running
+func (c *toolCalls) handleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	requestID, ok := notification.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+	key := toolCallKey(ctx, requestID)
+
+	c.mu.Lock()
+	cancel, running := c.cancels[key]
+	c.mu.Unlock()
+	if !running {
+		return
+	}
+
+	cause := errToolCallCancelled
+	if reason, ok := notification.Params.AdditionalFields["reason"].(string); ok && reason != "" {
+		cause = fmt.Errorf("%w: %s", errToolCallCancelled, reason)
+	}
+	c.logger.Infof("Cancelling MCP request %v: %v", requestID, cause)
+	cancel(cause)
+}
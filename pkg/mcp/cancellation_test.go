@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+)
+
+func TestToolCallCancellation(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exec, err := executor.New(&config.Config{
+		Server: config.ServerConfig{WorkingDir: t.TempDir(), Username: "testuser", UserID: os.Getuid()},
+	}, logger)
+	require.NoError(t, err)
+	s := NewServer(logger, exec)
+
+	handle := func(message map[string]interface{}) []byte {
+		request, err := json.Marshal(message)
+		require.NoError(t, err)
+		data, err := json.Marshal(s.mcpServer.HandleMessage(context.Background(), request))
+		require.NoError(t, err)
+		return data
+	}
+
+	start := time.Now()
+	responses := make(chan []byte, 1)
+	go func() {
+		responses <- handle(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      7,
+			"method":  "tools/call",
+			"params":  map[string]interface{}{"name": "cmd_run", "arguments": map[string]interface{}{"command": "sleep 30"}},
+		})
+	}()
+
+	// Cancelling calls that aren't running, including before this one starts, has no effect
+	cancel := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/cancelled",
+		"params":  map[string]interface{}{"requestId": 7, "reason": "user pressed stop"},
+	}
+	require.Eventually(t, func() bool {
+		handle(cancel)
+		select {
+		case data := <-responses:
+			responses <- data
+			return true
+		default:
+			return false
+		}
+	}, 10*time.Second, 100*time.Millisecond)
+
+	var response struct {
+		Result struct {
+			IsError bool `json:"isError"`
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(<-responses, &response))
+	assert.True(t, response.Result.IsError)
+	require.Len(t, response.Result.Content, 1)
+	assert.Equal(t, "tool call cancelled by the client: user pressed stop", response.Result.Content[0].Text)
+	assert.Less(t, time.Since(start), 10*time.Second, "the command must be killed")
+	assert.Empty(t, s.calls.cancels)
+}
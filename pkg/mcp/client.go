@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 	"sync"
 	"time"
@@ -68,6 +69,58 @@ func (m *MCPManager) GetConnection(id string) (*MCPConnection, bool) {
 	return conn, exists
 }
 
+// StartJanitor removes, every interval until ctx is done, the connections that haven't sent a
+// heartbeat for longer than staleAfter, such as those of clients that vanished without closing them
+func (m *MCPManager) StartJanitor(ctx context.Context, interval, staleAfter time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.reapStale(staleAfter)
+			}
+		}
+	}()
+}
+
+// reapStale closes and removes the connections whose last heartbeat is older than staleAfter
+func (m *MCPManager) reapStale(staleAfter time.Duration) {
+	m.mu.RLock()
+	var stale []*MCPConnection
+	for _, conn := range m.connections {
+		if time.Since(conn.lastHeartbeat()) > staleAfter {
+			stale = append(stale, conn)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, conn := range stale {
+		m.logger.Warnf("MCP connection %s sent no heartbeat for over %s", conn.ID, staleAfter)
+		conn.Close()
+		m.RemoveConnection(conn.ID)
+	}
+}
+
+// Broadcast sends a JSON-RPC message to every connection
+func (m *MCPManager) Broadcast(message interface{}) {
+	m.mu.RLock()
+	connections := make([]*MCPConnection, 0, len(m.connections))
+	for _, conn := range m.connections {
+		connections = append(connections, conn)
+	}
+	m.mu.RUnlock()
+
+	for _, conn := range connections {
+		if err := conn.SendMessage(message); err != nil {
+			m.logger.Errorf("Failed to send MCP message to connection %s: %v", conn.ID, err)
+		}
+	}
+}
+
 // SendMessage sends a JSON-RPC message to a specific connection
 func (conn *MCPConnection) SendMessage(message interface{}) error {
 	conn.mu.Lock()
@@ -100,6 +153,13 @@ func (conn *MCPConnection) UpdateHeartbeat() {
 	conn.LastHeartbeat = time.Now()
 }
 
+// lastHeartbeat returns the time of the last heartbeat
+func (conn *MCPConnection) lastHeartbeat() time.Time {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	return conn.LastHeartbeat
+}
+
 // Close marks the connection as closed
 func (conn *MCPConnection) Close() {
 	conn.mu.Lock()
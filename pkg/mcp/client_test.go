@@ -0,0 +1,33 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPManager_Janitor(t *testing.T) {
+	manager := NewMCPManager(logrus.New())
+	stale := manager.AddConnection("stale", nil)
+	live := manager.AddConnection("live", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager.StartJanitor(ctx, 10*time.Millisecond, 200*time.Millisecond)
+
+	// Only the live connection keeps heartbeating
+	require.Eventually(t, func() bool {
+		live.UpdateHeartbeat()
+		_, exists := manager.GetConnection("stale")
+		return !exists
+	}, 5*time.Second, 20*time.Millisecond)
+
+	_, exists := manager.GetConnection("live")
+	assert.True(t, exists)
+	assert.False(t, stale.Connected, "reaped connections must be closed")
+	assert.True(t, live.Connected)
+}
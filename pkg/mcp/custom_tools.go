@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+)
+
+// registerCustomTools registers the extra tools defined in mcp.tools, which run shell commands.
+// Tools clashing with a built-in one are skipped.
+func (s *Server) registerCustomTools() {
+	for _, tool := range s.executor.Config().Get().MCP.Tools {
+		if s.toolNames[tool.Name] {
+			s.logger.Errorf("Not registering MCP tool %s from the configuration: a built-in tool has that name", tool.Name)
+			continue
+		}
+		placeholders, err := tool.Placeholders()
+		if err != nil {
+			// Validated when the configuration is loaded
+			s.logger.Errorf("Not registering MCP tool %s from the configuration: %v", tool.Name, err)
+			continue
+		}
+
+		description := tool.Description
+		if description == "" {
+			description = "Run " + tool.Command
+		}
+		options := []mcp.ToolOption{mcp.WithDescription(description)}
+		for _, name := range placeholders {
+			options = append(options, mcp.WithString(name, mcp.Required(), mcp.Description(tool.Arguments[name])))
+		}
+		s.addTool(mcp.NewTool(tool.Name, options...), s.customToolHandler(tool, placeholders))
+	}
+}
+
+// customToolHandler runs a configured tool's command, with its arguments substituted shell-quoted
+func (s *Server) customToolHandler(tool config.MCPToolConfig, placeholders []string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := make(map[string]string, len(placeholders))
+		for _, name := range placeholders {
+			value, err := request.RequireString(name)
+			if err != nil {
+				return toolInputError("%v", err), nil
+			}
+			arguments[name] = value
+		}
+
+		command := tool.Expand(arguments, shellQuote)
+		if len(command) > maxCommandLength {
+			return toolInputError("command is %d bytes long, the maximum is %d", len(command), maxCommandLength), nil
+		}
+
+		result, err := s.executor.RunCommand(ctx, command)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("command execution failed: %v", err)), nil
+		}
+		return commandResult(command, result.Extras.ExitCode, result.Content), nil
+	}
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
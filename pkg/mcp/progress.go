@@ -0,0 +1,32 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// progressNotificationMethod is the method of the notifications reporting the progress of a request
+const progressNotificationMethod = "notifications/progress"
+
+// runCommandWithProgress runs a command like handleCmdRun, sending the client a progress
+// notification every server.stream_status_interval_seconds while it runs
+func (s *Server) runCommandWithProgress(ctx context.Context, command string, token mcp.ProgressToken) *mcp.CallToolResult {
+	progress := 0
+	result, err := s.executor.RunCommandWithStatus(ctx, command, func(message string) {
+		progress++
+		err := s.mcpServer.SendNotificationToClient(ctx, progressNotificationMethod, map[string]any{
+			"progressToken": token,
+			"progress":      progress,
+			"message":       message,
+		})
+		if err != nil {
+			s.logger.Debugf("Failed to send MCP progress notification: %v", err)
+		}
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("command execution failed: %v", err))
+	}
+	return commandResult(command, result.Extras.ExitCode, result.Content)
+}
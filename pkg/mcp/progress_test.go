@@ -0,0 +1,135 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/internal/models"
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+)
+
+// testSession is a client session collecting the notifications sent to it
+type testSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) Initialize()       {}
+func (s *testSession) Initialized() bool { return true }
+func (s *testSession) SessionID() string { return "test" }
+
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+func TestCmdRun_ProgressNotifications(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exec, err := executor.New(&config.Config{
+		Server: config.ServerConfig{WorkingDir: t.TempDir(), Username: "testuser", UserID: os.Getuid(), StreamStatusIntervalSec: 1},
+	}, logger)
+	require.NoError(t, err)
+	s := NewServer(logger, exec)
+
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	ctx := s.mcpServer.WithContext(context.Background(), session)
+
+	request, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params": map[string]interface{}{
+			"name":      "cmd_run",
+			"arguments": map[string]interface{}{"command": "echo start; sleep 2.5; echo done"},
+			"_meta":     map[string]interface{}{"progressToken": "slow-command"},
+		},
+	})
+	require.NoError(t, err)
+	data, err := json.Marshal(s.mcpServer.HandleMessage(ctx, request))
+	require.NoError(t, err)
+
+	var response struct {
+		Result struct {
+			IsError bool `json:"isError"`
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(data, &response))
+	assert.False(t, response.Result.IsError)
+	require.Len(t, response.Result.Content, 1)
+	assert.Contains(t, response.Result.Content[0].Text, "Exit Code: 0\nOutput:\nstart\ndone\n")
+	assert.NotContains(t, response.Result.Content[0].Text, "still running")
+
+	close(session.notifications)
+	var progress []interface{}
+	for notification := range session.notifications {
+		assert.Equal(t, progressNotificationMethod, notification.Method)
+		assert.Equal(t, "slow-command", notification.Params.AdditionalFields["progressToken"])
+		progress = append(progress, notification.Params.AdditionalFields["progress"])
+	}
+	assert.Equal(t, []interface{}{1, 2}, progress)
+}
+
+func TestCmdRun_ProgressMatchesPlainRun(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	workingDir := t.TempDir()
+	exec, err := executor.New(&config.Config{
+		Server: config.ServerConfig{
+			WorkingDir:                 workingDir,
+			Username:                   "testuser",
+			UserID:                     os.Getuid(),
+			StreamStatusIntervalSec:    1,
+			MaxObservationContentBytes: 200,
+		},
+	}, logger)
+	require.NoError(t, err)
+	s := NewServer(logger, exec)
+
+	// Move the session elsewhere: cmd_run still runs in the working directory
+	_, err = exec.ExecuteAction(context.Background(), map[string]interface{}{
+		"action": "run",
+		"args":   map[string]interface{}{"command": "mkdir -p sub && cd sub"},
+	})
+	require.NoError(t, err)
+
+	run := func(meta map[string]interface{}) map[string]interface{} {
+		session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+		ctx := s.mcpServer.WithContext(context.Background(), session)
+		params := map[string]interface{}{
+			"name":      "cmd_run",
+			"arguments": map[string]interface{}{"command": "pwd; seq 1 100; kill -TERM $$"},
+		}
+		if meta != nil {
+			params["_meta"] = meta
+		}
+		request, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": params})
+		require.NoError(t, err)
+		data, err := json.Marshal(s.mcpServer.HandleMessage(ctx, request))
+		require.NoError(t, err)
+
+		var response struct {
+			Result map[string]interface{} `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(data, &response))
+		require.NotNil(t, response.Result)
+		return response.Result
+	}
+
+	plain := run(nil)
+	assert.Equal(t, plain, run(map[string]interface{}{"progressToken": "token"}))
+	assert.Equal(t, true, plain["isError"])
+	text := plain["content"].([]interface{})[0].(map[string]interface{})["text"].(string)
+	assert.Contains(t, text, workingDir+"\n")
+	assert.Contains(t, text, models.TruncationMarker)
+}
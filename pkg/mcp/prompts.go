@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// promptArgument is an argument of a builtinPrompt. Optional arguments fall back to their default.
+type promptArgument struct {
+	name         string
+	description  string
+	required     bool
+	defaultValue string
+}
+
+// builtinPrompt is a prompt template offered to MCP clients, referring to its arguments as {name}
+type builtinPrompt struct {
+	name        string
+	description string
+	template    string
+	arguments   []promptArgument
+}
+
+// builtinPrompts are the prompts listed by prompts/list
+var builtinPrompts = []builtinPrompt{
+	{
+		name:        "summarize_file",
+		description: "Summarize what a file in the workspace does",
+		template:    "Read the file {path} and summarize what it does, its main components and anything that looks wrong or unusual.",
+		arguments: []promptArgument{
+			{name: "path", description: "Path of the file, relative to the workspace", required: true},
+		},
+	},
+	{
+		name:        "explain_command",
+		description: "Explain a shell command before running it",
+		template:    "Explain what the shell command `{command}` does, step by step, and point out any side effects or risks of running it.",
+		arguments: []promptArgument{
+			{name: "command", description: "The shell command to explain", required: true},
+		},
+	},
+	{
+		name:        "review_changes",
+		description: "Review the uncommitted changes in a git repository",
+		template:    "Review the uncommitted changes in the git repository at {path}, as shown by `git diff HEAD`. Point out bugs, missing tests and unclear code.",
+		arguments: []promptArgument{
+			{name: "path", description: "Path of the repository, relative to the workspace", defaultValue: "."},
+		},
+	},
+}
+
+// registerPrompts offers the built-in prompts to MCP clients
+func (s *Server) registerPrompts() {
+	for _, prompt := range builtinPrompts {
+		options := []mcp.PromptOption{mcp.WithPromptDescription(prompt.description)}
+		for _, argument := range prompt.arguments {
+			argumentOptions := []mcp.ArgumentOption{mcp.ArgumentDescription(argument.description)}
+			if argument.required {
+				argumentOptions = append(argumentOptions, mcp.RequiredArgument())
+			}
+			options = append(options, mcp.WithArgument(argument.name, argumentOptions...))
+		}
+		s.mcpServer.AddPrompt(mcp.NewPrompt(prompt.name, options...), prompt.handle)
+	}
+}
+
+// handle substitutes the request's arguments into the prompt's template
+func (p builtinPrompt) handle(_ context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	var replacements []string
+	for _, argument := range p.arguments {
+		value, ok := request.Params.Arguments[argument.name]
+		if !ok || value == "" {
+			if argument.required {
+				return nil, fmt.Errorf("prompt %s requires the %s argument", p.name, argument.name)
+			}
+			value = argument.defaultValue
+		}
+		replacements = append(replacements, "{"+argument.name+"}", value)
+	}
+
+	text := strings.NewReplacer(replacements...).Replace(p.template)
+	return mcp.NewGetPromptResult(p.description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	}), nil
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+)
+
+func TestPrompts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exec, err := executor.New(&config.Config{
+		Server: config.ServerConfig{WorkingDir: t.TempDir(), Username: "testuser", UserID: os.Getuid()},
+	}, logger)
+	require.NoError(t, err)
+	s := NewServer(logger, exec)
+
+	call := func(t *testing.T, method string, params interface{}) map[string]interface{} {
+		request, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		require.NoError(t, err)
+		data, err := json.Marshal(s.mcpServer.HandleMessage(context.Background(), request))
+		require.NoError(t, err)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &response))
+		return response
+	}
+
+	t.Run("initialize", func(t *testing.T) {
+		result := call(t, "initialize", map[string]interface{}{
+			"protocolVersion": "2024-11-05",
+			"capabilities":    map[string]interface{}{},
+			"clientInfo":      map[string]interface{}{"name": "test", "version": "1.0"},
+		})["result"].(map[string]interface{})
+		assert.Contains(t, result["capabilities"], "prompts")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		result := call(t, "prompts/list", map[string]interface{}{})["result"].(map[string]interface{})
+		var names []string
+		for _, prompt := range result["prompts"].([]interface{}) {
+			names = append(names, prompt.(map[string]interface{})["name"].(string))
+		}
+		assert.ElementsMatch(t, []string{"summarize_file", "explain_command", "review_changes"}, names)
+	})
+
+	promptText := func(t *testing.T, result map[string]interface{}) string {
+		messages := result["messages"].([]interface{})
+		require.Len(t, messages, 1)
+		message := messages[0].(map[string]interface{})
+		assert.Equal(t, "user", message["role"])
+		return message["content"].(map[string]interface{})["text"].(string)
+	}
+
+	t.Run("get", func(t *testing.T) {
+		result := call(t, "prompts/get", map[string]interface{}{
+			"name":      "explain_command",
+			"arguments": map[string]string{"command": "rm -rf build"},
+		})["result"].(map[string]interface{})
+		text := promptText(t, result)
+		assert.Contains(t, text, "`rm -rf build`")
+		assert.NotContains(t, text, "{command}")
+	})
+
+	t.Run("get with default argument", func(t *testing.T) {
+		result := call(t, "prompts/get", map[string]interface{}{"name": "review_changes"})["result"].(map[string]interface{})
+		assert.Contains(t, promptText(t, result), "git repository at .,")
+	})
+
+	t.Run("get without required argument", func(t *testing.T) {
+		response := call(t, "prompts/get", map[string]interface{}{"name": "summarize_file"})
+		assert.NotNil(t, response["error"])
+		assert.Nil(t, response["result"])
+	})
+}
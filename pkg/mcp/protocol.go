@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/denysvitali/openhands-runtime-go/internal/models"
+	"github.com/denysvitali/openhands-runtime-go/pkg/version"
 	"github.com/sirupsen/logrus"
 )
 
@@ -50,11 +51,13 @@ func (h *MCPProtocolHandler) handleInitialize(conn *MCPConnection, message *mode
 	initResult := map[string]interface{}{
 		"protocolVersion": "2024-11-05",
 		"capabilities": map[string]interface{}{
-			"tools": map[string]interface{}{},
+			"tools": map[string]interface{}{
+				"listChanged": true,
+			},
 		},
 		"serverInfo": map[string]interface{}{
 			"name":    "openhands-runtime-go",
-			"version": "1.0.0",
+			"version": version.Version,
 		},
 	}
 
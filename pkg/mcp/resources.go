@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+)
+
+// maxFileResources caps the workspace files resources/list reports
+const maxFileResources = 1000
+
+// fileResourceTemplate matches the file:// URI of any file, so that files can be read whether or
+// not resources/list reported them
+const fileResourceTemplate = "file:///{+path}"
+
+// registerResources exposes workspace files as read-only MCP resources. The listed resources are
+// refreshed from the workspace before each resources/list.
+func (s *Server) registerResources(hooks *server.Hooks) {
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(fileResourceTemplate, "Workspace file",
+			mcp.WithTemplateDescription("A file, read through the runtime's path checks"),
+		),
+		s.handleReadFileResource,
+	)
+
+	hooks.AddBeforeListResources(func(ctx context.Context, _ any, _ *mcp.ListResourcesRequest) {
+		if err := s.syncFileResources(ctx); err != nil {
+			s.logger.Warnf("Failed to list workspace files as MCP resources: %v", err)
+		}
+	})
+}
+
+// syncFileResources registers the files in the workspace, respecting .gitignore, as resources
+// and removes those registered for files that no longer exist
+func (s *Server) syncFileResources(ctx context.Context) error {
+	workingDir := s.executor.GetServerInfo().WorkingDir
+	files, _, err := s.executor.ListFiles(ctx, workingDir, executor.ListOptions{
+		Recursive:        true,
+		MaxResults:       maxFileResources,
+		RespectGitignore: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+
+	current := make(map[string]bool, len(files))
+	var resources []server.ServerResource
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+		uri := fileURI(filepath.Join(workingDir, file.Path))
+		current[uri] = true
+		if !s.fileResources[uri] {
+			resources = append(resources, server.ServerResource{
+				Resource: mcp.NewResource(uri, file.Path, mcp.WithMIMEType(mimeTypeOf(file.Path))),
+				Handler:  s.handleReadFileResource,
+			})
+		}
+	}
+	for uri := range s.fileResources {
+		if !current[uri] {
+			s.mcpServer.RemoveResource(uri)
+		}
+	}
+	if len(resources) > 0 {
+		s.mcpServer.AddResources(resources...)
+	}
+	s.fileResources = current
+	return nil
+}
+
+// handleReadFileResource reads a file:// resource with the executor, which applies the same
+// path checks as file actions. Text files are returned as text, others base64-encoded.
+func (s *Server) handleReadFileResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri, err := url.Parse(request.Params.URI)
+	if err != nil || uri.Scheme != "file" {
+		return nil, fmt.Errorf("unsupported resource URI %q: only file:// is supported", request.Params.URI)
+	}
+
+	path := uri.Path
+	if err := s.executor.SecurityCheck(path); err != nil {
+		return nil, err
+	}
+	content, err := s.executor.DownloadFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if utf8.Valid(content) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: mimeTypeOf(path),
+			Text:     string(content),
+		}}, nil
+	}
+	return []mcp.ResourceContents{mcp.BlobResourceContents{
+		URI:      request.Params.URI,
+		MIMEType: "application/octet-stream",
+		Blob:     base64.StdEncoding.EncodeToString(content),
+	}}, nil
+}
+
+// fileURI returns the file:// URI of an absolute path
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// mimeTypeOf guesses the MIME type of a file from its extension, defaulting to plain text
+func mimeTypeOf(path string) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		mimeType, _, _ = strings.Cut(mimeType, ";")
+		return mimeType
+	}
+	return "text/plain"
+}
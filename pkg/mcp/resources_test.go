@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+)
+
+func TestResources(t *testing.T) {
+	workingDir := t.TempDir()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exec, err := executor.New(&config.Config{
+		Server: config.ServerConfig{WorkingDir: workingDir, Username: "testuser", UserID: os.Getuid(), MaxFileSize: 1 << 20},
+	}, logger)
+	require.NoError(t, err)
+	s := NewServer(logger, exec)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(workingDir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "src", "main.go"), []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "README.md"), []byte("# Project\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, ".gitignore"), []byte("*.log\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "debug.log"), []byte("ignored"), 0644))
+
+	call := func(t *testing.T, method string, params interface{}) map[string]interface{} {
+		request, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		require.NoError(t, err)
+		data, err := json.Marshal(s.mcpServer.HandleMessage(context.Background(), request))
+		require.NoError(t, err)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &response))
+		return response
+	}
+
+	mainURI := fileURI(filepath.Join(workingDir, "src", "main.go"))
+
+	t.Run("list", func(t *testing.T) {
+		result := call(t, "resources/list", map[string]interface{}{})["result"].(map[string]interface{})
+		names := map[string]string{}
+		for _, resource := range result["resources"].([]interface{}) {
+			resource := resource.(map[string]interface{})
+			names[resource["name"].(string)] = resource["uri"].(string)
+		}
+		assert.Equal(t, map[string]string{
+			".gitignore":  fileURI(filepath.Join(workingDir, ".gitignore")),
+			"README.md":   fileURI(filepath.Join(workingDir, "README.md")),
+			"src/main.go": mainURI,
+		}, names)
+
+		// Removed files are no longer listed
+		require.NoError(t, os.Remove(filepath.Join(workingDir, "README.md")))
+		result = call(t, "resources/list", map[string]interface{}{})["result"].(map[string]interface{})
+		assert.Len(t, result["resources"], 2)
+	})
+
+	t.Run("read", func(t *testing.T) {
+		result := call(t, "resources/read", map[string]interface{}{"uri": mainURI})["result"].(map[string]interface{})
+		contents := result["contents"].([]interface{})
+		require.Len(t, contents, 1)
+		assert.Equal(t, "package main\n", contents[0].(map[string]interface{})["text"])
+	})
+
+	t.Run("read outside the workspace", func(t *testing.T) {
+		response := call(t, "resources/read", map[string]interface{}{"uri": "file:///etc/passwd"})
+		assert.NotNil(t, response["error"])
+		assert.Nil(t, response["result"])
+	})
+}
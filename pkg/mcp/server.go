@@ -2,11 +2,11 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,6 +15,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+	"github.com/denysvitali/openhands-runtime-go/pkg/version"
 )
 
 // Server wraps the mcp-go server with OpenHands-specific functionality
@@ -22,26 +23,54 @@ type Server struct {
 	logger    *logrus.Logger
 	executor  *executor.Executor
 	mcpServer *server.MCPServer
+
+	// connections holds the /sse connections, which receive the server's notifications
+	connections      *MCPManager
+	nextConnectionID atomic.Uint64
+
+	// calls tracks the running tool calls, which clients can cancel
+	calls *toolCalls
+
+	// toolNames holds the names of the registered tools
+	toolNames map[string]bool
+
+	// fileResources holds the URIs of the workspace files registered as resources
+	fileResources map[string]bool
+	resourcesMu   sync.Mutex
 }
 
 // NewServer creates a new MCP server using the mcp-go library
 func NewServer(logger *logrus.Logger, exec *executor.Executor) *Server {
-	// Create MCP server with OpenHands tools
+	// Create MCP server with OpenHands tools, the workspace files as resources and built-in prompts
+	hooks := &server.Hooks{}
+	calls := newToolCalls(logger)
+	hooks.AddBeforeCallTool(calls.beforeCallTool)
 	mcpServer := server.NewMCPServer(
 		"openhands-runtime",
-		"1.0.0",
-		server.WithToolCapabilities(false),
+		version.Version,
+		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false),
+		server.WithHooks(hooks),
+		server.WithToolHandlerMiddleware(calls.middleware),
 		server.WithRecovery(),
 	)
 
 	s := &Server{
-		logger:    logger,
-		executor:  exec,
-		mcpServer: mcpServer,
+		logger:      logger,
+		executor:    exec,
+		mcpServer:   mcpServer,
+		connections: NewMCPManager(logger),
+		calls:       calls,
+		toolNames:   make(map[string]bool),
 	}
 
 	// Register OpenHands-specific tools
 	s.registerTools()
+	s.registerCustomTools()
+	s.registerResources(hooks)
+	s.registerPrompts()
+	mcpServer.AddNotificationHandler(cancelledNotificationMethod, calls.handleCancelled)
 
 	return s
 }
@@ -53,44 +82,50 @@ func (s *Server) registerTools() {
 		mcp.WithDescription("Read the contents of a file"),
 		mcp.WithString("path",
 			mcp.Required(),
-			mcp.Description("Path to the file to read"),
+			mcp.Description("Absolute path to the file to read"),
 		),
 	)
-	s.mcpServer.AddTool(fileReadTool, s.handleFileRead)
+	s.addTool(fileReadTool, s.handleFileRead)
 
 	// File write tool
 	fileWriteTool := mcp.NewTool("file_write",
 		mcp.WithDescription("Write content to a file"),
 		mcp.WithString("path",
 			mcp.Required(),
-			mcp.Description("Path to the file to write"),
+			mcp.Description("Absolute path to the file to write"),
 		),
 		mcp.WithString("content",
 			mcp.Required(),
 			mcp.Description("Content to write to the file"),
 		),
 	)
-	s.mcpServer.AddTool(fileWriteTool, s.handleFileWrite)
+	s.addTool(fileWriteTool, s.handleFileWrite)
 
 	// Command execution tool
 	cmdRunTool := mcp.NewTool("cmd_run",
-		mcp.WithDescription("Execute a shell command"),
+		mcp.WithDescription("Execute a shell command. The exit code is also returned as exit_code in the result metadata"),
 		mcp.WithString("command",
 			mcp.Required(),
 			mcp.Description("Command to execute"),
 		),
 	)
-	s.mcpServer.AddTool(cmdRunTool, s.handleCmdRun)
+	s.addTool(cmdRunTool, s.handleCmdRun)
 
 	// List files tool
 	listFilesTool := mcp.NewTool("list_files",
 		mcp.WithDescription("List files in a directory"),
 		mcp.WithString("path",
 			mcp.Required(),
-			mcp.Description("Path to the directory to list"),
+			mcp.Description("Absolute path to the directory to list"),
 		),
 	)
-	s.mcpServer.AddTool(listFilesTool, s.handleListFiles)
+	s.addTool(listFilesTool, s.handleListFiles)
+}
+
+// addTool registers a tool, remembering its name
+func (s *Server) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	s.toolNames[tool.Name] = true
+	s.mcpServer.AddTool(tool, handler)
 }
 
 // HandleSSE handles MCP communication over Server-Sent Events using mcp-go library
@@ -109,17 +144,30 @@ func (s *Server) HandleSSE(c *gin.Context) {
 	// that handles JSON-RPC messages over SSE
 	ctx := c.Request.Context()
 
+	// Writes go through the connection, which serializes them with notifications sent by other requests
+	conn := s.connections.AddConnection(fmt.Sprintf("sse-%d", s.nextConnectionID.Add(1)), c)
+	defer func() {
+		conn.Close()
+		s.connections.RemoveConnection(conn.ID)
+	}()
+
 	// Send initial connection message
-	s.sendSSEMessage(c, map[string]interface{}{
+	s.sendSSEMessage(conn, map[string]interface{}{
 		"jsonrpc": "2.0",
 		"method":  "server/initialized",
 		"params": map[string]interface{}{
 			"server": map[string]interface{}{
 				"name":    "openhands-runtime",
-				"version": "1.0.0",
+				"version": version.Version,
 			},
 			"capabilities": map[string]interface{}{
 				"tools": map[string]interface{}{
+					"listChanged": true,
+				},
+				"resources": map[string]interface{}{
+					"listChanged": false,
+				},
+				"prompts": map[string]interface{}{
 					"listChanged": false,
 				},
 			},
@@ -137,7 +185,7 @@ func (s *Server) HandleSSE(c *gin.Context) {
 			return
 		case <-ticker.C:
 			// Send heartbeat
-			s.sendSSEMessage(c, map[string]interface{}{
+			s.sendSSEMessage(conn, map[string]interface{}{
 				"jsonrpc": "2.0",
 				"method":  "heartbeat",
 				"params": map[string]interface{}{
@@ -149,27 +197,36 @@ func (s *Server) HandleSSE(c *gin.Context) {
 }
 
 // sendSSEMessage sends a JSON-RPC message over SSE
-func (s *Server) sendSSEMessage(c *gin.Context, message interface{}) {
-	data, err := json.Marshal(message)
-	if err != nil {
-		s.logger.Errorf("Failed to marshal MCP message: %v", err)
-		return
+func (s *Server) sendSSEMessage(conn *MCPConnection, message interface{}) {
+	if err := conn.SendMessage(message); err != nil {
+		s.logger.Errorf("Failed to send MCP message: %v", err)
 	}
+}
 
-	// Send as SSE message event with JSON-RPC data
-	c.SSEvent("message", string(data))
-	if flusher, ok := c.Writer.(http.Flusher); ok {
-		flusher.Flush()
-	}
+// NotifyToolsListChanged tells connected MCP clients that the available tools changed,
+// so that they list them again
+func (s *Server) NotifyToolsListChanged() {
+	s.connections.Broadcast(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  mcp.MethodNotificationToolsListChanged,
+	})
+	s.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationToolsListChanged, nil)
 }
 
 // Tool handler methods
 
 // handleFileRead handles file read tool calls
 func (s *Server) handleFileRead(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	pathStr, err := request.RequireString("path")
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("path parameter error: %v", err)), nil
+	pathStr, invalid := s.requirePath(request)
+	if invalid != nil {
+		return invalid, nil
+	}
+	info, invalid := statPath(pathStr, false)
+	if invalid != nil {
+		return invalid, nil
+	}
+	if invalid := s.checkFileSize(pathStr, info.Size()); invalid != nil {
+		return invalid, nil
 	}
 
 	content, err := os.ReadFile(pathStr)
@@ -182,14 +239,20 @@ func (s *Server) handleFileRead(ctx context.Context, request mcp.CallToolRequest
 
 // handleFileWrite handles file write tool calls
 func (s *Server) handleFileWrite(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	pathStr, err := request.RequireString("path")
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("path parameter error: %v", err)), nil
+	pathStr, invalid := s.requirePath(request)
+	if invalid != nil {
+		return invalid, nil
 	}
 
 	content, err := request.RequireString("content")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("content parameter error: %v", err)), nil
+		return toolInputError("%v", err), nil
+	}
+	if invalid := s.checkFileSize(pathStr, int64(len(content))); invalid != nil {
+		return invalid, nil
+	}
+	if info, err := os.Stat(pathStr); err == nil && info.IsDir() {
+		return toolInputError("path is a directory: %s", pathStr), nil
 	}
 
 	// Create directory if it doesn't exist
@@ -208,32 +271,43 @@ func (s *Server) handleFileWrite(ctx context.Context, request mcp.CallToolReques
 
 // handleCmdRun handles command execution tool calls
 func (s *Server) handleCmdRun(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	command, err := request.RequireString("command")
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("command parameter error: %v", err)), nil
+	command, invalid := requireCommand(request)
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	// Clients that pass a progress token hear from long commands while they run
+	if meta := request.Params.Meta; meta != nil && meta.ProgressToken != nil {
+		return s.runCommandWithProgress(ctx, command, meta.ProgressToken), nil
 	}
 
 	// Use the executor to run the command
-	result, err := s.executor.RunCommand(command)
+	result, err := s.executor.RunCommand(ctx, command)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("command execution failed: %v", err)), nil
 	}
+	return commandResult(command, result.Extras.ExitCode, result.Content), nil
+}
 
-	response := fmt.Sprintf("Command: %s\nExit Code: %d\nOutput:\n%s",
-		command, result.Extras.ExitCode, result.Content)
+// commandResult reports a command's output, as an error if it failed. The exit code is also in the
+// result's metadata, so that clients needn't parse it from the text.
+func commandResult(command string, exitCode int, output string) *mcp.CallToolResult {
+	response := fmt.Sprintf("Command: %s\nExit Code: %d\nOutput:\n%s", command, exitCode, output)
 
-	if result.Extras.ExitCode == 0 {
-		return mcp.NewToolResultText(response), nil
-	} else {
-		return mcp.NewToolResultError(response), nil
-	}
+	result := mcp.NewToolResultText(response)
+	result.IsError = exitCode != 0
+	result.Meta = map[string]any{"exit_code": exitCode}
+	return result
 }
 
 // handleListFiles handles file listing tool calls
 func (s *Server) handleListFiles(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	pathStr, err := request.RequireString("path")
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("path parameter error: %v", err)), nil
+	pathStr, invalid := s.requirePath(request)
+	if invalid != nil {
+		return invalid, nil
+	}
+	if _, invalid := statPath(pathStr, true); invalid != nil {
+		return invalid, nil
 	}
 
 	entries, err := os.ReadDir(pathStr)
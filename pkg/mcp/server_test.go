@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+)
+
+func TestCmdRun_ExitCode(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exec, err := executor.New(&config.Config{
+		Server: config.ServerConfig{WorkingDir: t.TempDir(), Username: "testuser", UserID: os.Getuid()},
+	}, logger)
+	require.NoError(t, err)
+	s := NewServer(logger, exec)
+
+	tests := []struct {
+		name     string
+		command  string
+		exitCode int
+	}{
+		{"success", "echo ok", 0},
+		{"failure", "echo failing; exit 3", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := json.Marshal(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"method":  "tools/call",
+				"params":  map[string]interface{}{"name": "cmd_run", "arguments": map[string]interface{}{"command": tt.command}},
+			})
+			require.NoError(t, err)
+			data, err := json.Marshal(s.mcpServer.HandleMessage(context.Background(), request))
+			require.NoError(t, err)
+
+			var response struct {
+				Result struct {
+					Meta    map[string]interface{} `json:"_meta"`
+					IsError bool                   `json:"isError"`
+				} `json:"result"`
+			}
+			require.NoError(t, json.Unmarshal(data, &response))
+			assert.Equal(t, float64(tt.exitCode), response.Result.Meta["exit_code"])
+			assert.Equal(t, tt.exitCode != 0, response.Result.IsError)
+		})
+	}
+}
+
+func TestCustomTools(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exec, err := executor.New(&config.Config{
+		Server: config.ServerConfig{WorkingDir: t.TempDir(), Username: "testuser", UserID: os.Getuid()},
+		MCP: config.MCPConfig{Tools: []config.MCPToolConfig{
+			{
+				Name:        "greet",
+				Description: "Greet someone",
+				Command:     "echo Hello, {{name}}!",
+				Arguments:   map[string]string{"name": "Who to greet"},
+			},
+			{Name: "cmd_run", Command: "echo shadowed"},
+		}},
+	}, logger)
+	require.NoError(t, err)
+	s := NewServer(logger, exec)
+
+	call := func(t *testing.T, method string, params interface{}) json.RawMessage {
+		request, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		require.NoError(t, err)
+		data, err := json.Marshal(s.mcpServer.HandleMessage(context.Background(), request))
+		require.NoError(t, err)
+
+		var response struct {
+			Result json.RawMessage `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(data, &response))
+		return response.Result
+	}
+
+	t.Run("list", func(t *testing.T) {
+		var result struct {
+			Tools []struct {
+				Name        string `json:"name"`
+				Description string `json:"description"`
+				InputSchema struct {
+					Required []string `json:"required"`
+				} `json:"inputSchema"`
+			} `json:"tools"`
+		}
+		require.NoError(t, json.Unmarshal(call(t, "tools/list", map[string]interface{}{}), &result))
+
+		descriptions := map[string]string{}
+		for _, tool := range result.Tools {
+			descriptions[tool.Name] = tool.Description
+			if tool.Name == "greet" {
+				assert.Equal(t, []string{"name"}, tool.InputSchema.Required)
+			}
+		}
+		assert.Equal(t, "Greet someone", descriptions["greet"])
+		assert.NotEqual(t, "Run echo shadowed", descriptions["cmd_run"], "built-in tools can't be replaced")
+	})
+
+	t.Run("call", func(t *testing.T) {
+		var result struct {
+			IsError bool `json:"isError"`
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		}
+		require.NoError(t, json.Unmarshal(call(t, "tools/call", map[string]interface{}{
+			"name":      "greet",
+			"arguments": map[string]interface{}{"name": "O'Brien; exit 1"},
+		}), &result))
+		assert.False(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		// The argument is a single shell word
+		assert.Contains(t, result.Content[0].Text, "Output:\nHello, O'Brien; exit 1!")
+	})
+
+	t.Run("call without argument", func(t *testing.T) {
+		var result struct {
+			IsError bool `json:"isError"`
+		}
+		require.NoError(t, json.Unmarshal(call(t, "tools/call", map[string]interface{}{"name": "greet"}), &result))
+		assert.True(t, result.IsError)
+	})
+}
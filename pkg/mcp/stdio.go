@@ -0,0 +1,21 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"log"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/sirupsen/logrus"
+)
+
+// ServeStdio serves the MCP server to a single client over newline-delimited JSON-RPC on stdin and
+// stdout, as MCP clients that spawn their servers expect. It returns when stdin ends or ctx is done.
+func (s *Server) ServeStdio(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
+	errorLog := s.logger.WriterLevel(logrus.ErrorLevel)
+	defer func() { _ = errorLog.Close() }()
+
+	stdioServer := server.NewStdioServer(s.mcpServer)
+	stdioServer.SetErrorLogger(log.New(errorLog, "", 0))
+	return stdioServer.Listen(ctx, stdin, stdout)
+}
@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+)
+
+func TestServeStdio(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exec, err := executor.New(&config.Config{
+		Server: config.ServerConfig{WorkingDir: t.TempDir(), Username: "testuser", UserID: os.Getuid()},
+	}, logger)
+	require.NoError(t, err)
+	s := NewServer(logger, exec)
+
+	stdin := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+	}, "\n") + "\n"
+	var stdout bytes.Buffer
+	require.NoError(t, s.ServeStdio(context.Background(), strings.NewReader(stdin), &stdout))
+
+	// Each request gets one response line, and the notification none
+	var responses []map[string]interface{}
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &response))
+		responses = append(responses, response)
+	}
+	require.Len(t, responses, 2)
+
+	assert.Equal(t, float64(1), responses[0]["id"])
+	serverInfo := responses[0]["result"].(map[string]interface{})["serverInfo"].(map[string]interface{})
+	assert.Equal(t, "openhands-runtime", serverInfo["name"])
+
+	assert.Equal(t, float64(2), responses[1]["id"])
+	var tools []string
+	for _, tool := range responses[1]["result"].(map[string]interface{})["tools"].([]interface{}) {
+		tools = append(tools, tool.(map[string]interface{})["name"].(string))
+	}
+	assert.ElementsMatch(t, []string{"file_read", "file_write", "cmd_run", "list_files"}, tools)
+}
@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxCommandLength is the longest command cmd_run accepts. The shell gets the command as a single
+// argument, which Linux caps at 128KiB (MAX_ARG_STRLEN).
+const maxCommandLength = 128 * 1024
+
+// toolInputError is the result of a tool call rejected for its arguments
+func toolInputError(format string, args ...interface{}) *mcp.CallToolResult {
+	return mcp.NewToolResultError("invalid input: " + fmt.Sprintf(format, args...))
+}
+
+// requirePath returns a tool call's path argument, which must be an absolute path passing the
+// executor's security check, as on the HTTP path. A nil result means the path is valid.
+func (s *Server) requirePath(request mcp.CallToolRequest) (string, *mcp.CallToolResult) {
+	path, err := request.RequireString("path")
+	if err != nil {
+		return "", toolInputError("%v", err)
+	}
+	if path == "" {
+		return "", toolInputError("path must not be empty")
+	}
+	if !filepath.IsAbs(path) {
+		return "", toolInputError("path must be absolute: %s", path)
+	}
+	if err := s.executor.SecurityCheck(path); err != nil {
+		return "", mcp.NewToolResultError(fmt.Sprintf("security error: %v", err))
+	}
+	return filepath.Clean(path), nil
+}
+
+// requireCommand returns a tool call's command argument, which must be non-blank and no longer than maxCommandLength
+func requireCommand(request mcp.CallToolRequest) (string, *mcp.CallToolResult) {
+	command, err := request.RequireString("command")
+	if err != nil {
+		return "", toolInputError("%v", err)
+	}
+	if strings.TrimSpace(command) == "" {
+		return "", toolInputError("command must not be empty")
+	}
+	if len(command) > maxCommandLength {
+		return "", toolInputError("command is %d bytes long, the maximum is %d", len(command), maxCommandLength)
+	}
+	return command, nil
+}
+
+// checkFileSize rejects file contents larger than server.max_file_size, as the HTTP path does
+func (s *Server) checkFileSize(path string, size int64) *mcp.CallToolResult {
+	if maxSize := s.executor.Config().Get().Server.MaxFileSize; size > maxSize {
+		return toolInputError("%s is %d bytes, which exceeds the maximum file size of %d bytes", path, size, maxSize)
+	}
+	return nil
+}
+
+// statPath returns the file info of a validated path, or an error result naming what is wrong with it
+func statPath(path string, wantDir bool) (os.FileInfo, *mcp.CallToolResult) {
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil, toolInputError("no such file or directory: %s", path)
+	case err != nil:
+		return nil, mcp.NewToolResultError(fmt.Sprintf("cannot access %s: %v", path, err))
+	case wantDir && !info.IsDir():
+		return nil, toolInputError("not a directory: %s", path)
+	case !wantDir && info.IsDir():
+		return nil, toolInputError("path is a directory: %s", path)
+	}
+	return info, nil
+}
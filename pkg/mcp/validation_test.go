@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+)
+
+func TestToolInputValidation(t *testing.T) {
+	workingDir := t.TempDir()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exec, err := executor.New(&config.Config{
+		Server: config.ServerConfig{WorkingDir: workingDir, Username: "testuser", UserID: os.Getuid(), MaxFileSize: 16},
+	}, logger)
+	require.NoError(t, err)
+	s := NewServer(logger, exec)
+
+	largeFile := filepath.Join(workingDir, "large.txt")
+	require.NoError(t, os.WriteFile(largeFile, []byte(strings.Repeat("x", 32)), 0644))
+
+	tests := []struct {
+		name      string
+		tool      string
+		arguments map[string]interface{}
+		want      string
+	}{
+		{"read without path", "file_read", map[string]interface{}{}, `required argument "path" not found`},
+		{"read relative path", "file_read", map[string]interface{}{"path": "notes.txt"}, "path must be absolute: notes.txt"},
+		{"read outside workspace", "file_read", map[string]interface{}{"path": "/etc/passwd"}, "security error"},
+		{"read missing file", "file_read", map[string]interface{}{"path": filepath.Join(workingDir, "missing.txt")}, "no such file or directory"},
+		{"read directory", "file_read", map[string]interface{}{"path": workingDir}, "path is a directory"},
+		{"read too large", "file_read", map[string]interface{}{"path": largeFile}, "exceeds the maximum file size of 16 bytes"},
+		{"write relative path", "file_write", map[string]interface{}{"path": "out.txt", "content": "x"}, "path must be absolute"},
+		{"write without content", "file_write", map[string]interface{}{"path": filepath.Join(workingDir, "out.txt")}, `required argument "content" not found`},
+		{"write too large", "file_write", map[string]interface{}{"path": filepath.Join(workingDir, "out.txt"), "content": strings.Repeat("x", 17)}, "exceeds the maximum file size"},
+		{"write over directory", "file_write", map[string]interface{}{"path": workingDir, "content": "x"}, "path is a directory"},
+		{"run without command", "cmd_run", map[string]interface{}{}, `required argument "command" not found`},
+		{"run blank command", "cmd_run", map[string]interface{}{"command": "  "}, "command must not be empty"},
+		{"run too long command", "cmd_run", map[string]interface{}{"command": "echo " + strings.Repeat("x", maxCommandLength)}, "the maximum is 131072"},
+		{"list relative path", "list_files", map[string]interface{}{"path": "."}, "path must be absolute"},
+		{"list traversal", "list_files", map[string]interface{}{"path": workingDir + "/../.."}, "path traversal detected"},
+		{"list file", "list_files", map[string]interface{}{"path": largeFile}, "not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := json.Marshal(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"method":  "tools/call",
+				"params":  map[string]interface{}{"name": tt.tool, "arguments": tt.arguments},
+			})
+			require.NoError(t, err)
+			data, err := json.Marshal(s.mcpServer.HandleMessage(context.Background(), request))
+			require.NoError(t, err)
+
+			var response struct {
+				Result struct {
+					IsError bool `json:"isError"`
+					Content []struct {
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"result"`
+			}
+			require.NoError(t, json.Unmarshal(data, &response))
+			assert.True(t, response.Result.IsError)
+			require.Len(t, response.Result.Content, 1)
+			assert.Contains(t, response.Result.Content[0].Text, tt.want)
+		})
+	}
+
+	_, err = os.Stat(filepath.Join(workingDir, "out.txt"))
+	assert.True(t, os.IsNotExist(err), "rejected writes must not create the file")
+}
@@ -0,0 +1,94 @@
+// Package redact masks secrets, such as API keys and tokens, in text that leaves the runtime
+// through logs and telemetry.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Mask replaces redacted secrets
+const Mask = "***"
+
+// Redactor masks secrets matched by regular expressions or assigned to fields with secret-looking names
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// New returns a redactor for the given regular expressions and field name globs.
+// When a pattern has capturing groups only the first group is masked, otherwise the whole match is.
+// Field names, matched case-insensitively, are recognized in JSON ("name": "value") and in
+// shell assignments (NAME=value).
+func New(patterns, fieldNames []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	for _, name := range fieldNames {
+		nameRegexp := globToNameRegexp(name)
+		r.patterns = append(r.patterns,
+			regexp.MustCompile(`"(?i:`+nameRegexp+`)"\s*:\s*"((?:[^"\\]|\\.)*)"`),
+			regexp.MustCompile(`\b(?i:`+nameRegexp+`)=("[^"]*"|'[^']*'|[^\s"'\\]+)`),
+		)
+	}
+	return r, nil
+}
+
+// globToNameRegexp converts a field name glob, where * and ? stand for name characters, to a regular expression
+func globToNameRegexp(glob string) string {
+	quoted := regexp.QuoteMeta(glob)
+	quoted = strings.ReplaceAll(quoted, `\*`, `[A-Za-z0-9_-]*`)
+	return strings.ReplaceAll(quoted, `\?`, `[A-Za-z0-9_-]`)
+}
+
+// String masks the secrets in s
+func (r *Redactor) String(s string) string {
+	for _, re := range r.patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllString(s, Mask)
+			continue
+		}
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			// Mask only the first group, whose position is relative to the match here
+			loc := re.FindStringSubmatchIndex(match)
+			if loc == nil || loc[2] < 0 {
+				return match
+			}
+			return match[:loc[2]] + Mask + match[loc[3]:]
+		})
+	}
+	return s
+}
+
+// Hook returns a logrus hook masking secrets in the message and string fields of every log entry
+func (r *Redactor) Hook() logrus.Hook {
+	return hook{r}
+}
+
+// hook masks secrets in log entries before they are formatted
+type hook struct {
+	redactor *Redactor
+}
+
+func (h hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h hook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactor.String(entry.Message)
+
+	for key, value := range entry.Data {
+		if str, ok := value.(string); ok {
+			entry.Data[key] = h.redactor.String(str)
+		}
+	}
+	return nil
+}
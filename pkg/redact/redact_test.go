@@ -0,0 +1,53 @@
+package redact
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactorString(t *testing.T) {
+	redactor, err := New(
+		[]string{`(?i)\bbearer\s+([A-Za-z0-9._~+/-]+=*)`, `\bghp_[A-Za-z0-9]{36,}`},
+		[]string{"*TOKEN*", "password"},
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name, input, want string
+	}{
+		{"shell assignment", "export GITHUB_TOKEN=abc123 && make", "export GITHUB_TOKEN=*** && make"},
+		{"quoted assignment", `PASSWORD="s3cret value" ./run`, `PASSWORD=*** ./run`},
+		{"json field", `{"api_token": "abc\"123", "user": "bob"}`, `{"api_token": "***", "user": "bob"}`},
+		{"assignment inside json", `{"command":"export MY_TOKEN=abc123"}`, `{"command":"export MY_TOKEN=***"}`},
+		{"first group only", "curl -H 'Authorization: Bearer eyJhbGciOi.x-y'", "curl -H 'Authorization: Bearer ***'"},
+		{"whole match", "token ghp_" + "0123456789abcdefghijklmnopqrstuvwxyz", "token ***"},
+		{"nothing secret", "echo hello MONKEY=1", "echo hello MONKEY=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactor.String(tt.input))
+		})
+	}
+
+	_, err = New([]string{"("}, nil)
+	assert.Error(t, err)
+}
+
+func TestHook(t *testing.T) {
+	redactor, err := New(nil, []string{"*TOKEN*"})
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+	logger.AddHook(redactor.Hook())
+
+	logger.WithField("command", "API_TOKEN=abc make").Infof("Executing command: %s", "export API_TOKEN=abc")
+
+	assert.NotContains(t, logs.String(), "abc")
+	assert.Contains(t, logs.String(), "API_TOKEN=***")
+}
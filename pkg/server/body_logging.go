@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
+)
+
+// bodyCapture passes a response through while keeping a copy of it if it is JSON.
+// Other responses, such as zip downloads and event streams, aren't kept.
+type bodyCapture struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyCapture) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyCapture) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodyCapture) capture(data []byte) {
+	if isJSONContent(w.Header().Get("Content-Type")) {
+		w.body.Write(data)
+	}
+}
+
+// bodyLoggingMiddleware logs the JSON request and response bodies of each request when log.log_bodies
+// is enabled. Values of fields whose names match server.env_redact_patterns are redacted, as is the
+// session API key, and bodies are cut at log.max_body_log_bytes. Other bodies are only described.
+func bodyLoggingMiddleware(logger *logrus.Logger, holder *config.Holder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := holder.Get()
+		if !cfg.Log.LogBodies {
+			c.Next()
+			return
+		}
+
+		entry := logger.WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+		})
+
+		if c.Request.Body != nil && c.Request.ContentLength != 0 {
+			if isJSONContent(c.ContentType()) {
+				body, err := io.ReadAll(c.Request.Body)
+				if err != nil {
+					entry.Warnf("Failed to read request body for logging: %v", err)
+				}
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+				entry.Infof("Request body: %s", formatLoggedBody(body, cfg))
+			} else {
+				entry.Infof("Request body: [%d bytes of %s]", c.Request.ContentLength, c.ContentType())
+			}
+		}
+
+		capture := &bodyCapture{ResponseWriter: c.Writer}
+		c.Writer = capture
+		c.Next()
+
+		contentType := capture.Header().Get("Content-Type")
+		switch {
+		case capture.Size() <= 0:
+		case isJSONContent(contentType):
+			entry.Infof("Response body: %s", formatLoggedBody(capture.body.Bytes(), cfg))
+		default:
+			entry.Infof("Response body: [%d bytes of %s]", capture.Size(), contentType)
+		}
+	}
+}
+
+// isJSONContent reports whether a Content-Type header denotes JSON
+func isJSONContent(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.TrimSpace(mediaType) == "application/json"
+}
+
+// formatLoggedBody redacts secrets from a JSON body and cuts it at log.max_body_log_bytes.
+// Only the API key can be redacted from a body that isn't valid JSON.
+func formatLoggedBody(body []byte, cfg *config.Config) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err == nil {
+		if redacted, err := json.Marshal(redactSecrets(value, cfg.Server.EnvRedactPatterns)); err == nil {
+			body = redacted
+		}
+	}
+
+	text := string(body)
+	if key := cfg.Server.SessionAPIKey; key != "" {
+		text = strings.ReplaceAll(text, key, executor.RedactedValue)
+	}
+	if limit := cfg.Log.MaxBodyLogBytes; limit > 0 && len(text) > limit {
+		text = fmt.Sprintf("%s... [truncated at %d bytes]", text[:limit], limit)
+	}
+	return text
+}
+
+// redactSecrets replaces the values of object fields whose names match any of the patterns
+func redactSecrets(value interface{}, patterns []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if executor.IsSecretName(key, patterns) {
+				v[key] = executor.RedactedValue
+			} else {
+				v[key] = redactSecrets(field, patterns)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactSecrets(item, patterns)
+		}
+	}
+	return value
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fileViewer serves the working directory read-only so that OpenHands can render artifacts
+type fileViewer struct {
+	logger   *logrus.Logger
+	listener net.Listener
+	server   *http.Server
+}
+
+// newFileViewer binds the file viewer to the given port (0 picks a free port) on the loopback
+// interface without serving yet. When apiKey is set, requests must carry it like those to the
+// main server. Paths failing check, such as symlinks out of the working directory, are refused.
+func newFileViewer(root string, port int, apiKey string, check func(path string) error, logger *logrus.Logger) (*fileViewer, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on file viewer port %d: %w", port, err)
+	}
+
+	return &fileViewer{
+		logger:   logger,
+		listener: listener,
+		server: &http.Server{
+			Handler: readOnly(requireAPIKey(apiKey, confined(check, http.FileServer(http.Dir(root))))),
+		},
+	}, nil
+}
+
+// Port returns the port the file viewer is listening on
+func (v *fileViewer) Port() int {
+	return v.listener.Addr().(*net.TCPAddr).Port
+}
+
+// URL returns the base URL of the file viewer
+func (v *fileViewer) URL() string {
+	return fmt.Sprintf("http://localhost:%d", v.Port())
+}
+
+// Serve serves requests until the file viewer is shut down
+func (v *fileViewer) Serve() {
+	if err := v.server.Serve(v.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		v.logger.Errorf("File viewer stopped: %v", err)
+	}
+}
+
+// Shutdown gracefully stops the file viewer
+func (v *fileViewer) Shutdown(ctx context.Context) error {
+	return v.server.Shutdown(ctx)
+}
+
+// readOnly rejects every method except GET and HEAD
+func readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAPIKey rejects requests without the session API key, given in the X-Session-API-Key header
+// or the api_key query parameter for browsers. An empty key lets every request through.
+func requireAPIKey(apiKey string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey != "" {
+			key := r.Header.Get("X-Session-API-Key")
+			if key == "" {
+				key = r.URL.Query().Get("api_key")
+			}
+			if key != apiKey {
+				http.Error(w, "invalid API key", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// confined refuses paths for which check fails
+func confined(check func(path string) error, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" {
+			name = "."
+		}
+		if err := check(name); err != nil {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
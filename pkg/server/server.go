@@ -3,12 +3,21 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,12 +25,14 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 
 	"github.com/denysvitali/openhands-runtime-go/internal/models"
 	"github.com/denysvitali/openhands-runtime-go/pkg/config"
 	"github.com/denysvitali/openhands-runtime-go/pkg/executor"
 	"github.com/denysvitali/openhands-runtime-go/pkg/mcp"
 	"github.com/denysvitali/openhands-runtime-go/pkg/telemetry"
+	"github.com/denysvitali/openhands-runtime-go/pkg/version"
 )
 
 // Server represents the HTTP server
@@ -32,6 +43,21 @@ type Server struct {
 	engine    *gin.Engine
 	server    *http.Server
 	mcpServer *mcp.Server
+
+	fileViewer *fileViewer
+
+	// activeConnections tracks the open client connections of server
+	activeConnections metric.Int64UpDownCounter
+
+	// sseConnections counts the open /sse connections, limited by server.max_sse_connections
+	sseConnections atomic.Int64
+
+	// watchedPaths counts the open /watch streams, limited by server.max_watched_paths
+	watchedPaths atomic.Int64
+
+	// shutdownRequested is closed when a graceful shutdown is requested over HTTP
+	shutdownRequested chan struct{}
+	shutdownOnce      sync.Once
 }
 
 // New creates a new server instance
@@ -69,12 +95,28 @@ func New(cfg *config.Config, logger *logrus.Logger) (*Server, error) {
 		engine.Use(authMiddleware(cfg.Server.SessionAPIKey))
 	}
 
+	// Optionally log request and response bodies, which may contain secrets
+	engine.Use(bodyLoggingMiddleware(logger, exec.Config()))
+
+	// Bound requests by the client's X-Timeout-Seconds header
+	engine.Use(requestTimeoutMiddleware())
+
+	activeConnections, err := otel.Meter("openhands-runtime").Int64UpDownCounter("runtime.http.active_connections",
+		metric.WithDescription("Open client connections to the action execution server"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server metrics: %w", err)
+	}
+
 	server := &Server{
-		config:    cfg,
-		logger:    logger,
-		executor:  exec,
-		engine:    engine,
-		mcpServer: mcp.NewServer(logger, exec),
+		config:            cfg,
+		logger:            logger,
+		executor:          exec,
+		engine:            engine,
+		mcpServer:         mcp.NewServer(logger, exec),
+		activeConnections: activeConnections,
+		shutdownRequested: make(chan struct{}),
 	}
 
 	// Setup routes
@@ -85,17 +127,73 @@ func New(cfg *config.Config, logger *logrus.Logger) (*Server, error) {
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
+	// A negative file viewer port disables the file viewer
+	if s.config.Server.FileViewerPort >= 0 {
+		if err := s.StartFileViewer(); err != nil {
+			return err
+		}
+	}
+
 	s.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.config.Server.Port),
-		Handler: s.engine,
+		Addr:      fmt.Sprintf(":%d", s.config.Server.Port),
+		Handler:   s.engine,
+		ConnState: s.trackConnection,
+	}
+
+	if s.config.Server.UnixSocket == "" {
+		s.logger.Infof("Starting server on port %d", s.config.Server.Port)
+		return s.server.ListenAndServe()
+	}
+
+	listener, err := listenUnix(s.config.Server.UnixSocket)
+	if err != nil {
+		return err
+	}
+
+	// The same server serves both listeners, so Shutdown stops both and removes the socket file
+	serveErrors := make(chan error, 2)
+	s.logger.Infof("Starting server on Unix socket %s", s.config.Server.UnixSocket)
+	go func() { serveErrors <- s.server.Serve(listener) }()
+	if !s.config.Server.UnixSocketOnly {
+		s.logger.Infof("Starting server on port %d", s.config.Server.Port)
+		go func() { serveErrors <- s.server.ListenAndServe() }()
+	}
+	return <-serveErrors
+}
+
+// trackConnection keeps the active connections metric up to date as connections open and close
+func (s *Server) trackConnection(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		s.activeConnections.Add(context.Background(), 1)
+	case http.StateHijacked, http.StateClosed:
+		s.activeConnections.Add(context.Background(), -1)
+	}
+}
+
+// StartFileViewer starts serving the working directory read-only on the configured file viewer port of localhost
+func (s *Server) StartFileViewer() error {
+	viewer, err := newFileViewer(s.config.Server.WorkingDir, s.config.Server.FileViewerPort,
+		s.config.Server.SessionAPIKey, s.executor.SecurityCheck, s.logger)
+	if err != nil {
+		return err
 	}
 
-	s.logger.Infof("Starting server on port %d", s.config.Server.Port)
-	return s.server.ListenAndServe()
+	s.fileViewer = viewer
+	s.executor.SetFileViewerURL(viewer.URL())
+	s.logger.Infof("File viewer listening on port %d", viewer.Port())
+
+	go viewer.Serve()
+	return nil
 }
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.fileViewer != nil {
+		if err := s.fileViewer.Shutdown(ctx); err != nil {
+			s.logger.Errorf("Error shutting down file viewer: %v", err)
+		}
+	}
 	if s.server == nil {
 		return nil
 	}
@@ -111,6 +209,12 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
 
+// ShutdownRequested returns a channel that is closed when a graceful shutdown is requested via POST /shutdown.
+// The caller is expected to run the same shutdown path as for SIGTERM.
+func (s *Server) ShutdownRequested() <-chan struct{} {
+	return s.shutdownRequested
+}
+
 // Engine returns the gin engine for testing purposes
 func (s *Server) Engine() *gin.Engine {
 	return s.engine
@@ -125,28 +229,79 @@ func (s *Server) Executor() *executor.Executor {
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes() {
 	// Health check
-	s.engine.GET("/alive", s.handleAlive)
+	s.handle(http.MethodGet, "/alive", s.handleAlive)
+	s.handle(http.MethodGet, "/ready", s.handleReady)
+
+	// Orchestration
+	if s.config.Server.EnableRemoteShutdown {
+		s.handle(http.MethodPost, "/shutdown", s.handleShutdown)
+	}
 
 	// Server info
-	s.engine.GET("/server_info", s.handleServerInfo)
+	s.handle(http.MethodGet, "/server_info", s.handleServerInfo)
+	s.handle(http.MethodGet, "/version", s.handleVersion)
+	s.handle(http.MethodGet, "/env", s.handleEnv)
 
 	// Action execution
-	s.engine.POST("/execute_action", s.handleExecuteAction)
-	s.engine.POST("/execute_action_stream", s.handleExecuteActionStream)
+	s.handle(http.MethodPost, "/execute_action", s.handleExecuteAction)
+	s.handle(http.MethodPost, "/execute_action_stream", s.handleExecuteActionStream)
+	s.handle(http.MethodPost, "/kill", s.handleKill)
+	if s.logger.IsLevelEnabled(logrus.DebugLevel) {
+		// Only for diagnosing how actions are parsed, so not exposed outside debug mode
+		s.handle(http.MethodPost, "/parse_action", s.handleParseAction)
+	}
+
+	// Telemetry, flushed on demand when debugging
+	if s.config.Telemetry.Enabled && s.logger.IsLevelEnabled(logrus.DebugLevel) {
+		s.handle(http.MethodPost, "/telemetry/flush", s.handleFlushTelemetry)
+	}
 
 	// File operations
-	s.engine.POST("/upload_file", s.handleUploadFile)
-	s.engine.GET("/download_files", s.handleDownloadFiles)
-	s.engine.POST("/list_files", s.handleListFiles)
+	s.handle(http.MethodPost, "/upload_file", s.handleUploadFile)
+	s.handle(http.MethodGet, "/download_files", s.handleDownloadFiles)
+	s.handle(http.MethodPost, "/list_files", s.handleListFiles)
 
 	// VSCode integration
-	s.engine.GET("/vscode/connection_token", s.handleVSCodeToken)
+	s.handle(http.MethodGet, "/vscode/connection_token", s.handleVSCodeToken)
 
 	// MCP server management (placeholder)
-	s.engine.POST("/update_mcp_server", s.handleUpdateMCPServer)
+	s.handle(http.MethodPost, "/update_mcp_server", s.handleUpdateMCPServer)
 
 	// SSE endpoint for streaming communication
-	s.engine.GET("/sse", s.handleSSE)
+	s.handle(http.MethodGet, "/sse", s.handleSSE)
+
+	// Workspace change events over SSE
+	s.handle(http.MethodGet, "/watch", s.handleWatch)
+
+	// Interactive terminal over WebSocket
+	s.handle(http.MethodGet, "/terminal", s.handleTerminal)
+
+	// Profiling, behind the same authentication as everything else
+	if s.config.Server.EnablePprof {
+		s.setupPprofRoutes()
+	}
+}
+
+// setupPprofRoutes serves the net/http/pprof handlers under /debug/pprof
+func (s *Server) setupPprofRoutes() {
+	s.handle(http.MethodGet, "/debug/pprof/", gin.WrapF(pprof.Index))
+	s.handle(http.MethodGet, "/debug/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+	s.handle(http.MethodGet, "/debug/pprof/profile", gin.WrapF(pprof.Profile))
+	s.handle(http.MethodGet, "/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+	s.handle(http.MethodPost, "/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+	s.handle(http.MethodGet, "/debug/pprof/trace", gin.WrapF(pprof.Trace))
+	for _, profile := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		s.handle(http.MethodGet, "/debug/pprof/"+profile, gin.WrapH(pprof.Handler(profile)))
+	}
+}
+
+// handle registers a route unless server.disabled_endpoints lists its path, which then answers 404
+func (s *Server) handle(method, path string, handler gin.HandlerFunc) {
+	if slices.Contains(s.config.Server.DisabledEndpoints, path) {
+		s.logger.Infof("Endpoint %s %s is disabled", method, path)
+		return
+	}
+	s.engine.Handle(method, path, handler)
 }
 
 // handleAlive handles health check requests
@@ -159,6 +314,57 @@ func (s *Server) handleAlive(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// handleReady reports whether the runtime can accept work, so orchestrators can avoid routing to
+// a runtime that is misconfigured or whose workspace disk is full. The response lists every check.
+func (s *Server) handleReady(c *gin.Context) {
+	checks := s.executor.CheckReadiness(c.Request.Context())
+
+	var failures []string
+	for _, check := range checks {
+		if !check.OK {
+			failures = append(failures, fmt.Sprintf("%s: %s", check.Name, check.Error))
+		}
+	}
+	if len(failures) > 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not ready",
+			"error":  strings.Join(failures, "; "),
+			"checks": checks,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
+
+// handleShutdown starts a graceful shutdown and responds before it happens
+func (s *Server) handleShutdown(c *gin.Context) {
+	// Without a session API key anyone could stop the runtime
+	if s.config.Server.SessionAPIKey == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "remote shutdown requires a session API key"})
+		return
+	}
+
+	s.logger.Info("Graceful shutdown requested over HTTP")
+	s.shutdownOnce.Do(func() { close(s.shutdownRequested) })
+	c.JSON(http.StatusAccepted, gin.H{"status": "shutting down"})
+}
+
+// handleFlushTelemetry exports pending spans, log records and metrics right away. The SDK doesn't
+// report how many items were pending, so only the outcome is returned.
+func (s *Server) handleFlushTelemetry(c *gin.Context) {
+	// Like shutdown, flushing must not be available to anyone who can reach the port
+	if s.config.Server.SessionAPIKey == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "flushing telemetry requires a session API key"})
+		return
+	}
+
+	if err := telemetry.Flush(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to flush telemetry: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "flushed"})
+}
+
 // handleServerInfo handles server info requests
 func (s *Server) handleServerInfo(c *gin.Context) {
 	// Get current time for uptime/idle calculations
@@ -174,14 +380,17 @@ func (s *Server) handleServerInfo(c *gin.Context) {
 	// Get system stats and convert to Python format
 	systemStats := s.executor.GetSystemStats()
 	resources := models.SystemResources{
-		CPUCount:      runtime.NumCPU(),
-		CPUPercent:    systemStats.CPUPercent,
-		MemoryTotal:   int64(systemStats.Memory.RSS + systemStats.Memory.VMS), // Use RSS + VMS as total
-		MemoryUsed:    int64(systemStats.Memory.RSS),                          // Use RSS as used
-		MemoryPercent: float64(systemStats.Memory.Percent),
-		DiskTotal:     int64(systemStats.Disk.Total),
-		DiskUsed:      int64(systemStats.Disk.Used),
-		DiskPercent:   systemStats.Disk.Percent,
+		CPUCount:       runtime.NumCPU(),
+		CPUPercent:     systemStats.CPUPercent,
+		HostCPUPercent: systemStats.HostCPUPercent,
+		MemoryTotal:    int64(systemStats.Memory.Total), // Host memory, not the process size
+		MemoryUsed:     int64(systemStats.Memory.RSS),   // Use RSS as used
+		MemoryPercent:  float64(systemStats.Memory.Percent),
+		DiskTotal:      int64(systemStats.Disk.Total),
+		DiskUsed:       int64(systemStats.Disk.Used),
+		DiskPercent:    systemStats.Disk.Percent,
+		IOReadRate:     systemStats.IO.ReadBytesPerSec,
+		IOWriteRate:    systemStats.IO.WriteBytesPerSec,
 	}
 
 	// Create response matching Python format
@@ -189,12 +398,35 @@ func (s *Server) handleServerInfo(c *gin.Context) {
 		Uptime:    uptime,
 		IdleTime:  idleTime,
 		Resources: resources,
+		Version:   version.Version,
 	}
 
 	s.logger.Infof("Server info endpoint response: uptime=%.2fs, idle_time=%.2fs", uptime, idleTime)
 	c.JSON(http.StatusOK, response)
 }
 
+// handleVersion returns the version, commit and build date the runtime was built with
+func (s *Server) handleVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
+
+// handleEnv returns the environment variables seen by the command session, with secrets redacted
+func (s *Server) handleEnv(c *gin.Context) {
+	// Without a session API key anyone could read the environment
+	if s.config.Server.SessionAPIKey == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "environment snapshot requires a session API key"})
+		return
+	}
+
+	env, err := s.executor.SessionEnv(c.Request.Context())
+	if err != nil {
+		s.logger.Errorf("Failed to read session environment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"env": env})
+}
+
 // handleExecuteAction handles action execution requests
 func (s *Server) handleExecuteAction(c *gin.Context) {
 	tracer := otel.Tracer("openhands-runtime")
@@ -211,100 +443,27 @@ func (s *Server) handleExecuteAction(c *gin.Context) {
 		return
 	}
 
-	// Log the raw request body
-	s.logger.Infof("Received command: %s", string(bodyBytes))
-
-	// -----------------------------------------------------------------------
-	// Tool Compatibility Layer
-	// -----------------------------------------------------------------------
-	// This section handles compatibility with different AI tool calling formats.
-	// OpenHands can work with various frontends that might use OpenAI, Claude,
-	// or other LLM APIs. Each system has different tool calling formats:
-	//
-	// 1. Claude: Uses "tool_call_metadata" with tools like "str_replace_editor"
-	// 2. OpenAI: Uses "tool_calls" array with function name and arguments
-	//
-	// We map these external formats to our internal action formats before processing.
-	// -----------------------------------------------------------------------
+	// Map the tool calls of LLM APIs to runtime actions (see tool_compat.go)
 	var bodyMap map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &bodyMap); err == nil {
-		// Check if this is a tool call with specific formats we need to map
 		if action, hasAction := bodyMap["action"].(map[string]interface{}); hasAction {
-			// Handle Claude str_replace_editor used for reading files
-			if toolMeta, hasToolMeta := action["tool_call_metadata"].(map[string]interface{}); hasToolMeta {
-				if toolName, hasToolName := toolMeta["function_name"].(string); hasToolName {
-					s.logger.Infof("Detected tool call: %s", toolName)
-
-					// Handle str_replace_editor for file viewing
-					if toolName == "str_replace_editor" {
-						args, hasArgs := action["args"].(map[string]interface{})
-						if hasArgs {
-							command, hasCommand := args["command"].(string)
-							if hasCommand && command == "view" {
-								// This is a file read request using str_replace_editor
-								// Remap it to a standard read action
-								s.logger.Infof("Remapping str_replace_editor view to read action")
-								bodyMap["action"] = "read"
-								if path, hasPath := args["path"].(string); hasPath {
-									bodyMap["path"] = path
-								}
-								// Re-encode the modified request
-								modifiedBody, _ := json.Marshal(bodyMap)
-								bodyBytes = modifiedBody
-							}
-						}
-					}
-				}
-			}
-
-			// Handle OpenAI tool calls
-			if tool_calls, hasToolCalls := action["tool_calls"].([]interface{}); hasToolCalls && len(tool_calls) > 0 {
-				s.logger.Infof("Detected OpenAI format tool calls")
-
-				// Process the first tool call
-				if toolCall, ok := tool_calls[0].(map[string]interface{}); ok {
-					if function, hasFunction := toolCall["function"].(map[string]interface{}); hasFunction {
-						name, hasName := function["name"].(string)
-						arguments, hasArguments := function["arguments"].(string)
-
-						if hasName && hasArguments {
-							s.logger.Infof("Processing OpenAI tool call: %s", name)
-
-							// Parse arguments
-							var args map[string]interface{}
-							if err := json.Unmarshal([]byte(arguments), &args); err == nil {
-								// Map to our internal actions
-								switch name {
-								case "read_file":
-									if filePath, ok := args["target_file"].(string); ok {
-										bodyMap["action"] = "read"
-										bodyMap["path"] = filePath
-										s.logger.Infof("Remapped read_file to read action for %s", filePath)
-									}
-								case "run_terminal_cmd":
-									if cmd, ok := args["command"].(string); ok {
-										bodyMap["action"] = "run"
-										bodyMap["command"] = cmd
-										s.logger.Infof("Remapped run_terminal_cmd to run action: %s", cmd)
-									}
-								}
-
-								// Re-encode the modified request
-								modifiedBody, _ := json.Marshal(bodyMap)
-								bodyBytes = modifiedBody
-							} else {
-								s.logger.Warnf("Failed to parse OpenAI tool arguments: %v", err)
-							}
-						}
-					}
-				}
+			call, remapped, err := remapToolCall(action)
+			if errors.Is(err, errMultipleToolCalls) {
+				span.RecordError(err)
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			} else if err != nil {
+				s.logger.Warnf("Failed to map tool call: %v", err)
+			} else if remapped {
+				s.logger.Infof("Remapped %s tool call %s to %v action", call.Format, call.Name, action["action"])
+				// Re-encode the modified request
+				modifiedBody, _ := json.Marshal(bodyMap)
+				bodyBytes = modifiedBody
 			}
 		}
 	} else {
 		s.logger.Warnf("Failed to parse request for tool compatibility check: %v", err)
 	}
-	// End of Tool Compatibility Layer
-	// -----------------------------------------------------------------------
 
 	// Unmarshal the body into the request object
 	if err := json.Unmarshal(bodyBytes, &req); err != nil {
@@ -320,6 +479,11 @@ func (s *Server) handleExecuteAction(c *gin.Context) {
 		s.logger.Infof("Processing action type: %s", actionType)
 	}
 
+	// Accept the action ID next to the action as well as inside it
+	if _, hasID := req.Action["id"]; !hasID && req.ID != nil {
+		req.Action["id"] = *req.ID
+	}
+
 	// Report action request JSON in traces and logs
 	if s.config.Telemetry.Enabled {
 		telemetry.ReportJSON(ctx, s.logger, "action_request", req.Action)
@@ -327,13 +491,25 @@ func (s *Server) handleExecuteAction(c *gin.Context) {
 
 	// Execute action
 	observation, err := s.executor.ExecuteAction(ctx, req.Action)
+	if respondRequestTimeout(ctx, c, req.Action) {
+		s.logger.Warnf("Action exceeded the %s header", timeoutHeader)
+		return
+	}
+	if errors.Is(err, executor.ErrTooManyFileOps) {
+		span.RecordError(err)
+		respondBusy(c)
+		return
+	}
 	if err != nil {
 		span.RecordError(err)
 		s.logger.Errorf("Failed to execute action: %v", err)
-		errorObs := models.NewErrorObservation(
+		var errorObs interface{} = models.NewErrorObservation(
 			fmt.Sprintf("Failed to execute action: %v", err),
 			"ExecutionError",
 		)
+		if id, ok := models.ActionID(req.Action); ok {
+			errorObs = models.WithCause(errorObs, id)
+		}
 
 		// Report error observation JSON in traces and logs
 		if s.config.Telemetry.Enabled {
@@ -349,10 +525,6 @@ func (s *Server) handleExecuteAction(c *gin.Context) {
 		telemetry.ReportJSON(ctx, s.logger, "action_response", observation)
 	}
 
-	// Log the response
-	responseBytes, _ := json.Marshal(observation)
-	s.logger.Infof("Sending reply: %s", string(responseBytes))
-
 	c.JSON(http.StatusOK, observation)
 }
 
@@ -384,11 +556,17 @@ func (s *Server) handleExecuteActionStream(c *gin.Context) {
 		return
 	}
 
+	// Streamed commands are run actions, so they are disabled along with them
+	if s.executor.ActionDisabled("run") {
+		c.JSON(http.StatusForbidden, gin.H{"error": `action "run" is disabled on this runtime`})
+		return
+	}
+
 	// Set headers for streaming
 	setSSEHeaders(c)
 
 	// Create a channel for streaming output
-	outputChan := make(chan string, 100)
+	outputChan := make(chan executor.StreamEvent, 100)
 
 	// Create the action
 	action := models.CmdRunAction{
@@ -405,6 +583,9 @@ func (s *Server) handleExecuteActionStream(c *gin.Context) {
 	if hardTimeout, ok := req.Action["hard_timeout"].(float64); ok {
 		action.HardTimeout = int(hardTimeout)
 	}
+	if envFile, ok := req.Action["env_file"].(string); ok {
+		action.EnvFile = envFile
+	}
 
 	// Start streaming command execution in a goroutine
 	go func() {
@@ -417,15 +598,6 @@ func (s *Server) handleExecuteActionStream(c *gin.Context) {
 	// Stream the output
 	s.logger.Infof("Starting streaming execution for command: %s", command)
 
-	// Send initial message
-	c.SSEvent("start", gin.H{
-		"command":   command,
-		"timestamp": time.Now().Unix(),
-	})
-	if flusher, ok := c.Writer.(http.Flusher); ok {
-		flusher.Flush()
-	}
-
 	// Stream output lines with client disconnect detection
 	clientGone := c.Request.Context().Done()
 streamLoop:
@@ -434,7 +606,7 @@ streamLoop:
 		case <-clientGone:
 			s.logger.Info("Client disconnected during streaming execution")
 			return
-		case line, ok := <-outputChan:
+		case event, ok := <-outputChan:
 			if !ok {
 				// Channel closed, command completed
 				break streamLoop
@@ -445,10 +617,25 @@ streamLoop:
 				s.logger.Info("Client disconnected while sending output")
 				return
 			default:
-				c.SSEvent("output", gin.H{
-					"data":      line,
-					"timestamp": time.Now().Unix(),
-				})
+				switch {
+				case event.Type == executor.StreamStarted:
+					// The command ID lets the client interrupt the command with /kill
+					c.SSEvent("start", gin.H{
+						"command":    command,
+						"command_id": strconv.Itoa(event.CommandID),
+						"timestamp":  time.Now().Unix(),
+					})
+				case event.Type == executor.StreamStatus:
+					c.SSEvent("status", gin.H{
+						"data":      event.Data,
+						"timestamp": time.Now().Unix(),
+					})
+				default:
+					c.SSEvent("output", gin.H{
+						"data":      event.Data,
+						"timestamp": time.Now().Unix(),
+					})
+				}
 				if flusher, ok := c.Writer.(http.Flusher); ok {
 					flusher.Flush()
 				}
@@ -474,6 +661,66 @@ streamLoop:
 	s.logger.Infof("Completed streaming execution for command: %s", command)
 }
 
+// handleParseAction parses an action like handleExecuteAction, tool call mapping included,
+// and reports how it was parsed without executing it
+func (s *Server) handleParseAction(c *gin.Context) {
+	var req models.ActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{}
+	call, remapped, err := remapToolCall(req.Action)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if remapped {
+		response["tool_call"] = gin.H{"format": call.Format, "name": call.Name}
+	}
+
+	action, unparsed, err := s.executor.ParseAction(req.Action)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response["action_type"] = req.Action["action"]
+	response["go_type"] = fmt.Sprintf("%T", action)
+	response["action"] = action
+	if len(unparsed) > 0 {
+		response["unparsed"] = unparsed
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// handleKill interrupts a running command, escalating to stronger signals if it ignores the first one
+func (s *Server) handleKill(c *gin.Context) {
+	var req models.KillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pid, err := strconv.Atoi(req.CommandID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid command_id %q", req.CommandID)})
+		return
+	}
+
+	signal, err := s.executor.InterruptCommand(pid, req.Signal)
+	switch {
+	case errors.Is(err, executor.ErrUnsupportedSignal):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, executor.ErrCommandNotRunning):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "killed", "signal": signal})
+	}
+}
+
 // handleUploadFile handles file upload requests
 func (s *Server) handleUploadFile(c *gin.Context) {
 	tracer := otel.Tracer("openhands-runtime")
@@ -502,6 +749,10 @@ func (s *Server) handleUploadFile(c *gin.Context) {
 	}
 
 	if err := s.executor.UploadFile(ctx, path, content); err != nil {
+		if errors.Is(err, executor.ErrTooManyFileOps) {
+			respondBusy(c)
+			return
+		}
 		errorData := map[string]interface{}{
 			"path":  path,
 			"error": err.Error(),
@@ -563,6 +814,29 @@ func (s *Server) handleDownloadFiles(c *gin.Context) {
 		}
 	}
 
+	// The archive is compressed while it streams, so its length isn't known up front.
+	// Report the uncompressed size instead, which clients can use to estimate progress.
+	stats, err := s.executor.ArchiveStats(ctx, paths)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to inspect files: %v", err)})
+		return
+	}
+	if c.Query("dry_run") == "true" {
+		c.JSON(http.StatusOK, stats)
+		return
+	}
+
+	// A single regular file can be sent as is, so interrupted transfers can resume with a Range request
+	if c.Query("raw") == "true" && len(paths) == 1 {
+		if info, err := os.Stat(paths[0]); err == nil && info.Mode().IsRegular() {
+			s.serveRawFile(ctx, c, paths[0])
+			return
+		}
+	}
+
+	c.Header("X-File-Count", strconv.Itoa(stats.FileCount))
+	c.Header("X-Uncompressed-Size", strconv.FormatInt(stats.TotalBytes, 10))
+
 	// Determine filename for the zip
 	var filename string
 	if len(paths) == 1 {
@@ -577,6 +851,12 @@ func (s *Server) handleDownloadFiles(c *gin.Context) {
 
 	// Stream the zip file directly to the response writer
 	if err := s.executor.StreamZipArchiveMultiple(ctx, paths, c.Writer); err != nil {
+		if errors.Is(err, executor.ErrTooManyFileOps) && !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			respondBusy(c)
+			return
+		}
 		s.logger.Errorf("Error streaming zip file: %v", err)
 		// At this point headers are already sent, so we can't send a JSON error
 		// The client will see a truncated/corrupted zip file
@@ -584,6 +864,24 @@ func (s *Server) handleDownloadFiles(c *gin.Context) {
 	}
 }
 
+// serveRawFile answers a download request with the file's content rather than a zip,
+// honouring Range and conditional request headers
+func (s *Server) serveRawFile(ctx context.Context, c *gin.Context, path string) {
+	err := s.executor.ServeFile(ctx, path, func(content io.ReadSeeker, info os.FileInfo) {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", info.Name()))
+		http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), content)
+	})
+	if err == nil {
+		return
+	}
+	if errors.Is(err, executor.ErrTooManyFileOps) {
+		respondBusy(c)
+		return
+	}
+	s.logger.Errorf("Error serving file %s: %v", path, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to download file: %v", err)})
+}
+
 // handleListFiles handles file listing requests
 func (s *Server) handleListFiles(c *gin.Context) {
 	tracer := otel.Tracer("openhands-runtime")
@@ -596,6 +894,11 @@ func (s *Server) handleListFiles(c *gin.Context) {
 		return
 	}
 
+	if req.Recursive {
+		s.listFilesRecursive(ctx, c, req)
+		return
+	}
+
 	// Use the new ListFileNames function to match Python implementation
 	fileNames, err := s.executor.ListFileNames(ctx, req.Path)
 	if err != nil {
@@ -606,11 +909,37 @@ func (s *Server) handleListFiles(c *gin.Context) {
 	c.JSON(http.StatusOK, fileNames)
 }
 
+// listFilesRecursive answers a recursive /list_files request with the workspace-relative paths
+// of the entries down to req.MaxDepth, directories suffixed with "/" as in non-recursive listings
+func (s *Server) listFilesRecursive(ctx context.Context, c *gin.Context, req models.ListFilesRequest) {
+	files, truncated, err := s.executor.ListFiles(ctx, req.Path, executor.ListOptions{
+		Recursive:        true,
+		MaxDepth:         req.MaxDepth,
+		RespectGitignore: req.RespectGitignore,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to list files: %v", err)})
+		return
+	}
+	if truncated {
+		s.logger.Warnf("Listing of %s truncated at %d entries", req.Path, len(files))
+	}
+
+	fileNames := make([]string, 0, len(files))
+	for _, file := range files {
+		if file.IsDir {
+			fileNames = append(fileNames, file.Path+"/")
+		} else {
+			fileNames = append(fileNames, file.Path)
+		}
+	}
+	c.JSON(http.StatusOK, fileNames)
+}
+
 // handleVSCodeToken handles VSCode connection token requests
 func (s *Server) handleVSCodeToken(c *gin.Context) {
-	// This is a placeholder implementation
 	c.JSON(http.StatusOK, models.VSCodeConnectionToken{
-		Token: "placeholder-token",
+		Token: s.executor.VSCodeToken(),
 	})
 }
 
@@ -643,6 +972,9 @@ func (s *Server) handleUpdateMCPServer(c *gin.Context) {
 	// 3. Writes back to the profile file
 	// 4. Reloads the profile and updates servers
 
+	// Connected MCP clients list the tools again
+	s.mcpServer.NotifyToolsListChanged()
+
 	resp := gin.H{
 		"detail":           "MCP server updated successfully",
 		"router_error_log": "",
@@ -654,6 +986,12 @@ func (s *Server) handleUpdateMCPServer(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// respondBusy tells the client that the runtime is saturated and when to retry
+func respondBusy(c *gin.Context) {
+	c.Header("Retry-After", "1")
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": executor.ErrTooManyFileOps.Error()})
+}
+
 // setSSEHeaders sets the standard headers required for Server-Sent Events
 func setSSEHeaders(c *gin.Context) {
 	c.Header("Content-Type", "text/event-stream")
@@ -665,6 +1003,16 @@ func setSSEHeaders(c *gin.Context) {
 
 // handleSSE handles Server-Sent Events for streaming communication
 func (s *Server) handleSSE(c *gin.Context) {
+	// Every connection holds a goroutine until the client goes away, so their number is capped
+	open := s.sseConnections.Add(1)
+	defer s.sseConnections.Add(-1)
+	if limit := s.config.Server.MaxSSEConnections; limit > 0 && open > int64(limit) {
+		s.logger.Warnf("Refusing SSE connection: %d connections already open", limit)
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("too many SSE connections, at most %d are allowed", limit)})
+		return
+	}
+
 	// Delegate to the MCP server's SSE handler
 	s.mcpServer.HandleSSE(c)
 }
@@ -672,8 +1020,8 @@ func (s *Server) handleSSE(c *gin.Context) {
 // ginLogger creates a gin logger middleware using logrus
 func ginLogger(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Don't log /alive requests
-		if c.Request.URL.Path == "/alive" {
+		// Don't log health check requests
+		if c.Request.URL.Path == "/alive" || c.Request.URL.Path == "/ready" {
 			c.Next()
 			return
 		}
@@ -721,8 +1069,9 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Session-API-Key")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Session-API-Key, X-Timeout-Seconds, Range, If-Range, If-Modified-Since")
 		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Access-Control-Expose-Headers", "X-File-Count, X-Uncompressed-Size, Accept-Ranges, Content-Range")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -733,20 +1082,65 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// timeoutHeader bounds how long a single request may run, in seconds
+const timeoutHeader = "X-Timeout-Seconds"
+
+// requestTimeoutMiddleware gives the request context a deadline when the client sets X-Timeout-Seconds.
+// Handlers that run actions report exceeding it with a 504 and a timeout observation.
+func requestTimeoutMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(timeoutHeader)
+		if value == "" {
+			c.Next()
+			return
+		}
+
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds <= 0 || math.IsInf(seconds, 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s header %q: must be a positive number", timeoutHeader, value)})
+			c.Abort()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(seconds*float64(time.Second)))
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// respondRequestTimeout answers with a 504 and a timeout observation if the request's
+// X-Timeout-Seconds deadline has passed, and reports whether it did
+func respondRequestTimeout(ctx context.Context, c *gin.Context, action map[string]interface{}) bool {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+
+	var observation interface{} = models.NewErrorObservation(
+		fmt.Sprintf("Request timed out after %s seconds", c.GetHeader(timeoutHeader)),
+		"RequestTimeoutError",
+	)
+	if id, ok := models.ActionID(action); ok {
+		observation = models.WithCause(observation, id)
+	}
+	c.JSON(http.StatusGatewayTimeout, observation)
+	return true
+}
+
 // authMiddleware validates API key
 func authMiddleware(expectedAPIKey string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip authentication for certain endpoints
 		path := c.Request.URL.Path
-		if path == "/alive" || path == "/server_info" {
+		if path == "/alive" || path == "/ready" || path == "/server_info" {
 			c.Next()
 			return
 		}
 
 		apiKey := c.GetHeader("X-Session-API-Key")
 
-		// For SSE endpoints, also check query parameters as fallback
-		if apiKey == "" && path == "/sse" {
+		// Browsers can't set headers on SSE and WebSocket connections, so accept a query parameter there
+		if apiKey == "" && (path == "/sse" || path == "/terminal") {
 			apiKey = c.Query("api_key")
 		}
 
@@ -1,23 +1,46 @@
 package server_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/denysvitali/openhands-runtime-go/internal/models"
 	"github.com/denysvitali/openhands-runtime-go/pkg/config"
 	"github.com/denysvitali/openhands-runtime-go/pkg/server"
+	"github.com/denysvitali/openhands-runtime-go/pkg/version"
+	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/net/websocket"
 )
 
 func setupTestServer(t *testing.T) *server.Server {
+	return setupTestServerWithConfig(t, nil)
+}
+
+// setupTestServerWithConfig creates a test server, letting the caller adjust the configuration first
+func setupTestServerWithConfig(t *testing.T, configure func(cfg *config.Config)) *server.Server {
 	// Create a temporary directory for testing
 	tempDir := t.TempDir()
 
@@ -34,6 +57,9 @@ func setupTestServer(t *testing.T) *server.Server {
 			Enabled: false,
 		},
 	}
+	if configure != nil {
+		configure(cfg)
+	}
 	logger := logrus.New()
 	logger.SetLevel(logrus.DebugLevel)
 
@@ -71,6 +97,348 @@ func TestHandleAlive_Success(t *testing.T) {
 	assert.Contains(t, []string{"ok", "not initialized"}, status)
 }
 
+func TestHandleReady(t *testing.T) {
+	t.Run("enough disk space", func(t *testing.T) {
+		srv := setupTestServer(t)
+
+		req, err := http.NewRequest(http.MethodGet, "/ready", nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp struct {
+			Status string                  `json:"status"`
+			Checks []models.ReadinessCheck `json:"checks"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "ready", resp.Status)
+		assert.Equal(t, []models.ReadinessCheck{
+			{Name: "disk_space", OK: true},
+			{Name: "workspace_writable", OK: true},
+			{Name: "shell", OK: true},
+		}, resp.Checks)
+	})
+
+	t.Run("jupyter plugin without jupyter", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+			cfg.Server.Plugins = []string{"jupyter"}
+			cfg.Server.Shell = "/bin/sh"
+		})
+
+		req, err := http.NewRequest(http.MethodGet, "/ready", nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		var resp struct {
+			Status string                  `json:"status"`
+			Checks []models.ReadinessCheck `json:"checks"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "not ready", resp.Status)
+		require.Len(t, resp.Checks, 4)
+		assert.Equal(t, "jupyter", resp.Checks[3].Name)
+		assert.False(t, resp.Checks[3].OK)
+		assert.NotEmpty(t, resp.Checks[3].Error)
+	})
+
+	t.Run("disk below threshold", func(t *testing.T) {
+		srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+			cfg.Server.MinFreeDiskBytes = math.MaxUint64 // No disk has this much free space
+		})
+
+		req, err := http.NewRequest(http.MethodGet, "/ready", nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "not ready", resp["status"])
+		assert.Contains(t, resp["error"], "low disk space")
+	})
+}
+
+func TestHandleVersion(t *testing.T) {
+	getVersion := func(t *testing.T) map[string]string {
+		srv := setupTestServer(t)
+		req, err := createAuthenticatedRequest(http.MethodGet, "/version", nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp map[string]string
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		assert.Equal(t, map[string]string{"version": "dev", "commit": "dev", "build_date": "dev"}, getVersion(t))
+	})
+
+	t.Run("injected", func(t *testing.T) {
+		// As set by -ldflags -X at build time
+		defer func(v, c, d string) { version.Version, version.Commit, version.BuildDate = v, c, d }(version.Version, version.Commit, version.BuildDate)
+		version.Version, version.Commit, version.BuildDate = "1.2.3", "abc1234", "2024-05-01T12:00:00Z"
+
+		assert.Equal(t, map[string]string{"version": "1.2.3", "commit": "abc1234", "build_date": "2024-05-01T12:00:00Z"}, getVersion(t))
+	})
+}
+
+func TestHandleShutdown(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+			cfg.Server.EnableRemoteShutdown = true
+		})
+
+		req, err := createAuthenticatedRequest(http.MethodPost, "/shutdown", nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		select {
+		case <-srv.ShutdownRequested():
+		case <-time.After(time.Second):
+			t.Fatal("shutdown was not requested")
+		}
+	})
+
+	t.Run("enabled without API key header", func(t *testing.T) {
+		srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+			cfg.Server.EnableRemoteShutdown = true
+		})
+
+		req, err := http.NewRequest(http.MethodPost, "/shutdown", nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		select {
+		case <-srv.ShutdownRequested():
+			t.Fatal("shutdown must not be requested without authentication")
+		default:
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		srv := setupTestServer(t)
+
+		req, err := createAuthenticatedRequest(http.MethodPost, "/shutdown", nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestHandleEnv(t *testing.T) {
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.EnvRedactPatterns = []string{"*KEY*", "*TOKEN*"}
+	})
+
+	payload := `{"action": {"action": "run", "args": {"command": "export GREETING=hello MY_API_KEY=s3cret"}}}`
+	req, err := createAuthenticatedRequest(http.MethodPost, "/execute_action", bytes.NewBufferString(payload))
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	srv.Engine().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	req, err = createAuthenticatedRequest(http.MethodGet, "/env", nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	srv.Engine().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		Env map[string]string `json:"env"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "hello", response.Env["GREETING"])
+	assert.Equal(t, "[REDACTED]", response.Env["MY_API_KEY"])
+	assert.NotContains(t, rr.Body.String(), "s3cret")
+}
+
+func TestHandleKill(t *testing.T) {
+	srv := setupTestServer(t)
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected int
+	}{
+		{"unknown command", `{"command_id": "999999"}`, http.StatusNotFound},
+		{"unsupported signal", `{"command_id": "999999", "signal": "HUP"}`, http.StatusBadRequest},
+		{"invalid command id", `{"command_id": "abc"}`, http.StatusBadRequest},
+		{"missing command id", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := createAuthenticatedRequest(http.MethodPost, "/kill", bytes.NewBufferString(tt.payload))
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			srv.Engine().ServeHTTP(rr, req)
+			assert.Equal(t, tt.expected, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestHandleTerminal(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("terminals are only supported on Linux")
+	}
+	srv := setupTestServer(t)
+	ts := httptest.NewServer(srv.Engine())
+	defer ts.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/terminal?api_key=test-key&cols=100&rows=30"
+	ws, err := websocket.Dial(wsURL, "", ts.URL)
+	require.NoError(t, err)
+	defer func() { _ = ws.Close() }()
+
+	// readUntil collects terminal output until want appears count times
+	var output strings.Builder
+	readUntil := func(want string, count int) {
+		t.Helper()
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+		for strings.Count(output.String(), want) < count {
+			var chunk []byte
+			require.NoError(t, websocket.Message.Receive(ws, &chunk), "output so far: %q", output.String())
+			output.Write(chunk)
+		}
+	}
+
+	require.NoError(t, websocket.JSON.Send(ws, map[string]interface{}{"type": "input", "data": "echo hi\n"}))
+	// Once as the echoed keystrokes, once as the command's output
+	readUntil("hi\r\n", 2)
+	assert.Contains(t, output.String(), "echo hi")
+
+	require.NoError(t, websocket.JSON.Send(ws, map[string]interface{}{"type": "resize", "cols": 120, "rows": 40}))
+	require.NoError(t, websocket.JSON.Send(ws, map[string]interface{}{"type": "input", "data": "stty size\n"}))
+	readUntil("40 120", 1)
+}
+
+func TestHandleTerminal_RequiresSessionAPIKey(t *testing.T) {
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.SessionAPIKey = ""
+	})
+	ts := httptest.NewServer(srv.Engine())
+	defer ts.Close()
+
+	// A page on another site must not get a shell, whatever Origin it sends
+	_, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/terminal", "", "http://evil.example")
+	require.Error(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "/terminal", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	srv.Engine().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestHandleKill_StreamedCommand(t *testing.T) {
+	srv := setupTestServer(t)
+	ts := httptest.NewServer(srv.Engine())
+	t.Cleanup(ts.Close)
+
+	req, err := createAuthenticatedRequest(http.MethodPost, ts.URL+"/execute_action_stream",
+		bytes.NewBufferString(`{"action": {"action": "run", "command": "sleep 30"}}`))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Events arrive as event: and data: line pairs
+	events := make(chan [2]string, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		var event string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if name, ok := strings.CutPrefix(line, "event:"); ok {
+				event = name
+			} else if data, ok := strings.CutPrefix(line, "data:"); ok {
+				events <- [2]string{event, data}
+			}
+		}
+	}()
+	next := func() (string, map[string]interface{}) {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "stream ended")
+			var data map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(event[1]), &data))
+			return event[0], data
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "no stream event received")
+			return "", nil
+		}
+	}
+
+	event, data := next()
+	require.Equal(t, "start", event)
+	commandID, ok := data["command_id"].(string)
+	require.True(t, ok, "start event without command_id: %v", data)
+
+	kill, err := createAuthenticatedRequest(http.MethodPost, ts.URL+"/kill",
+		bytes.NewBufferString(fmt.Sprintf(`{"command_id": %q}`, commandID)))
+	require.NoError(t, err)
+	killResp, err := http.DefaultClient.Do(kill)
+	require.NoError(t, err)
+	defer func() { _ = killResp.Body.Close() }()
+	assert.Equal(t, http.StatusOK, killResp.StatusCode)
+
+	// The stream completes long before the sleep would have
+	for event != "complete" {
+		event, _ = next()
+	}
+}
+
+func TestHandleExecuteActionStream_StatusEvents(t *testing.T) {
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.StreamStatusIntervalSec = 1
+	})
+	ts := httptest.NewServer(srv.Engine())
+	t.Cleanup(ts.Close)
+
+	// Output that looks like a status event is still output
+	req, err := createAuthenticatedRequest(http.MethodPost, ts.URL+"/execute_action_stream",
+		bytes.NewBufferString(`{"action": {"action": "run", "command": "echo still running after 1s; sleep 1.5"}}`))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	events := map[string][]string{}
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event:"); ok {
+			event = name
+		} else if data, ok := strings.CutPrefix(line, "data:"); ok {
+			var payload map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(data), &payload))
+			if text, ok := payload["data"].(string); ok {
+				events[event] = append(events[event], text)
+			}
+		}
+	}
+
+	assert.Equal(t, []string{"still running after 1s\n"}, events["output"])
+	assert.Equal(t, []string{"still running after 1s"}, events["status"])
+}
+
 func TestHandleServerInfo_Success(t *testing.T) {
 	srv := setupTestServer(t)
 
@@ -96,6 +464,25 @@ func TestHandleServerInfo_Success(t *testing.T) {
 	assert.GreaterOrEqual(t, resp.Resources.CPUCount, 1)
 }
 
+func TestHandleServerInfo_HostMemory(t *testing.T) {
+	srv := setupTestServer(t)
+
+	req, err := createAuthenticatedRequest(http.MethodGet, "/server_info", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	srv.Engine().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp models.ServerInfoResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	hostMem, err := mem.VirtualMemory()
+	require.NoError(t, err)
+	assert.Equal(t, int64(hostMem.Total), resp.Resources.MemoryTotal, "memory_total must be the host's memory")
+	assert.Greater(t, resp.Resources.MemoryTotal, resp.Resources.MemoryUsed, "memory_total must exceed the process RSS")
+	assert.LessOrEqual(t, resp.Resources.MemoryPercent, 100.0)
+}
+
 func TestHandleExecuteAction_CmdRun_Success(t *testing.T) {
 	srv := setupTestServer(t)
 
@@ -129,6 +516,41 @@ func TestHandleExecuteAction_CmdRun_Success(t *testing.T) {
 	assert.NotEmpty(t, resp.Extras.CommandID)
 }
 
+func TestHandleExecuteAction_CorrelationID(t *testing.T) {
+	srv := setupTestServer(t)
+
+	execute := func(t *testing.T, payload string) models.Observation[models.CmdOutputExtras] {
+		req, err := createAuthenticatedRequest(http.MethodPost, "/execute_action", bytes.NewBufferString(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp models.Observation[models.CmdOutputExtras]
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+
+	t.Run("id inside the action", func(t *testing.T) {
+		resp := execute(t, `{"action": {"action": "run", "args": {"command": "echo hi"}, "id": 42}}`)
+		require.NotNil(t, resp.Cause)
+		assert.Equal(t, int64(42), *resp.Cause)
+	})
+
+	t.Run("id next to the action", func(t *testing.T) {
+		resp := execute(t, `{"action": {"action": "run", "args": {"command": "echo hi"}}, "id": 0}`)
+		require.NotNil(t, resp.Cause)
+		assert.Equal(t, int64(0), *resp.Cause)
+	})
+
+	t.Run("no id", func(t *testing.T) {
+		resp := execute(t, `{"action": {"action": "run", "args": {"command": "echo hi"}}}`)
+		assert.Nil(t, resp.Cause)
+	})
+}
+
 func TestHandleExecuteAction_InvalidJSON(t *testing.T) {
 	srv := setupTestServer(t)
 
@@ -240,7 +662,9 @@ func TestHandleListFiles_Success(t *testing.T) {
 }
 
 func TestHandleVSCodeToken_Success(t *testing.T) {
-	srv := setupTestServer(t)
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.VSCodeConnectionToken = "vscode-token"
+	})
 
 	req, err := createAuthenticatedRequest(http.MethodGet, "/vscode/connection_token", nil)
 	require.NoError(t, err)
@@ -254,6 +678,909 @@ func TestHandleVSCodeToken_Success(t *testing.T) {
 	err = json.Unmarshal(rr.Body.Bytes(), &resp)
 	require.NoError(t, err, "Failed to unmarshal response")
 
-	// Should return a token (even if placeholder)
-	assert.NotEmpty(t, resp.Token)
+	// The token the VSCode server was started with
+	assert.Equal(t, "vscode-token", resp.Token)
+}
+
+func TestFileViewer_ServesWorkingDirectory(t *testing.T) {
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.FileViewerPort = 0 // Auto-assign
+	})
+	t.Cleanup(func() {
+		_ = srv.Shutdown(context.Background())
+	})
+
+	workingDir := srv.Executor().GetServerInfo().WorkingDir
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "artifact.txt"), []byte("rendered artifact"), 0644))
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(workingDir, "escape.txt")))
+
+	require.NoError(t, srv.StartFileViewer())
+
+	viewerURL := srv.Executor().GetServerInfo().FileViewerURL
+	require.NotEmpty(t, viewerURL)
+	assert.True(t, strings.HasPrefix(viewerURL, "http://localhost:"), viewerURL)
+
+	get := func(t *testing.T, url string, authenticated bool) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		if authenticated {
+			req.Header.Set("X-Session-API-Key", "test-key")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get(t, viewerURL+"/artifact.txt", true)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "rendered artifact", body)
+
+	t.Run("requires the API key", func(t *testing.T) {
+		status, _ := get(t, viewerURL+"/artifact.txt", false)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		status, body := get(t, viewerURL+"/artifact.txt?api_key=test-key", false)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "rendered artifact", body)
+	})
+
+	t.Run("refuses symlinks out of the working directory", func(t *testing.T) {
+		status, body := get(t, viewerURL+"/escape.txt", true)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.NotContains(t, body, "secret")
+	})
+
+	t.Run("is read-only", func(t *testing.T) {
+		req, err := createAuthenticatedRequest(http.MethodPost, viewerURL+"/artifact.txt", bytes.NewBufferString("overwrite"))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
+
+func TestHandleSSE_ConnectionLimit(t *testing.T) {
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.MaxSSEConnections = 2
+	})
+	ts := httptest.NewServer(srv.Engine())
+	defer ts.Close()
+
+	// connect opens an SSE connection; once it is accepted, the first event has been read
+	connect := func(t *testing.T) (*http.Response, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := createAuthenticatedRequest(http.MethodGet, ts.URL+"/sse", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		require.NoError(t, err)
+		if resp.StatusCode == http.StatusOK {
+			line, err := bufio.NewReader(resp.Body).ReadString('\n')
+			require.NoError(t, err)
+			require.Equal(t, "event:message\n", line)
+		}
+		return resp, func() {
+			cancel()
+			_ = resp.Body.Close()
+		}
+	}
+
+	first, closeFirst := connect(t)
+	require.Equal(t, http.StatusOK, first.StatusCode)
+	second, closeSecond := connect(t)
+	defer closeSecond()
+	require.Equal(t, http.StatusOK, second.StatusCode)
+
+	refused, closeRefused := connect(t)
+	closeRefused()
+	assert.Equal(t, http.StatusServiceUnavailable, refused.StatusCode)
+	assert.Equal(t, "1", refused.Header.Get("Retry-After"))
+
+	// Refused connections don't take a slot, and a disconnected client frees its own
+	closeFirst()
+	require.Eventually(t, func() bool {
+		resp, closeResp := connect(t)
+		defer closeResp()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestHandleDownloadFiles_ConcurrencyLimit(t *testing.T) {
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.MaxConcurrentFileOps = 1
+	})
+	ts := httptest.NewServer(srv.Engine())
+	defer ts.Close()
+
+	// Incompressible content large enough to fill the socket buffers, so the first
+	// download keeps its slot while the client isn't reading
+	workingDir := srv.Executor().GetServerInfo().WorkingDir
+	largeFile := filepath.Join(workingDir, "large.bin")
+	data := make([]byte, 32*1024*1024)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(largeFile, data, 0644))
+
+	download := func() *http.Response {
+		req, err := createAuthenticatedRequest(http.MethodGet, ts.URL+"/download_files?path="+largeFile, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	first := download()
+	defer func() { _ = first.Body.Close() }()
+	require.Equal(t, http.StatusOK, first.StatusCode)
+
+	var wg sync.WaitGroup
+	statuses := make([]int, 4)
+	retryAfter := make([]string, 4)
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp := download()
+			defer func() { _ = resp.Body.Close() }()
+			statuses[i] = resp.StatusCode
+			retryAfter[i] = resp.Header.Get("Retry-After")
+		}(i)
+	}
+	wg.Wait()
+
+	for i, status := range statuses {
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.NotEmpty(t, retryAfter[i])
+	}
+
+	// Reads through /execute_action share the limit
+	read, err := createAuthenticatedRequest(http.MethodPost, ts.URL+"/execute_action",
+		bytes.NewBufferString(fmt.Sprintf(`{"action": {"action": "read", "path": %q}}`, largeFile)))
+	require.NoError(t, err)
+	readResp, err := http.DefaultClient.Do(read)
+	require.NoError(t, err)
+	_ = readResp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, readResp.StatusCode)
+	assert.NotEmpty(t, readResp.Header.Get("Retry-After"))
+
+	// Once the first download completes, the slot is released again
+	_, err = io.Copy(io.Discard, first.Body)
+	require.NoError(t, err)
+	_ = first.Body.Close()
+
+	assert.Eventually(t, func() bool {
+		resp := download()
+		defer func() { _ = resp.Body.Close() }()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestHandleExecuteAction_StrReplaceEditor(t *testing.T) {
+	var workingDir string
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		workingDir = cfg.Server.WorkingDir
+	})
+
+	execute := func(t *testing.T, args string) models.Observation[models.FileEditExtras] {
+		payload := `{"action": {"action": "str_replace_editor", "args": ` + args + `, "tool_call_metadata": {"function_name": "str_replace_editor"}}}`
+		req, err := createAuthenticatedRequest(http.MethodPost, "/execute_action", bytes.NewBufferString(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var resp models.Observation[models.FileEditExtras]
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+	readFile := func(t *testing.T) string {
+		data, err := os.ReadFile(filepath.Join(workingDir, "hello.py"))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("create", func(t *testing.T) {
+		resp := execute(t, `{"command": "create", "path": "hello.py", "file_text": "print('hello')\n"}`)
+		assert.Equal(t, "write", resp.Observation)
+		assert.Equal(t, "print('hello')\n", readFile(t))
+	})
+
+	t.Run("str_replace", func(t *testing.T) {
+		resp := execute(t, `{"command": "str_replace", "path": "hello.py", "old_str": "hello", "new_str": "world"}`)
+		assert.Equal(t, "edit", resp.Observation)
+		assert.Equal(t, "print('world')\n", readFile(t))
+	})
+
+	t.Run("insert", func(t *testing.T) {
+		resp := execute(t, `{"command": "insert", "path": "hello.py", "insert_line": 0, "new_str": "import os"}`)
+		assert.Equal(t, "edit", resp.Observation)
+		assert.Equal(t, "import os\nprint('world')\n", readFile(t))
+	})
+
+	t.Run("view", func(t *testing.T) {
+		resp := execute(t, `{"command": "view", "path": "hello.py"}`)
+		assert.Equal(t, "read", resp.Observation)
+		assert.Contains(t, resp.Content, "print('world')")
+	})
+}
+
+func TestHandleExecuteAction_OpenAIToolCalls(t *testing.T) {
+	var workingDir string
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		workingDir = cfg.Server.WorkingDir
+	})
+
+	execute := func(t *testing.T, name string, args map[string]interface{}) []byte {
+		arguments, err := json.Marshal(args)
+		require.NoError(t, err)
+		payload, err := json.Marshal(map[string]interface{}{
+			"action": map[string]interface{}{
+				"action": "tool_call",
+				"tool_calls": []interface{}{
+					map[string]interface{}{"function": map[string]interface{}{"name": name, "arguments": string(arguments)}},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		req, err := createAuthenticatedRequest(http.MethodPost, "/execute_action", bytes.NewBuffer(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return rr.Body.Bytes()
+	}
+	readFile := func(t *testing.T) string {
+		data, err := os.ReadFile(filepath.Join(workingDir, "notes.md"))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("write_file", func(t *testing.T) {
+		var resp models.Observation[models.FileWriteExtras]
+		require.NoError(t, json.Unmarshal(execute(t, "write_file", map[string]interface{}{"path": "notes.md", "content": "# Notes\n"}), &resp))
+		assert.Equal(t, "write", resp.Observation)
+		assert.Equal(t, "# Notes\n", readFile(t))
+	})
+
+	t.Run("edit_file", func(t *testing.T) {
+		var resp models.Observation[models.FileEditExtras]
+		body := execute(t, "edit_file", map[string]interface{}{"target_file": "notes.md", "old_string": "Notes", "new_string": "Todo"})
+		require.NoError(t, json.Unmarshal(body, &resp))
+		assert.Equal(t, "edit", resp.Observation)
+		assert.Equal(t, "# Todo\n", readFile(t))
+	})
+
+	t.Run("read_file", func(t *testing.T) {
+		var resp models.Observation[models.FileReadExtras]
+		require.NoError(t, json.Unmarshal(execute(t, "read_file", map[string]interface{}{"target_file": "notes.md"}), &resp))
+		assert.Equal(t, "read", resp.Observation)
+		assert.Contains(t, resp.Content, "# Todo")
+	})
+
+	t.Run("apply_patch", func(t *testing.T) {
+		var resp models.Observation[models.PatchExtras]
+		patch := "--- a/notes.md\n+++ b/notes.md\n@@ -1 +1 @@\n-# Todo\n+# Done\n"
+		require.NoError(t, json.Unmarshal(execute(t, "apply_patch", map[string]interface{}{"patch": patch}), &resp))
+		assert.Equal(t, "patch", resp.Observation)
+		assert.Equal(t, "# Done\n", readFile(t))
+	})
+
+	t.Run("run_terminal_cmd", func(t *testing.T) {
+		var resp models.Observation[models.CmdOutputExtras]
+		require.NoError(t, json.Unmarshal(execute(t, "run_terminal_cmd", map[string]interface{}{"command": "echo hi"}), &resp))
+		assert.Equal(t, "run", resp.Observation)
+		assert.Equal(t, "hi\n", resp.Content)
+	})
+}
+
+func TestHandleExecuteAction_MultipleToolCalls(t *testing.T) {
+	srv := setupTestServer(t)
+
+	payload := `{"action": {"action": "tool_call", "tool_calls": [
+		{"function": {"name": "read_file", "arguments": "{\"target_file\": \"a.go\"}"}},
+		{"function": {"name": "run_terminal_cmd", "arguments": "{\"command\": \"ls\"}"}}
+	]}}`
+	req, err := createAuthenticatedRequest(http.MethodPost, "/execute_action", bytes.NewBufferString(payload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	srv.Engine().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var resp map[string]string
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Contains(t, resp["error"], "got 2: read_file, run_terminal_cmd")
+}
+
+func TestHandleParseAction(t *testing.T) {
+	srv := setupTestServer(t)
+
+	parse := func(t *testing.T, payload string) (int, map[string]interface{}) {
+		req, err := createAuthenticatedRequest(http.MethodPost, "/parse_action", bytes.NewBufferString(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return rr.Code, resp
+	}
+
+	tests := []struct {
+		name       string
+		payload    string
+		actionType string
+		goType     string
+	}{
+		{"flat", `{"action": {"action": "run", "command": "ls"}}`, "run", "models.CmdRunAction"},
+		{"nested args", `{"action": {"action": "read", "args": {"path": "a.go"}}}`, "read", "models.FileReadAction"},
+		{"unknown type", `{"action": {"action": "teleport", "args": {}}}`, "teleport", "models.Action"},
+		{"tool call", `{"action": {"action": "tool_call", "tool_calls": [{"function": {"name": "read_file", "arguments": "{\"target_file\": \"a.go\"}"}}]}}`, "read", "models.FileReadAction"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := parse(t, tt.payload)
+			require.Equal(t, http.StatusOK, code, resp)
+			assert.Equal(t, tt.actionType, resp["action_type"])
+			assert.Equal(t, tt.goType, resp["go_type"])
+		})
+	}
+
+	t.Run("unparsed args", func(t *testing.T) {
+		code, resp := parse(t, `{"action": {"action": "run", "args": {"command": "ls", "colour": "blue"}}}`)
+		require.Equal(t, http.StatusOK, code, resp)
+		assert.Equal(t, map[string]interface{}{"colour": "blue"}, resp["unparsed"])
+		action, ok := resp["action"].(map[string]interface{})
+		require.True(t, ok, "expected the parsed action, got %v", resp["action"])
+		assert.Equal(t, map[string]interface{}{"colour": "blue"}, action["args"])
+	})
+
+	t.Run("missing action type", func(t *testing.T) {
+		code, resp := parse(t, `{"action": {"command": "ls"}}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, resp["error"], "missing 'action' field")
+	})
+
+	t.Run("disabled outside debug mode", func(t *testing.T) {
+		cfg := &config.Config{Server: config.ServerConfig{SessionAPIKey: "test-key", WorkingDir: t.TempDir()}}
+		logger := logrus.New()
+		logger.SetLevel(logrus.InfoLevel)
+		srv, err := server.New(cfg, logger)
+		require.NoError(t, err)
+
+		req, err := createAuthenticatedRequest(http.MethodPost, "/parse_action", bytes.NewBufferString(`{"action": {"action": "run"}}`))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestHandleListFiles_RecursiveMaxDepth(t *testing.T) {
+	var workingDir string
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		workingDir = cfg.Server.WorkingDir
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(workingDir, "src", "pkg", "deep"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "src", "main.go"), []byte("package main"), 0644))
+
+	req, err := createAuthenticatedRequest(http.MethodPost, "/list_files", bytes.NewBufferString(`{"path": "", "recursive": true, "max_depth": 2}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	srv.Engine().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp []string
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.ElementsMatch(t, []string{"src/", "src/main.go", "src/pkg/"}, resp)
+}
+
+func TestHandleDownloadFiles_DryRun(t *testing.T) {
+	srv := setupTestServer(t)
+	workingDir := srv.Executor().GetServerInfo().WorkingDir
+
+	dir := filepath.Join(workingDir, "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("world!"), 0644))
+	single := filepath.Join(workingDir, "c.txt")
+	require.NoError(t, os.WriteFile(single, []byte("abc"), 0644))
+
+	download := func(t *testing.T, query string) *httptest.ResponseRecorder {
+		req, err := createAuthenticatedRequest(http.MethodGet, "/download_files?"+query, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return rr
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		rr := download(t, "paths="+dir+"&paths="+single+"&dry_run=true")
+
+		var stats models.ArchiveStats
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+		assert.Equal(t, models.ArchiveStats{FileCount: 3, TotalBytes: 14}, stats)
+	})
+
+	t.Run("size headers", func(t *testing.T) {
+		rr := download(t, "path="+dir)
+
+		assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "2", rr.Header().Get("X-File-Count"))
+		assert.Equal(t, "11", rr.Header().Get("X-Uncompressed-Size"))
+	})
+}
+
+func TestHandleDownloadFiles_Raw(t *testing.T) {
+	srv := setupTestServer(t)
+	workingDir := srv.Executor().GetServerInfo().WorkingDir
+
+	path := filepath.Join(workingDir, "artifact.bin")
+	content := []byte("0123456789abcdefghij")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	download := func(t *testing.T, headers map[string]string) *httptest.ResponseRecorder {
+		req, err := createAuthenticatedRequest(http.MethodGet, "/download_files?raw=true&path="+path, nil)
+		require.NoError(t, err)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("full", func(t *testing.T) {
+		rr := download(t, nil)
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, content, rr.Body.Bytes())
+		assert.Equal(t, "20", rr.Header().Get("Content-Length"))
+		assert.Equal(t, "bytes", rr.Header().Get("Accept-Ranges"))
+		assert.Equal(t, "attachment; filename=artifact.bin", rr.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("range", func(t *testing.T) {
+		rr := download(t, map[string]string{"Range": "bytes=10-"})
+
+		require.Equal(t, http.StatusPartialContent, rr.Code, rr.Body.String())
+		assert.Equal(t, "abcdefghij", rr.Body.String())
+		assert.Equal(t, "bytes 10-19/20", rr.Header().Get("Content-Range"))
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		lastModified := download(t, nil).Header().Get("Last-Modified")
+		require.NotEmpty(t, lastModified)
+
+		rr := download(t, map[string]string{"If-Modified-Since": lastModified})
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.Bytes())
+	})
+
+	t.Run("directory is zipped", func(t *testing.T) {
+		req, err := createAuthenticatedRequest(http.MethodGet, "/download_files?raw=true&path="+workingDir, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	})
+}
+
+func TestDisabledActionsAndEndpoints(t *testing.T) {
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.DisabledActions = []string{"run"}
+		cfg.Server.DisabledEndpoints = []string{"/update_mcp_server"}
+	})
+
+	serve := func(t *testing.T, method, url, payload string) *httptest.ResponseRecorder {
+		req, err := createAuthenticatedRequest(method, url, bytes.NewBufferString(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("disabled action", func(t *testing.T) {
+		rr := serve(t, http.MethodPost, "/execute_action", `{"action": {"action": "run", "command": "echo hi"}}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp models.Observation[models.ErrorExtras]
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "ActionDisabledError", resp.Extras.ErrorID)
+		assert.Contains(t, resp.Content, `"run" is disabled`)
+	})
+
+	t.Run("disabled action over the stream endpoint", func(t *testing.T) {
+		rr := serve(t, http.MethodPost, "/execute_action_stream", `{"action": {"action": "run", "command": "echo hi"}}`)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("other actions still run", func(t *testing.T) {
+		rr := serve(t, http.MethodPost, "/execute_action", `{"action": {"action": "think", "thought": "hmm"}}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "ActionDisabledError")
+	})
+
+	t.Run("disabled endpoint", func(t *testing.T) {
+		rr := serve(t, http.MethodPost, "/update_mcp_server", `[]`)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestStart_UnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, which t.TempDir() can exceed
+	dir, err := os.MkdirTemp("", "sock")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "runtime.sock")
+
+	// A socket file left behind by a crashed runtime
+	stale, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.FileViewerPort = -1
+		cfg.Server.UnixSocket = socketPath
+		cfg.Server.UnixSocketOnly = true
+	})
+	started := make(chan error, 1)
+	go func() { started <- srv.Start() }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = client.Get("http://runtime/alive")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status": "ok"}`, string(body))
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-started, http.ErrServerClosed)
+	assert.NoFileExists(t, socketPath, "the socket file should be removed on shutdown")
+}
+
+func TestHandleExecuteAction_TimeoutHeader(t *testing.T) {
+	srv := setupTestServer(t)
+
+	execute := func(t *testing.T, timeout, command string) *httptest.ResponseRecorder {
+		payload := fmt.Sprintf(`{"action": {"action": "run", "command": %q, "id": 7}}`, command)
+		req, err := createAuthenticatedRequest(http.MethodPost, "/execute_action", bytes.NewBufferString(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Timeout-Seconds", timeout)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("exceeded", func(t *testing.T) {
+		start := time.Now()
+		rr := execute(t, "0.5", "sleep 10")
+		assert.Less(t, time.Since(start), 5*time.Second, "the command should be stopped at the deadline")
+
+		require.Equal(t, http.StatusGatewayTimeout, rr.Code, rr.Body.String())
+		var resp models.Observation[models.ErrorExtras]
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "RequestTimeoutError", resp.Extras.ErrorID)
+		assert.Contains(t, resp.Content, "timed out after 0.5 seconds")
+		assert.Contains(t, rr.Body.String(), `"cause":7`)
+	})
+
+	t.Run("within the deadline", func(t *testing.T) {
+		rr := execute(t, "10", "echo fast")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Contains(t, rr.Body.String(), "fast")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rr := execute(t, "soon", "echo fast")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestPprofEndpoints(t *testing.T) {
+	get := func(t *testing.T, srv *server.Server, url string, authenticated bool) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		if authenticated {
+			req.Header.Set("X-Session-API-Key", "test-key")
+		}
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		srv := setupTestServer(t)
+		assert.Equal(t, http.StatusNotFound, get(t, srv, "/debug/pprof/", true).Code)
+		assert.Equal(t, http.StatusNotFound, get(t, srv, "/debug/pprof/heap", true).Code)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+			cfg.Server.EnablePprof = true
+		})
+
+		rr := get(t, srv, "/debug/pprof/", true)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "goroutine")
+
+		rr = get(t, srv, "/debug/pprof/goroutine?debug=1", true)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "goroutine profile")
+
+		assert.Equal(t, http.StatusForbidden, get(t, srv, "/debug/pprof/heap", false).Code, "profiles require the API key")
+	})
+}
+
+func TestHandleFlushTelemetry(t *testing.T) {
+	flush := func(t *testing.T, srv *server.Server) *httptest.ResponseRecorder {
+		req, err := createAuthenticatedRequest(http.MethodPost, "/telemetry/flush", nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("not served without telemetry", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, flush(t, setupTestServer(t)).Code)
+	})
+
+	t.Run("exports pending spans", func(t *testing.T) {
+		// Spans wait in the batcher until the next scheduled export, an hour away
+		exporter := tracetest.NewInMemoryExporter()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(time.Hour)))
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(provider)
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+			cfg.Telemetry.Enabled = true
+		})
+
+		_, span := otel.Tracer("test").Start(context.Background(), "pending")
+		span.End()
+		require.Empty(t, exporter.GetSpans())
+
+		rr := flush(t, srv)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"status": "flushed"}`, rr.Body.String())
+
+		var names []string
+		for _, exported := range exporter.GetSpans() {
+			names = append(names, exported.Name)
+		}
+		assert.Contains(t, names, "pending")
+	})
+}
+
+func TestBodyLogging(t *testing.T) {
+	newServer := func(t *testing.T, logBodies bool) (*server.Server, *bytes.Buffer) {
+		var logs bytes.Buffer
+		logger := logrus.New()
+		logger.SetOutput(&logs)
+
+		cfg := &config.Config{
+			Server: config.ServerConfig{
+				SessionAPIKey:     "test-key",
+				WorkingDir:        t.TempDir(),
+				EnvRedactPatterns: []string{"*KEY*", "*TOKEN*"},
+			},
+			Log: config.LogConfig{LogBodies: logBodies, MaxBodyLogBytes: 4096},
+		}
+		srv, err := server.New(cfg, logger)
+		require.NoError(t, err)
+		return srv, &logs
+	}
+
+	execute := func(t *testing.T, srv *server.Server) {
+		payload := `{"action": {"action": "run", "command": "echo body-logged"}, "github_token": "ghp_secret", "note": "uses test-key"}`
+		req, err := createAuthenticatedRequest(http.MethodPost, "/execute_action", bytes.NewBufferString(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		srv, logs := newServer(t, false)
+		execute(t, srv)
+
+		assert.NotContains(t, logs.String(), "Request body")
+		assert.NotContains(t, logs.String(), "Response body")
+		assert.NotContains(t, logs.String(), "ghp_secret")
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		srv, logs := newServer(t, true)
+		execute(t, srv)
+
+		assert.Contains(t, logs.String(), "Request body")
+		assert.Contains(t, logs.String(), "echo body-logged")
+		assert.Contains(t, logs.String(), "Response body")
+		assert.Contains(t, logs.String(), `body-logged\\n`, "the observation should be logged")
+		assert.NotContains(t, logs.String(), "ghp_secret")
+		assert.NotContains(t, logs.String(), "test-key")
+	})
+}
+
+func TestHandleUpdateMCPServer_NotifiesSSEClients(t *testing.T) {
+	srv := setupTestServer(t)
+	// Closed last, once the SSE connections are
+	ts := httptest.NewServer(srv.Engine())
+	t.Cleanup(ts.Close)
+
+	// connect opens an SSE connection and returns its JSON-RPC messages
+	connect := func(t *testing.T) <-chan map[string]interface{} {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		req, err := createAuthenticatedRequest(http.MethodGet, ts.URL+"/sse", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		messages := make(chan map[string]interface{}, 16)
+		go func() {
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				if data, ok := strings.CutPrefix(scanner.Text(), "data:"); ok {
+					var message map[string]interface{}
+					if json.Unmarshal([]byte(data), &message) == nil {
+						messages <- message
+					}
+				}
+			}
+		}()
+		return messages
+	}
+
+	receive := func(t *testing.T, messages <-chan map[string]interface{}) map[string]interface{} {
+		select {
+		case message := <-messages:
+			return message
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no MCP message received")
+			return nil
+		}
+	}
+
+	clients := []<-chan map[string]interface{}{connect(t), connect(t)}
+	for _, messages := range clients {
+		initialized := receive(t, messages)
+		require.Equal(t, "server/initialized", initialized["method"])
+		capabilities := initialized["params"].(map[string]interface{})["capabilities"].(map[string]interface{})
+		assert.Equal(t, true, capabilities["tools"].(map[string]interface{})["listChanged"])
+	}
+
+	req, err := createAuthenticatedRequest(http.MethodPost, ts.URL+"/update_mcp_server",
+		bytes.NewBufferString(`[{"name": "fetch", "command": "uvx", "args": ["mcp-server-fetch"]}]`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	for _, messages := range clients {
+		assert.Equal(t, "notifications/tools/list_changed", receive(t, messages)["method"])
+	}
+}
+
+func TestHandleWatch(t *testing.T) {
+	srv := setupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Server.MaxWatchedPaths = 1
+	})
+	// Closed last, once the watch stream is
+	ts := httptest.NewServer(srv.Engine())
+	t.Cleanup(ts.Close)
+	workingDir := srv.Executor().GetServerInfo().WorkingDir
+
+	watch := func(t *testing.T, path string) *http.Response {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		req, err := createAuthenticatedRequest(http.MethodGet, ts.URL+"/watch?path="+url.QueryEscape(path), nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("relative path", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, watch(t, "src").StatusCode)
+	})
+
+	t.Run("outside the workspace", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, watch(t, "/etc").StatusCode)
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, watch(t, filepath.Join(workingDir, "missing")).StatusCode)
+	})
+
+	t.Run("create event", func(t *testing.T) {
+		resp := watch(t, workingDir)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		// Events arrive as event: and data: line pairs
+		events := make(chan [2]string, 16)
+		go func() {
+			scanner := bufio.NewScanner(resp.Body)
+			var event string
+			for scanner.Scan() {
+				line := scanner.Text()
+				if name, ok := strings.CutPrefix(line, "event:"); ok {
+					event = name
+				} else if data, ok := strings.CutPrefix(line, "data:"); ok {
+					events <- [2]string{event, data}
+				}
+			}
+		}()
+		next := func() (string, map[string]interface{}) {
+			select {
+			case event := <-events:
+				var data map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(event[1]), &data))
+				return event[0], data
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "no watch event received")
+				return "", nil
+			}
+		}
+
+		event, data := next()
+		require.Equal(t, "watching", event)
+		assert.Equal(t, workingDir, data["path"])
+
+		// The limit of one watched path is taken
+		refused := watch(t, workingDir)
+		assert.Equal(t, http.StatusServiceUnavailable, refused.StatusCode)
+		assert.Equal(t, "1", refused.Header.Get("Retry-After"))
+
+		created := filepath.Join(workingDir, "new.txt")
+		require.NoError(t, os.WriteFile(created, []byte("hello"), 0644))
+		event, data = next()
+		assert.Equal(t, "create", event)
+		assert.Equal(t, created, data["path"])
+	})
 }
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// Default terminal size until the client sends a resize message
+const (
+	defaultTerminalCols = 80
+	defaultTerminalRows = 24
+)
+
+// terminalMessage is a message sent by a /terminal client
+type terminalMessage struct {
+	Type string `json:"type"`           // "input" or "resize"
+	Data string `json:"data,omitempty"` // Keystrokes, for input
+	Cols uint16 `json:"cols,omitempty"` // New size, for resize
+	Rows uint16 `json:"rows,omitempty"`
+}
+
+// handleTerminal upgrades to a WebSocket attached to an interactive shell.
+// Clients send JSON terminalMessages and receive raw terminal output as binary frames.
+// The initial size can be set with the cols and rows query parameters.
+func (s *Server) handleTerminal(c *gin.Context) {
+	// Browsers don't apply CORS to WebSockets, so without a session API key any web page could open a shell
+	if s.config.Server.SessionAPIKey == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "the terminal requires a session API key"})
+		return
+	}
+
+	cols := parseTerminalSize(c.Query("cols"), defaultTerminalCols)
+	rows := parseTerminalSize(c.Query("rows"), defaultTerminalRows)
+
+	websocket.Server{
+		Handshake: s.terminalHandshake,
+		Handler: func(ws *websocket.Conn) {
+			s.serveTerminal(ws, cols, rows)
+		},
+	}.ServeHTTP(c.Writer, c.Request)
+}
+
+// terminalHandshake accepts connections authenticated with the session API key and otherwise only
+// those from a page served by this host
+func (s *Server) terminalHandshake(config *websocket.Config, r *http.Request) error {
+	key := r.Header.Get("X-Session-API-Key")
+	if key == "" {
+		key = r.URL.Query().Get("api_key")
+	}
+	if key != "" && key == s.config.Server.SessionAPIKey {
+		return nil
+	}
+
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+	if origin == nil || origin.Host != r.Host {
+		return fmt.Errorf("cross-origin terminal connection from %v refused", origin)
+	}
+	return nil
+}
+
+// serveTerminal relays between a WebSocket and a new terminal until either side closes
+func (s *Server) serveTerminal(ws *websocket.Conn, cols, rows uint16) {
+	defer func() { _ = ws.Close() }()
+
+	terminal, err := s.executor.OpenTerminal(cols, rows)
+	if err != nil {
+		s.logger.Errorf("Failed to open terminal: %v", err)
+		_ = websocket.Message.Send(ws, "failed to open terminal: "+err.Error())
+		return
+	}
+	defer func() { _ = terminal.Close() }()
+
+	// Terminal output to the client; ends when the shell exits
+	go func() {
+		defer func() { _ = ws.Close() }()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := terminal.Read(buf)
+			if n > 0 {
+				if sendErr := websocket.Message.Send(ws, buf[:n]); sendErr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Client messages to the terminal; ends when the client disconnects
+	for {
+		var msg terminalMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.logger.Debugf("Terminal connection closed: %v", err)
+			}
+			return
+		}
+
+		switch msg.Type {
+		case "input":
+			if _, err := terminal.Write([]byte(msg.Data)); err != nil {
+				return
+			}
+		case "resize":
+			if err := terminal.Resize(msg.Cols, msg.Rows); err != nil {
+				s.logger.Warnf("Failed to resize terminal: %v", err)
+			}
+		default:
+			s.logger.Debugf("Ignoring unknown terminal message type %q", msg.Type)
+		}
+	}
+}
+
+// parseTerminalSize parses a terminal dimension, falling back to def for missing or invalid values
+func parseTerminalSize(value string, def uint16) uint16 {
+	size, err := strconv.ParseUint(value, 10, 16)
+	if err != nil || size == 0 {
+		return def
+	}
+	return uint16(size)
+}
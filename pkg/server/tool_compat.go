@@ -0,0 +1,257 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The tool compatibility layer lets frontends send the tool calls of their LLM API instead of
+// runtime actions. OpenHands can work with frontends using OpenAI, Claude or other APIs, and each
+// has its own tool calling format:
+//
+//  1. Claude: "tool_call_metadata" naming tools like "str_replace_editor", with the tool's args as the action's args
+//  2. OpenAI: a "tool_calls" array with a function name and JSON-encoded arguments
+//
+// Supporting a new format means adding an extractor to toolCallExtractors, and supporting a new
+// tool means adding an entry to toolMappings.
+
+// errMultipleToolCalls is returned for actions batching several tool calls. Each request executes a
+// single action and returns its observation, so the calls have to be sent one per request.
+var errMultipleToolCalls = errors.New("only one tool call per request is supported")
+
+// toolCall is a tool call found in an action
+type toolCall struct {
+	Format string // Tool calling format, "claude" or "openai"
+	Name   string
+	Args   map[string]interface{}
+}
+
+// toolCallExtractor finds a tool call of one format in an action.
+// It returns false when the action has no tool call of its format, and an error when one is malformed.
+type toolCallExtractor func(action map[string]interface{}) (toolCall, bool, error)
+
+// toolMapping translates the tool calls it matches into a runtime action type and args.
+// Transform returns false when the call lacks what the action needs, leaving the action unchanged.
+type toolMapping struct {
+	Format    string
+	Tools     []string
+	Matches   func(call toolCall) bool // Optional further condition on the call
+	Transform func(call toolCall) (actionType string, args map[string]interface{}, ok bool)
+}
+
+// toolCallExtractors are tried in order; the first tool call found is mapped
+var toolCallExtractors = []toolCallExtractor{
+	extractClaudeToolCall,
+	extractOpenAIToolCall,
+}
+
+// toolMappings are tried in order; the first matching mapping is applied
+var toolMappings = []toolMapping{
+	{
+		// The editor's args already match the read action's fields
+		Format:  "claude",
+		Tools:   []string{"str_replace_editor"},
+		Matches: commandIs("view"),
+		Transform: func(call toolCall) (string, map[string]interface{}, bool) {
+			return "read", call.Args, true
+		},
+	},
+	{
+		// The editor's args already match the edit action's fields
+		Format:  "claude",
+		Tools:   []string{"str_replace_editor"},
+		Matches: commandIs("create", "str_replace", "insert"),
+		Transform: func(call toolCall) (string, map[string]interface{}, bool) {
+			return "edit", call.Args, true
+		},
+	},
+	{
+		Format: "openai",
+		Tools:  []string{"read_file"},
+		Transform: func(call toolCall) (string, map[string]interface{}, bool) {
+			filePath, ok := call.Args["target_file"].(string)
+			return "read", map[string]interface{}{"path": filePath}, ok
+		},
+	},
+	{
+		Format: "openai",
+		Tools:  []string{"run_terminal_cmd"},
+		Transform: func(call toolCall) (string, map[string]interface{}, bool) {
+			command, ok := call.Args["command"].(string)
+			return "run", map[string]interface{}{"command": command}, ok
+		},
+	},
+	{
+		Format: "openai",
+		Tools:  []string{"write_file", "create_file"},
+		Transform: func(call toolCall) (string, map[string]interface{}, bool) {
+			filePath := firstStringArg(call.Args, "path", "target_file", "file_path")
+			return "write", map[string]interface{}{
+				"path":     filePath,
+				"contents": firstStringArg(call.Args, "content", "contents", "file_text"),
+			}, filePath != ""
+		},
+	},
+	{
+		Format: "openai",
+		Tools:  []string{"edit_file"},
+		Transform: func(call toolCall) (string, map[string]interface{}, bool) {
+			filePath := firstStringArg(call.Args, "path", "target_file", "file_path")
+			return "edit", map[string]interface{}{
+				"command": "str_replace",
+				"path":    filePath,
+				"old_str": firstStringArg(call.Args, "old_string", "old_str"),
+				"new_str": firstStringArg(call.Args, "new_string", "new_str"),
+			}, filePath != ""
+		},
+	},
+	{
+		// The patch must be a unified diff, as for the patch action
+		Format: "openai",
+		Tools:  []string{"apply_patch"},
+		Transform: func(call toolCall) (string, map[string]interface{}, bool) {
+			patch := firstStringArg(call.Args, "patch", "diff", "input")
+			return "patch", map[string]interface{}{
+				"patch": patch,
+				"path":  firstStringArg(call.Args, "path", "workdir"),
+			}, patch != ""
+		},
+	},
+	{
+		Format: "openai",
+		Tools:  []string{"list_dir"},
+		Transform: func(call toolCall) (string, map[string]interface{}, bool) {
+			return "list_files", map[string]interface{}{
+				"path": firstStringArg(call.Args, "relative_workspace_path", "path"),
+			}, true
+		},
+	},
+}
+
+// remapToolCall replaces the type and args of an action carrying a known tool call with those of
+// the matching runtime action. It returns the tool call and whether the action was remapped.
+func remapToolCall(action map[string]interface{}) (toolCall, bool, error) {
+	for _, extract := range toolCallExtractors {
+		call, found, err := extract(action)
+		if err != nil {
+			return toolCall{}, false, err
+		}
+		if !found {
+			continue
+		}
+
+		for _, mapping := range toolMappings {
+			if !mapping.matches(call) {
+				continue
+			}
+			actionType, args, ok := mapping.Transform(call)
+			if !ok {
+				return call, false, nil
+			}
+			action["action"] = actionType
+			action["args"] = args
+			return call, true, nil
+		}
+		return call, false, nil
+	}
+	return toolCall{}, false, nil
+}
+
+// matches reports whether the mapping applies to a tool call
+func (m toolMapping) matches(call toolCall) bool {
+	if m.Format != call.Format {
+		return false
+	}
+	for _, tool := range m.Tools {
+		if tool == call.Name {
+			return m.Matches == nil || m.Matches(call)
+		}
+	}
+	return false
+}
+
+// extractClaudeToolCall finds a tool named in the action's "tool_call_metadata", whose args are the action's
+func extractClaudeToolCall(action map[string]interface{}) (toolCall, bool, error) {
+	toolMeta, ok := action["tool_call_metadata"].(map[string]interface{})
+	if !ok {
+		return toolCall{}, false, nil
+	}
+	name, ok := toolMeta["function_name"].(string)
+	if !ok {
+		return toolCall{}, false, nil
+	}
+	args, _ := action["args"].(map[string]interface{})
+	return toolCall{Format: "claude", Name: name, Args: args}, true, nil
+}
+
+// extractOpenAIToolCall finds the action's single entry of "tool_calls", decoding its JSON arguments
+func extractOpenAIToolCall(action map[string]interface{}) (toolCall, bool, error) {
+	toolCalls, ok := action["tool_calls"].([]interface{})
+	if !ok || len(toolCalls) == 0 {
+		return toolCall{}, false, nil
+	}
+	if len(toolCalls) > 1 {
+		return toolCall{}, false, fmt.Errorf("%w, got %d: %s", errMultipleToolCalls, len(toolCalls), strings.Join(openAIToolNames(toolCalls), ", "))
+	}
+	first, ok := toolCalls[0].(map[string]interface{})
+	if !ok {
+		return toolCall{}, false, nil
+	}
+	function, ok := first["function"].(map[string]interface{})
+	if !ok {
+		return toolCall{}, false, nil
+	}
+	name, hasName := function["name"].(string)
+	arguments, hasArguments := function["arguments"].(string)
+	if !hasName || !hasArguments {
+		return toolCall{}, false, nil
+	}
+
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return toolCall{}, false, fmt.Errorf("failed to parse arguments of OpenAI tool call %s: %w", name, err)
+	}
+	return toolCall{Format: "openai", Name: name, Args: args}, true, nil
+}
+
+// openAIToolNames returns the function names of OpenAI tool calls, "?" for calls without one
+func openAIToolNames(toolCalls []interface{}) []string {
+	names := make([]string, len(toolCalls))
+	for i, tc := range toolCalls {
+		names[i] = "?"
+		if call, ok := tc.(map[string]interface{}); ok {
+			if function, ok := call["function"].(map[string]interface{}); ok {
+				if name, ok := function["name"].(string); ok {
+					names[i] = name
+				}
+			}
+		}
+	}
+	return names
+}
+
+// commandIs matches tool calls whose "command" arg is one of commands
+func commandIs(commands ...string) func(call toolCall) bool {
+	return func(call toolCall) bool {
+		command, _ := call.Args["command"].(string)
+		for _, c := range commands {
+			if command == c {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// firstStringArg returns the first of the named tool call arguments that is a non-empty string.
+// Frontends name the same argument differently, e.g. "path" or "target_file".
+func firstStringArg(args map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		if value, ok := args[name].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
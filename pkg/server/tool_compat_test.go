@@ -0,0 +1,150 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func claudeAction(args map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"action":             "str_replace_editor",
+		"args":               args,
+		"tool_call_metadata": map[string]interface{}{"function_name": "str_replace_editor"},
+	}
+}
+
+func openAIAction(name, arguments string) map[string]interface{} {
+	return map[string]interface{}{
+		"action": "tool_call",
+		"tool_calls": []interface{}{
+			map[string]interface{}{"function": map[string]interface{}{"name": name, "arguments": arguments}},
+		},
+	}
+}
+
+func TestRemapToolCall(t *testing.T) {
+	tests := []struct {
+		name         string
+		action       map[string]interface{}
+		expectedType string
+		expectedArgs map[string]interface{}
+	}{
+		{
+			name:         "claude view",
+			action:       claudeAction(map[string]interface{}{"command": "view", "path": "a.go"}),
+			expectedType: "read",
+			expectedArgs: map[string]interface{}{"command": "view", "path": "a.go"},
+		},
+		{
+			name:         "claude create",
+			action:       claudeAction(map[string]interface{}{"command": "create", "path": "a.go", "file_text": "package a"}),
+			expectedType: "edit",
+			expectedArgs: map[string]interface{}{"command": "create", "path": "a.go", "file_text": "package a"},
+		},
+		{
+			name:         "claude str_replace",
+			action:       claudeAction(map[string]interface{}{"command": "str_replace", "path": "a.go", "old_str": "a", "new_str": "b"}),
+			expectedType: "edit",
+			expectedArgs: map[string]interface{}{"command": "str_replace", "path": "a.go", "old_str": "a", "new_str": "b"},
+		},
+		{
+			name:         "claude insert",
+			action:       claudeAction(map[string]interface{}{"command": "insert", "path": "a.go", "insert_line": 1.0, "new_str": "b"}),
+			expectedType: "edit",
+			expectedArgs: map[string]interface{}{"command": "insert", "path": "a.go", "insert_line": 1.0, "new_str": "b"},
+		},
+		{
+			name:         "openai read_file",
+			action:       openAIAction("read_file", `{"target_file": "a.go"}`),
+			expectedType: "read",
+			expectedArgs: map[string]interface{}{"path": "a.go"},
+		},
+		{
+			name:         "openai run_terminal_cmd",
+			action:       openAIAction("run_terminal_cmd", `{"command": "ls"}`),
+			expectedType: "run",
+			expectedArgs: map[string]interface{}{"command": "ls"},
+		},
+		{
+			name:         "openai write_file",
+			action:       openAIAction("write_file", `{"path": "a.go", "content": "package a"}`),
+			expectedType: "write",
+			expectedArgs: map[string]interface{}{"path": "a.go", "contents": "package a"},
+		},
+		{
+			name:         "openai create_file",
+			action:       openAIAction("create_file", `{"target_file": "a.go", "contents": "package a"}`),
+			expectedType: "write",
+			expectedArgs: map[string]interface{}{"path": "a.go", "contents": "package a"},
+		},
+		{
+			name:         "openai edit_file",
+			action:       openAIAction("edit_file", `{"target_file": "a.go", "old_string": "a", "new_string": "b"}`),
+			expectedType: "edit",
+			expectedArgs: map[string]interface{}{"command": "str_replace", "path": "a.go", "old_str": "a", "new_str": "b"},
+		},
+		{
+			name:         "openai apply_patch",
+			action:       openAIAction("apply_patch", `{"patch": "--- a/a.go\n+++ b/a.go\n"}`),
+			expectedType: "patch",
+			expectedArgs: map[string]interface{}{"patch": "--- a/a.go\n+++ b/a.go\n", "path": ""},
+		},
+		{
+			name:         "openai list_dir",
+			action:       openAIAction("list_dir", `{"relative_workspace_path": "src"}`),
+			expectedType: "list_files",
+			expectedArgs: map[string]interface{}{"path": "src"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, remapped, err := remapToolCall(tt.action)
+			require.NoError(t, err)
+			assert.True(t, remapped)
+			assert.Equal(t, tt.expectedType, tt.action["action"])
+			assert.Equal(t, tt.expectedArgs, tt.action["args"])
+		})
+	}
+}
+
+func TestRemapToolCall_Unmapped(t *testing.T) {
+	tests := []struct {
+		name   string
+		action map[string]interface{}
+	}{
+		{"plain action", map[string]interface{}{"action": "run", "args": map[string]interface{}{"command": "ls"}}},
+		{"unknown claude command", claudeAction(map[string]interface{}{"command": "undo_edit", "path": "a.go"})},
+		{"unknown openai tool", openAIAction("search_web", `{"query": "go"}`)},
+		{"missing required argument", openAIAction("read_file", `{}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actionType := tt.action["action"]
+			_, remapped, err := remapToolCall(tt.action)
+			require.NoError(t, err)
+			assert.False(t, remapped)
+			assert.Equal(t, actionType, tt.action["action"])
+		})
+	}
+
+	t.Run("multiple openai tool calls", func(t *testing.T) {
+		action := openAIAction("read_file", `{"target_file": "a.go"}`)
+		action["tool_calls"] = append(action["tool_calls"].([]interface{}),
+			map[string]interface{}{"function": map[string]interface{}{"name": "run_terminal_cmd", "arguments": `{"command": "ls"}`}})
+
+		_, remapped, err := remapToolCall(action)
+		assert.ErrorIs(t, err, errMultipleToolCalls)
+		assert.ErrorContains(t, err, "read_file, run_terminal_cmd")
+		assert.False(t, remapped)
+	})
+
+	t.Run("malformed openai arguments", func(t *testing.T) {
+		_, remapped, err := remapToolCall(openAIAction("read_file", `{not json`))
+		assert.Error(t, err)
+		assert.False(t, remapped)
+	})
+}
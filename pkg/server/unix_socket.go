@@ -0,0 +1,54 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// unixSocketMode lets the runtime's user and group connect to the socket, but nobody else
+const unixSocketMode = 0660
+
+// listenUnix listens on a Unix domain socket at path. A socket file left behind by a runtime that
+// didn't shut down cleanly is replaced, but not one another process is still listening on.
+func listenUnix(path string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on Unix socket %s: %w", path, err)
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set permissions of Unix socket %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// removeStaleSocket removes the socket file at path if nothing accepts connections on it
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("unix socket path %s exists and is not a socket", path)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("unix socket %s is already in use", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("failed to check Unix socket %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin"
+)
+
+// watchEventType names the SSE event streamed for a filesystem change. A renamed path is reported
+// as deleted, followed by the creation of its new name if that is watched too. Permission changes
+// aren't reported.
+func watchEventType(op fsnotify.Op) string {
+	switch {
+	case op.Has(fsnotify.Create):
+		return "create"
+	case op.Has(fsnotify.Write):
+		return "modify"
+	case op.Has(fsnotify.Remove), op.Has(fsnotify.Rename):
+		return "delete"
+	}
+	return ""
+}
+
+// handleWatch streams the changes to a file, or to the entries of a directory, as create, modify and
+// delete SSE events until the client goes away. Subdirectories must be watched separately.
+func (s *Server) handleWatch(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path query parameter is required"})
+		return
+	}
+	if !filepath.IsAbs(path) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Path must be an absolute path: %s", path)})
+		return
+	}
+	if err := s.executor.SecurityCheck(path); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	path = filepath.Clean(path)
+
+	// Every stream holds an inotify watch and a goroutine until the client goes away
+	open := s.watchedPaths.Add(1)
+	defer s.watchedPaths.Add(-1)
+	if limit := s.config.Server.MaxWatchedPaths; limit > 0 && open > int64(limit) {
+		s.logger.Warnf("Refusing to watch %s: %d paths already watched", path, limit)
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("too many watched paths, at most %d are allowed", limit)})
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to create watcher: %v", err)})
+		return
+	}
+	defer func() { _ = watcher.Close() }()
+	if err := watcher.Add(path); err != nil {
+		status := http.StatusInternalServerError
+		if os.IsNotExist(err) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": fmt.Sprintf("cannot watch %s: %v", path, err)})
+		return
+	}
+
+	setSSEHeaders(c)
+	s.logger.Infof("Watching %s for changes", path)
+
+	send := func(event string, data gin.H) {
+		data["timestamp"] = time.Now().Unix()
+		c.SSEvent(event, data)
+		if flusher, ok := c.Writer.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+
+	// Clients know from this event that changes from now on are reported
+	send("watching", gin.H{"path": path})
+
+	clientGone := c.Request.Context().Done()
+	for {
+		select {
+		case <-clientGone:
+			s.logger.Infof("Stopped watching %s", path)
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if eventType := watchEventType(event.Op); eventType != "" {
+				send(eventType, gin.H{"path": event.Name})
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			s.logger.Warnf("Error watching %s: %v", path, err)
+			send("error", gin.H{"error": err.Error()})
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package telemetry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// exportErrorLogInterval is the least time between two logged telemetry errors
+const exportErrorLogInterval = time.Minute
+
+// rateLimitedErrorHandler logs the errors of the OpenTelemetry SDK, such as failed exports to an
+// unreachable collector, at most once per interval. Errors in between are only counted.
+type rateLimitedErrorHandler struct {
+	logger   *logrus.Logger
+	interval time.Duration
+
+	mu         sync.Mutex
+	lastLogged time.Time
+	suppressed int
+}
+
+func (h *rateLimitedErrorHandler) Handle(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.lastLogged.IsZero() && time.Since(h.lastLogged) < h.interval {
+		h.suppressed++
+		return
+	}
+
+	if h.suppressed > 0 {
+		h.logger.Warnf("Telemetry error: %v (%d more errors since the last one logged)", err, h.suppressed)
+	} else {
+		h.logger.Warnf("Telemetry error: %v", err)
+	}
+	h.lastLogged = time.Now()
+	h.suppressed = 0
+}
@@ -0,0 +1,88 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	otlplog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/denysvitali/openhands-runtime-go/pkg/redact"
+)
+
+// redactingSpanExporter masks secrets in the attributes, events and status of spans before exporting them
+type redactingSpanExporter struct {
+	sdktrace.SpanExporter
+	redactor *redact.Redactor
+}
+
+func (e redactingSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	redacted := make([]sdktrace.ReadOnlySpan, len(spans))
+	for i, span := range spans {
+		redacted[i] = redactedSpan{ReadOnlySpan: span, redactor: e.redactor}
+	}
+	return e.SpanExporter.ExportSpans(ctx, redacted)
+}
+
+// redactedSpan is a span whose string attributes, event attributes and status description are masked
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	redactor *redact.Redactor
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return s.redactAttributes(s.ReadOnlySpan.Attributes())
+}
+
+func (s redactedSpan) Events() []sdktrace.Event {
+	events := s.ReadOnlySpan.Events()
+	redacted := make([]sdktrace.Event, len(events))
+	for i, event := range events {
+		event.Attributes = s.redactAttributes(event.Attributes)
+		redacted[i] = event
+	}
+	return redacted
+}
+
+func (s redactedSpan) Status() sdktrace.Status {
+	status := s.ReadOnlySpan.Status()
+	status.Description = s.redactor.String(status.Description)
+	return status
+}
+
+func (s redactedSpan) redactAttributes(attributes []attribute.KeyValue) []attribute.KeyValue {
+	redacted := make([]attribute.KeyValue, len(attributes))
+	for i, attr := range attributes {
+		if attr.Value.Type() == attribute.STRING {
+			attr.Value = attribute.StringValue(s.redactor.String(attr.Value.AsString()))
+		}
+		redacted[i] = attr
+	}
+	return redacted
+}
+
+// redactingLogProcessor masks secrets in the body and string attributes of log records before
+// passing them on to the next processor
+type redactingLogProcessor struct {
+	sdklog.Processor
+	redactor *redact.Redactor
+}
+
+func (p redactingLogProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	if body := record.Body(); body.Kind() == otlplog.KindString {
+		record.SetBody(otlplog.StringValue(p.redactor.String(body.AsString())))
+	}
+
+	var attributes []otlplog.KeyValue
+	record.WalkAttributes(func(kv otlplog.KeyValue) bool {
+		if kv.Value.Kind() == otlplog.KindString {
+			kv.Value = otlplog.StringValue(p.redactor.String(kv.Value.AsString()))
+		}
+		attributes = append(attributes, kv)
+		return true
+	})
+	record.SetAttributes(attributes...)
+
+	return p.Processor.OnEmit(ctx, record)
+}
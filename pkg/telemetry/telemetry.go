@@ -3,9 +3,18 @@ package telemetry
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/denysvitali/openhands-runtime-go/pkg/config"
+	"github.com/denysvitali/openhands-runtime-go/pkg/redact"
+	"github.com/denysvitali/openhands-runtime-go/pkg/version"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/contrib/exporters/autoexport"
 	"go.opentelemetry.io/otel"
@@ -14,39 +23,62 @@ import (
 	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/propagation"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
-// Initialize sets up OpenTelemetry tracing and logging using autoexport
-func Initialize(cfg config.TelemetryConfig, logger *logrus.Logger) (func(), error) {
-	// Create resource with service info
-	res, err := resource.New(context.Background(),
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String("openhands-runtime"),
-			semconv.ServiceVersionKey.String("1.0.0"),
-		),
-		resource.WithFromEnv(),
-		resource.WithTelemetrySDK(),
-		resource.WithProcess(),
-		resource.WithOS(),
-		resource.WithContainer(),
-		resource.WithHost(),
-	)
-	if err != nil {
-		logger.Warnf("Failed to create resource: %v", err)
-		res = resource.Default()
+// defaultServiceName is reported when telemetry.service_name is empty
+const defaultServiceName = "openhands-runtime"
+
+// otlpEndpointEnv is the standard variable autoexport's OTLP exporters read their endpoint from
+const otlpEndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+// Initialize sets up OpenTelemetry tracing, logging and metrics using autoexport.
+// Secrets are masked with redactor before spans and log records are exported.
+// A configured telemetry.endpoint takes precedence over OTEL_EXPORTER_OTLP_ENDPOINT.
+func Initialize(cfg config.TelemetryConfig, logger *logrus.Logger, redactor *redact.Redactor) (func(), error) {
+	// autoexport only reads the environment, so the configured endpoint is passed on through it
+	if cfg.Endpoint != "" && os.Getenv(otlpEndpointEnv) != cfg.Endpoint {
+		if err := os.Setenv(otlpEndpointEnv, cfg.Endpoint); err != nil {
+			return nil, fmt.Errorf("failed to set OTLP endpoint: %w", err)
+		}
+		logger.Infof("Exporting telemetry to %s", cfg.Endpoint)
 	}
 
+	// Create resource with service info
+	res := newResource(cfg, logger)
+
 	// Initialize trace provider
 	traceExporter, err := autoexport.NewSpanExporter(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
+	// Failed exports are logged rather than returned anywhere, so that a collector that is down
+	// is noticed without flooding the log
+	otel.SetErrorHandler(&rateLimitedErrorHandler{logger: logger, interval: exportErrorLogInterval})
+
+	// The batch processors never block the spans and log records being recorded: when the queue
+	// is full, as it is while the collector is unreachable, new items are dropped
+	var batcherOptions []sdktrace.BatchSpanProcessorOption
+	var logProcessorOptions []sdklog.BatchProcessorOption
+	if cfg.MaxQueueSize > 0 {
+		batcherOptions = append(batcherOptions,
+			sdktrace.WithMaxQueueSize(cfg.MaxQueueSize),
+			sdktrace.WithMaxExportBatchSize(min(cfg.MaxQueueSize, sdktrace.DefaultMaxExportBatchSize)),
+		)
+		logProcessorOptions = append(logProcessorOptions, sdklog.WithMaxQueueSize(cfg.MaxQueueSize))
+	}
+	if cfg.ExportTimeoutSec > 0 {
+		timeout := time.Duration(cfg.ExportTimeoutSec) * time.Second
+		batcherOptions = append(batcherOptions, sdktrace.WithExportTimeout(timeout))
+		logProcessorOptions = append(logProcessorOptions, sdklog.WithExportTimeout(timeout))
+	}
+
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithBatcher(redactingSpanExporter{SpanExporter: traceExporter, redactor: redactor}, batcherOptions...),
 		sdktrace.WithResource(res),
 	)
 	otel.SetTracerProvider(tp)
@@ -60,12 +92,27 @@ func Initialize(cfg config.TelemetryConfig, logger *logrus.Logger) (func(), erro
 	var logProvider *sdklog.LoggerProvider
 	if logExporter != nil {
 		logProvider = sdklog.NewLoggerProvider(
-			sdklog.WithProcessor(sdklog.NewBatchProcessor(logExporter)),
+			sdklog.WithProcessor(redactingLogProcessor{Processor: sdklog.NewBatchProcessor(logExporter, logProcessorOptions...), redactor: redactor}),
 			sdklog.WithResource(res),
 		)
 		global.SetLoggerProvider(logProvider)
 	}
 
+	// Initialize meter provider
+	metricReader, err := autoexport.NewMetricReader(context.Background())
+	if err != nil {
+		logger.Warnf("Failed to create metric reader: %v", err)
+	}
+
+	var meterProvider *sdkmetric.MeterProvider
+	if metricReader != nil {
+		meterProvider = sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(metricReader),
+			sdkmetric.WithResource(res),
+		)
+		otel.SetMeterProvider(meterProvider)
+	}
+
 	// Set propagator
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{},
@@ -85,9 +132,68 @@ func Initialize(cfg config.TelemetryConfig, logger *logrus.Logger) (func(), erro
 				logger.Warnf("Failed to shutdown log provider: %v", err)
 			}
 		}
+		if meterProvider != nil {
+			if err := meterProvider.Shutdown(ctx); err != nil {
+				logger.Warnf("Failed to shutdown meter provider: %v", err)
+			}
+		}
 	}, nil
 }
 
+// newResource describes the runtime to the collector.
+// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the configured attributes.
+func newResource(cfg config.TelemetryConfig, logger *logrus.Logger) *resource.Resource {
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(resourceAttributes(cfg)...),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithProcess(),
+		resource.WithOS(),
+		resource.WithContainer(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		logger.Warnf("Failed to create resource: %v", err)
+		return resource.Default()
+	}
+	return res
+}
+
+// resourceAttributes returns the configured service name and version and extra resource attributes
+func resourceAttributes(cfg config.TelemetryConfig) []attribute.KeyValue {
+	// The version defaults to the build's
+	serviceName, serviceVersion := defaultServiceName, version.Version
+	if cfg.ServiceName != "" {
+		serviceName = cfg.ServiceName
+	}
+	if cfg.ServiceVersion != "" {
+		serviceVersion = cfg.ServiceVersion
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceVersionKey.String(serviceVersion),
+	}
+	for key, value := range cfg.ResourceAttributes {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	return attrs
+}
+
+// Flush exports the spans, log records and metrics still waiting in the batch processors of the
+// global providers, without waiting for the next scheduled export
+func Flush(ctx context.Context) error {
+	var errs []error
+	for _, provider := range []interface{}{otel.GetTracerProvider(), global.GetLoggerProvider(), otel.GetMeterProvider()} {
+		if flusher, ok := provider.(interface{ ForceFlush(context.Context) error }); ok {
+			if err := flusher.ForceFlush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // ReportJSON reports the given data as JSON in both traces and logs (debug level)
 func ReportJSON(ctx context.Context, logger *logrus.Logger, operationName string, data interface{}) {
 	// Convert data to JSON
@@ -104,7 +210,19 @@ func ReportJSON(ctx context.Context, logger *logrus.Logger, operationName string
 	ReportJSONInLogs(logger, operationName, data, jsonData)
 }
 
-// ReportJSONInTrace adds JSON data to the current trace span
+// Limits on what ReportJSONInTrace attaches to a span, keeping the number and size of attributes
+// bounded whatever the reported data contains
+const (
+	maxFieldAttributes       = 32        // Per-field attributes of a map, taken in key order
+	maxFieldKeyLength        = 64        // Longer field names are cut
+	maxAttributeValueLength  = 1024      // Longer field values are truncated
+	maxJSONAttributeLength   = 16 * 1024 // Longer JSON documents are truncated
+	fieldAttributePrefix     = "data.field."
+	truncatedAttributeSuffix = "...[truncated]"
+)
+
+// ReportJSONInTrace adds JSON data to the current trace span.
+// The scalar fields of a map are also added, under data.field.<key>, up to maxFieldAttributes of them.
 func ReportJSONInTrace(ctx context.Context, operationName string, data interface{}, jsonData []byte) {
 	tracer := otel.Tracer("openhands-runtime")
 	_, span := tracer.Start(ctx, operationName)
@@ -112,24 +230,81 @@ func ReportJSONInTrace(ctx context.Context, operationName string, data interface
 
 	// Add JSON as span attribute
 	span.SetAttributes(
-		attribute.String("json.data", string(jsonData)),
+		attribute.String("json.data", truncateAttributeValue(string(jsonData), maxJSONAttributeLength)),
 		attribute.String("data.type", getDataType(data)),
 	)
 
 	// Add individual fields if it's a map
 	if dataMap, ok := data.(map[string]interface{}); ok {
-		for key, value := range dataMap {
-			if strValue, ok := value.(string); ok {
-				span.SetAttributes(attribute.String("data."+key, strValue))
-			} else if intValue, ok := value.(int); ok {
-				span.SetAttributes(attribute.Int("data."+key, intValue))
-			} else if floatValue, ok := value.(float64); ok {
-				span.SetAttributes(attribute.Float64("data."+key, floatValue))
-			} else if boolValue, ok := value.(bool); ok {
-				span.SetAttributes(attribute.Bool("data."+key, boolValue))
-			}
+		span.SetAttributes(fieldAttributes(dataMap)...)
+	}
+}
+
+// fieldAttributes converts the scalar fields of a map to span attributes. Keys come from the
+// reported data, so they are sanitized and namespaced, and fields past maxFieldAttributes are
+// only counted in data.fields_dropped.
+func fieldAttributes(dataMap map[string]interface{}) []attribute.KeyValue {
+	keys := make([]string, 0, len(dataMap))
+	for key := range dataMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var attrs []attribute.KeyValue
+	dropped := 0
+	for _, key := range keys {
+		var value attribute.Value
+		switch v := dataMap[key].(type) {
+		case string:
+			value = attribute.StringValue(truncateAttributeValue(v, maxAttributeValueLength))
+		case int:
+			value = attribute.IntValue(v)
+		case float64:
+			value = attribute.Float64Value(v)
+		case bool:
+			value = attribute.BoolValue(v)
+		default:
+			continue
+		}
+
+		if len(attrs) == maxFieldAttributes {
+			dropped++
+			continue
+		}
+		attrs = append(attrs, attribute.KeyValue{Key: attribute.Key(fieldAttributePrefix + sanitizeAttributeKey(key)), Value: value})
+	}
+
+	if dropped > 0 {
+		attrs = append(attrs, attribute.Int("data.fields_dropped", dropped))
+	}
+	return attrs
+}
+
+// sanitizeAttributeKey cuts a field name to maxFieldKeyLength and replaces characters other than
+// letters, digits, '_' and '-' with '_', so a field name can't create nested attribute namespaces
+func sanitizeAttributeKey(key string) string {
+	if len(key) > maxFieldKeyLength {
+		key = key[:maxFieldKeyLength]
+	}
+	return strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
 		}
+		return '_'
+	}, key)
+}
+
+// truncateAttributeValue cuts value to at most limit bytes, without splitting a UTF-8 character,
+// and marks it as truncated
+func truncateAttributeValue(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	cut := limit - len(truncatedAttributeSuffix)
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
 	}
+	return value[:max(cut, 0)] + truncatedAttributeSuffix
 }
 
 // ReportJSONInLogs logs JSON data at debug level
@@ -0,0 +1,25 @@
+// Package version holds the build information of the runtime, injected at build time with
+//
+//	go build -ldflags "-X github.com/denysvitali/openhands-runtime-go/pkg/version.Version=1.2.3 \
+//		-X github.com/denysvitali/openhands-runtime-go/pkg/version.Commit=abc1234 \
+//		-X github.com/denysvitali/openhands-runtime-go/pkg/version.BuildDate=2024-01-01T00:00:00Z"
+package version
+
+// Build information, left at its defaults by plain go build and go run
+var (
+	Version   = "dev"
+	Commit    = "dev"
+	BuildDate = "dev"
+)
+
+// Info is the build information as reported by GET /version
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// Get returns the build information
+func Get() Info {
+	return Info{Version: Version, Commit: Commit, BuildDate: BuildDate}
+}